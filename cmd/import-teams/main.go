@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"tournament-bot/config"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/services"
+)
+
+// Утилита для загрузки справочника команд из CSV с рейтингами FIFA / EA FC:
+//
+//	go run ./cmd/import-teams -file teams.csv               загрузить или обновить команды
+//	go run ./cmd/import-teams -file teams.csv -crests dir   взять эмблемы <ID>.png из каталога
+//	go run ./cmd/import-teams -file teams.csv -dry-run      показать, что изменится, без записи
+func main() {
+	file := flag.String("file", "", "CSV file with team ratings")
+	crests := flag.String("crests", "", "directory with <team id>.png crest images")
+	dryRun := flag.Bool("dry-run", false, "print what would be done without changing the database")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		fatal("Error opening CSV file", err)
+	}
+	defer f.Close()
+
+	db.InitDB(config.LoadMongoURI())
+	defer db.Close(context.Background())
+
+	// Уникальные индексы справочника нужны до первой записи
+	if !*dryRun {
+		if _, err := db.EnsureSchema(context.Background()); err != nil {
+			fatal("Error ensuring database schema", err)
+		}
+	}
+
+	result, err := services.ImportTeamsCSV(f, *crests, *dryRun)
+	if result != nil {
+		for _, skipped := range result.Skipped {
+			fmt.Println("skipped", skipped)
+		}
+		action := "imported"
+		if *dryRun {
+			action = "would import"
+		}
+		fmt.Printf("%s: %d new, %d updated, %d skipped\n", action, result.Created, result.Updated, len(result.Skipped))
+	}
+	if err != nil {
+		fatal("Error importing teams", err)
+	}
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/robfig/cron/v3"
-	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 	"tournament-bot/config"
 	"tournament-bot/internal/bot"
 	"tournament-bot/internal/db"
+	"tournament-bot/internal/logging"
+	"tournament-bot/internal/migrations"
+	"tournament-bot/internal/notifications"
 	"tournament-bot/internal/services"
 	"tournament-bot/internal/web"
 )
@@ -15,16 +23,30 @@ import (
 func main() {
 	cfg := config.LoadConfig()
 
+	// Настройка структурированного логирования
+	if err := logging.Setup(cfg.LogLevel, cfg.LogFormat, cfg.BotToken); err != nil {
+		fatal("Error configuring logging", err)
+	}
+
+	// Контекст приложения отменяется по SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	// Инициализация базы данных
 	db.InitDB(cfg.MongoURI)
 
+	// Применение миграций при запуске (по умолчанию выключено, см. cmd/migrate)
+	if cfg.AutoMigrate {
+		runMigrations(ctx, cfg.MigrationsDir)
+	}
+
 	// Создаем новый планировщик задач
 	c := cron.New()
 
 	// Добавляем задачу для удаления незавершенных турниров каждый день в 6:00 AM по московскому времени
 	_, err := c.AddFunc("0 6 * * *", deleteUnfinishedTournaments)
 	if err != nil {
-		log.Fatalf("Error adding deleteUnfinishedTournaments to cron: %v", err)
+		fatal("Error adding deleteUnfinishedTournaments to cron", err)
 	}
 
 	// Запускаем планировщик задач
@@ -32,9 +54,32 @@ func main() {
 
 	go deleteUnfinishedTournaments()
 
-	botAPI, err := tgbotapi.NewBotAPI(cfg.BotToken)
+	err = bot.Init(cfg.BotToken)
 	if err != nil {
-		log.Fatal(err)
+		fatal("Error creating bot", err)
+	}
+
+	// Очередь уведомлений в канал
+	err = notifications.Start(cfg.BotToken, cfg.UpdateQueue)
+	if err != nil {
+		fatal("Error starting notifications", err)
+	}
+
+	// Закрытие просроченных подтверждений участия
+	_, err = c.AddFunc("@every 1m", func() { bot.CloseExpiredCheckIns(context.Background()) })
+	if err != nil {
+		fatal("Error adding CloseExpiredCheckIns to cron", err)
+	}
+
+	// Счет технических поражений
+	services.SetForfeitScore(cfg.ForfeitWinnerGoals, cfg.ForfeitLoserGoals)
+	services.SetNoRepeatTournaments(cfg.DrawNoRepeatTournaments)
+	notifications.SetDrawRevealPause(cfg.DrawRevealPause)
+
+	// Система рейтинга игроков
+	err = services.SetRatingSystem(cfg.RatingSystem, float64(cfg.RatingKFactor), cfg.RatingTeamAdjust)
+	if err != nil {
+		fatal("Error configuring player rating", err)
 	}
 
 	// Установка меню команд
@@ -48,43 +93,170 @@ func main() {
 		{Command: "start_playoff", Description: "🔥 Начать этап плей-офф турнира"},
 		{Command: "deletelastmatch", Description: "🗑️ Удалить последний добавленный матч (только для админов)"},
 		{Command: "add_match", Description: "➕ Добавить результат матча (только для админов)"},
+		{Command: "register", Description: "📝 Привязать аккаунт к участнику"},
+		{Command: "approve_link", Description: "🔗 Подтвердить привязку аккаунта (только для админов)"},
+		{Command: "reject_link", Description: "🚫 Отклонить привязку аккаунта (только для админов)"},
+		{Command: "join", Description: "✅ Записаться на турнир"},
+		{Command: "withdraw", Description: "🚪 Отказаться от участия в турнире"},
+		{Command: "registration_deadline", Description: "⏰ Установить срок записи (только для админов)"},
+		{Command: "start_checkin", Description: "🕒 Начать подтверждение участия (только для админов)"},
+		{Command: "checkin", Description: "🙋 Подтвердить участие в турнире"},
+		{Command: "close_checkin", Description: "🔒 Закрыть подтверждение участия (только для админов)"},
+		{Command: "withdraw_player", Description: "🚫 Снять участника с турнира (только для админов)"},
+		{Command: "match_status", Description: "🏳️ Тех. поражение, неявка, перенос матча (только для админов)"},
+		{Command: "pick_team", Description: "🎽 Выбрать команду (турнир со свободным выбором)"},
+		{Command: "verify_draw", Description: "🔍 Проверить жеребьевку турнира"},
+		{Command: "cancel_draw", Description: "↩️ Отменить жеребьевку до старта турнира (только для админов)"},
+		{Command: "rating", Description: "📈 Рейтинг игроков"},
+		{Command: "profile", Description: "👤 Профиль и карьерная статистика игрока"},
+		{Command: "h2h", Description: "⚔️ Личные встречи двух игроков"},
+		{Command: "rating_history", Description: "📉 История рейтинга игрока"},
+		{Command: "recalculate_ratings", Description: "🔄 Пересчитать рейтинг (только для админов)"},
+		{Command: "season", Description: "🗓 Таблица сезона"},
+		{Command: "seasons", Description: "📚 Архив сезонов"},
+		{Command: "season_start", Description: "🚩 Начать сезон (только для админов)"},
+		{Command: "season_end", Description: "🏁 Завершить сезон (только для админов)"},
+		{Command: "season_scoring", Description: "🧮 Правила начисления очков сезона"},
+		{Command: "recalculate_season", Description: "🔄 Пересчитать очки сезона (только для админов)"},
+		{Command: "categories", Description: "🗂 Категории команд"},
+		{Command: "add_team_category", Description: "➕ Добавить категорию команд (только для админов)"},
+		{Command: "rename_team_category", Description: "✏️ Переименовать категорию команд (только для админов)"},
+		{Command: "add_category_team", Description: "➕ Добавить команду в категорию (только для админов)"},
+		{Command: "remove_category_team", Description: "➖ Убрать команду из категории (только для админов)"},
+		{Command: "remove_team_category", Description: "🗑 Удалить категорию команд (только для админов)"},
+		{Command: "add_catalog_category", Description: "📚 Категория из справочника команд (только для админов)"},
+		{Command: "team", Description: "🛡 Карточка команды"},
+		{Command: "team_alias", Description: "🔤 Другое название команды (только для админов)"},
+		{Command: "delete_template", Description: "🗑 Удалить шаблон турнира (только для админов)"},
+		{Command: "rename_participant", Description: "✏️ Переименовать участника (только для админов)"},
+		{Command: "merge_participants", Description: "🔗 Объединить дубликаты участника (только для админов)"},
+		{Command: "rebuild_stats", Description: "🧾 Пересчитать статистику игроков по турнирам (только для админов)"},
+	}
+
+	_, err = bot.Request(tgbotapi.NewSetMyCommands(commands...))
+	if err != nil {
+		slog.Error("Error setting command menu", "err", err)
+	}
+
+	// Пул воркеров для асинхронной обработки обновлений
+	bot.StartDispatcher(cfg.UpdateWorkers, cfg.UpdateQueue)
+
+	// Секрет вебхука задается до запуска веб-сервера: если не задан, генерируем
+	// новый при каждом запуске
+	webhookSecret := cfg.WebhookSecret
+	if !cfg.IsLocalMode {
+		if webhookSecret == "" {
+			webhookSecret, err = bot.NewWebhookSecret()
+			if err != nil {
+				fatal("Error generating webhook secret", err)
+			}
+		}
+		bot.SetWebhookSecret(webhookSecret)
 	}
 
-	_, err = botAPI.Request(tgbotapi.NewSetMyCommands(commands...))
+	// Запуск веб-сервера для обработки вебхуков, проверок состояния и метрик
+	server, err := web.StartServer(":"+cfg.Port, cfg.WebhookURL)
 	if err != nil {
-		log.Printf("Error setting command menu: %v", err)
+		fatal("Error starting web server", err)
+	}
+
+	if cfg.IsLocalMode {
+		// Режим long polling для локальной разработки
+		slog.Info("Starting bot in local mode (long polling)")
+		err = bot.StartPolling()
+		if err != nil {
+			fatal("Error starting long polling", err)
+		}
+	} else {
+		// Режим webhook для production: вебхук регистрируется, когда сервер уже слушает порт
+		slog.Info("Starting bot in production mode (webhook)")
+		err = bot.SetWebhook(cfg.WebhookURL, webhookSecret)
+		if err != nil {
+			fatal("Error setting webhook", err)
+		}
+	}
+
+	// Ожидание сигнала завершения
+	<-ctx.Done()
+	stop()
+	slog.Info("Shutting down")
+
+	shutdown(cfg, c, server)
+}
+
+// shutdown останавливает компоненты в порядке, обратном запуску: сначала перестаем
+// принимать обновления, затем дожидаемся их обработки и отправки уведомлений,
+// и только после этого закрываем соединение с базой данных
+func shutdown(cfg *config.Config, scheduler *cron.Cron, server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if cfg.IsLocalMode {
+		bot.StopPolling()
+	}
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("Error shutting down web server", "err", err)
+	}
+
+	if err := bot.StopDispatcher(ctx); err != nil {
+		slog.Error("Error waiting for update handlers", "err", err)
 	}
 
-	// Режим webhook для production
-	log.Println("Starting bot in production mode (webhook)")
-	// Настройка вебхука
-	err = bot.SetWebhook(cfg.BotToken, cfg.WebhookURL)
+	if err := notifications.Shutdown(ctx); err != nil {
+		slog.Error("Error flushing notifications", "err", err)
+	}
+
+	select {
+	case <-scheduler.Stop().Done():
+	case <-ctx.Done():
+		slog.Error("Error waiting for scheduled jobs", "err", ctx.Err())
+	}
+
+	if err := db.Close(ctx); err != nil {
+		slog.Error("Error disconnecting from MongoDB", "err", err)
+	}
+
+	slog.Info("Shutdown complete")
+}
+
+func runMigrations(ctx context.Context, dir string) {
+	loaded, err := migrations.LoadDir(dir)
 	if err != nil {
-		log.Fatalf("Error setting webhook: %v", err)
+		fatal("Error loading migrations", err)
 	}
 
-	// Запуск веб-сервера для обработки вебхуков
-	go web.StartServer(":" + cfg.Port)
+	runner := &migrations.Runner{
+		Store:      &migrations.MongoStore{DB: db.DB},
+		Migrations: loaded,
+	}
+	done, err := runner.Up(ctx)
+	if err != nil {
+		fatal("Error applying migrations", err)
+	}
+	slog.Info("Migrations applied", "count", len(done))
+}
 
-	// Ожидание завершения программы
-	select {}
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
 
 func deleteUnfinishedTournaments() {
 	// Получаем список всех незавершенных и неактивных турниров
 	inactiveTournaments, err := services.GetInactiveTournaments()
 	if err != nil {
-		log.Printf("Error getting inactive tournaments: %v", err)
+		slog.Error("Error getting inactive tournaments", "err", err)
 		return
 	}
 
 	// Проверяем каждый неактивный турнир
 	for _, tournament := range inactiveTournaments {
-		if (!tournament.SetupCompleted || !tournament.IsActive) && time.Since(tournament.CreatedAt) > 24*time.Hour {
-			// Если настройка турнира не завершена или турнир неактивен, и прошло более 24 часов с момента создания, удаляем турнир
+		if services.Abandoned(tournament, time.Now()) {
+			// Турнир так и не начался, а запись на него закрыта: удаляем турнир
 			err := services.DeleteTournament(tournament.ID)
 			if err != nil {
-				log.Printf("Error deleting tournament: %v", err)
+				slog.Error("Error deleting tournament", "tournament_id", tournament.ID, "err", err)
 			}
 		}
 	}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"tournament-bot/config"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/migrations"
+	"tournament-bot/internal/services"
+)
+
+// Утилита для применения миграций из каталога migrations:
+//
+//	go run ./cmd/migrate                  применить все непримененные миграции
+//	go run ./cmd/migrate -status          показать состояние миграций
+//	go run ./cmd/migrate -down 20230624170000   откатить миграции новее указанной версии (0 — все)
+//	go run ./cmd/migrate -baseline 20230628000000  отметить миграции как примененные без выполнения
+//	go run ./cmd/migrate -dry-run ...     показать план без изменений в базе
+//	go run ./cmd/migrate -repair-tournament-ids  перенумеровать турниры с повторяющимися ID
+//	go run ./cmd/migrate -rebuild-stats   пересчитать статистику игроков по завершенным турнирам
+func main() {
+	dir := flag.String("dir", "migrations", "directory with *.up.json / *.down.json files")
+	status := flag.Bool("status", false, "print migration status and exit")
+	down := flag.Int64("down", -1, "roll back to the given version (0 rolls back everything)")
+	baseline := flag.Int64("baseline", -1, "mark migrations up to the given version as applied without running them")
+	dryRun := flag.Bool("dry-run", false, "print what would be done without changing the database")
+	repairIDs := flag.Bool("repair-tournament-ids", false, "renumber tournaments that share an id and exit")
+	rebuildStats := flag.Bool("rebuild-stats", false, "recompute participant stats from completed tournaments and exit")
+	flag.Parse()
+
+	loaded, err := migrations.LoadDir(*dir)
+	if err != nil {
+		fatal("Error loading migrations", err)
+	}
+
+	db.InitDB(config.LoadMongoURI())
+	defer db.Close(context.Background())
+
+	runner := &migrations.Runner{
+		Store:      &migrations.MongoStore{DB: db.DB},
+		Migrations: loaded,
+		DryRun:     *dryRun,
+	}
+
+	ctx := context.Background()
+
+	switch {
+	case *repairIDs:
+		repairTournamentIDs(ctx, *dryRun)
+		return
+	case *rebuildStats:
+		rebuildParticipantStats(*dryRun)
+		return
+	case *status:
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			fatal("Error reading migration status", err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d_%s\t%s\n", s.Migration.Version, s.Migration.Name, state)
+		}
+		return
+	case *baseline >= 0:
+		done, err := runner.Baseline(ctx, *baseline)
+		report("marked as applied", done)
+		if err != nil {
+			fatal("Error recording baseline", err)
+		}
+	case *down >= 0:
+		done, err := runner.DownTo(ctx, *down)
+		report("rolled back", done)
+		if err != nil {
+			fatal("Error rolling back migrations", err)
+		}
+	default:
+		done, err := runner.Up(ctx)
+		report("applied", done)
+		if err != nil {
+			fatal("Error applying migrations", err)
+		}
+	}
+}
+
+func repairTournamentIDs(ctx context.Context, dryRun bool) {
+	changes, err := services.RepairDuplicateTournamentIDs(dryRun)
+	for _, change := range changes {
+		if dryRun {
+			fmt.Printf("would renumber %q (id %d)\n", change.Name, change.OldID)
+		} else {
+			fmt.Printf("renumbered %q: %d -> %d\n", change.Name, change.OldID, change.NewID)
+		}
+	}
+	if err != nil {
+		fatal("Error repairing tournament ids", err)
+	}
+	if len(changes) == 0 {
+		fmt.Println("No duplicate tournament ids")
+		return
+	}
+
+	// Уникальный индекс не создается, пока в коллекции есть дубликаты
+	if !dryRun {
+		drift, err := db.EnsureSchema(ctx)
+		for _, line := range drift {
+			fmt.Println(line)
+		}
+		if err != nil {
+			fatal("Error ensuring database schema", err)
+		}
+	}
+}
+
+func rebuildParticipantStats(dryRun bool) {
+	diffs, err := services.RebuildParticipantStats(dryRun)
+	for _, diff := range diffs {
+		action := "rebuilt"
+		if dryRun {
+			action = "would rebuild"
+		}
+		fmt.Printf("%s %s:\n", action, diff.Participant)
+		for _, change := range diff.Changes {
+			fmt.Printf("\t%s\n", change)
+		}
+	}
+	if err != nil {
+		fatal("Error rebuilding participant stats", err)
+	}
+	if len(diffs) == 0 {
+		fmt.Println("Participant stats match the tournament history")
+	}
+}
+
+func report(action string, done []migrations.Migration) {
+	if len(done) == 0 {
+		fmt.Println("Nothing to do")
+		return
+	}
+	for _, migration := range done {
+		fmt.Printf("%s %d_%s\n", action, migration.Version, migration.Name)
+	}
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
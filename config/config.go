@@ -1,37 +1,81 @@
 package config
 
 import (
+	"fmt"
 	"github.com/joho/godotenv"
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	MongoURI    string
-	BotToken    string
-	WebhookURL  string
-	Port        string
-	IsLocalMode bool
+	MongoURI      string
+	BotToken      string
+	WebhookURL    string
+	WebhookSecret string
+	Port          string
+	IsLocalMode   bool
+	UpdateWorkers int
+	UpdateQueue   int
+	LogLevel      string
+	LogFormat     string
+	// Применять ли непримененные миграции из MigrationsDir при запуске
+	AutoMigrate   bool
+	MigrationsDir string
+	// Сколько ждать завершения обработки обновлений и отправки уведомлений при остановке
+	ShutdownTimeout time.Duration
+	// Счет технического поражения при снятии участника с начатого турнира (FORFEIT_SCORE, например 3:0)
+	ForfeitWinnerGoals int
+	ForfeitLoserGoals  int
+	// За сколько последних турниров игроку не выпадают прежние клубы при жеребьевке без повторов
+	DrawNoRepeatTournaments int
+	// Пауза между парами при показе жеребьевки в канале
+	DrawRevealPause time.Duration
+	// Рейтинг игроков: система (elo или glicko2), коэффициент K для Эло
+	// и учет звезд команд из справочника как форы
+	RatingSystem     string
+	RatingKFactor    int
+	RatingTeamAdjust bool
 }
 
 func LoadConfig() *Config {
 	// Загрузка .env файла
 	err := godotenv.Load()
 	if err != nil {
-		log.Println("Error loading .env file. Using environment variables.")
+		slog.Info("Error loading .env file. Using environment variables.")
 	}
 
 	isLocalMode, _ := strconv.ParseBool(getEnvOrPanic("LOCAL_MODE"))
+	autoMigrate, _ := strconv.ParseBool(getEnvOrDefault("AUTO_MIGRATE", "false"))
+	ratingTeamAdjust, _ := strconv.ParseBool(getEnvOrDefault("RATING_TEAM_ADJUST", "false"))
 
 	config := &Config{
-		MongoURI:    getEnvOrPanic("MONGO_URI"),
-		BotToken:    getEnvOrPanic("BOT_TOKEN"),
-		Port:        getEnvOrPanic("PORT"),
-		WebhookURL:  getEnvOrPanic("WEBHOOK_URL"),
-		IsLocalMode: isLocalMode,
+		MongoURI:      getEnvOrPanic("MONGO_URI"),
+		BotToken:      getEnvOrPanic("BOT_TOKEN"),
+		Port:          getEnvOrPanic("PORT"),
+		WebhookSecret: getEnvOrDefault("WEBHOOK_SECRET", ""),
+		IsLocalMode:   isLocalMode,
+		UpdateWorkers: getEnvIntOrDefault("UPDATE_WORKERS", 4),
+		UpdateQueue:   getEnvIntOrDefault("UPDATE_QUEUE_SIZE", 100),
+		LogLevel:      getEnvOrDefault("LOG_LEVEL", "info"),
+		LogFormat:     getEnvOrDefault("LOG_FORMAT", "json"),
+		AutoMigrate:   autoMigrate,
+		MigrationsDir: getEnvOrDefault("MIGRATIONS_DIR", "migrations"),
+
+		ShutdownTimeout: getEnvDurationOrDefault("SHUTDOWN_TIMEOUT", 30*time.Second),
+
+		DrawNoRepeatTournaments: getEnvIntOrDefault("DRAW_NO_REPEAT_TOURNAMENTS", 3),
+		DrawRevealPause:         getEnvDurationOrDefault("DRAW_REVEAL_PAUSE", 3*time.Second),
+
+		RatingSystem:     getEnvOrDefault("RATING_SYSTEM", "elo"),
+		RatingKFactor:    getEnvIntOrDefault("RATING_K_FACTOR", 32),
+		RatingTeamAdjust: ratingTeamAdjust,
 	}
 
+	config.ForfeitWinnerGoals, config.ForfeitLoserGoals = getEnvScoreOrDefault("FORFEIT_SCORE", 3, 0)
+
 	if !isLocalMode {
 		config.WebhookURL = getEnvOrPanic("WEBHOOK_URL")
 	}
@@ -39,10 +83,70 @@ func LoadConfig() *Config {
 	return config
 }
 
+// LoadMongoURI читает только адрес MongoDB — для утилит, которым не нужна остальная конфигурация бота
+func LoadMongoURI() string {
+	_ = godotenv.Load()
+	return getEnvOrPanic("MONGO_URI")
+}
+
 func getEnvOrPanic(key string) string {
 	value, exists := os.LookupEnv(key)
 	if !exists {
-		log.Fatalf("Environment variable %s is not set", key)
+		slog.Error("Environment variable is not set", "key", key)
+		os.Exit(1)
 	}
 	return value
 }
+
+func getEnvOrDefault(key, defaultValue string) string {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		slog.Warn("Invalid config value, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		slog.Warn("Invalid config value, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvScoreOrDefault читает счет в формате "3:0"; счет победителя должен быть больше
+func getEnvScoreOrDefault(key string, defaultWinner, defaultLoser int) (int, int) {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultWinner, defaultLoser
+	}
+	parts := strings.Split(value, ":")
+	if len(parts) == 2 {
+		winner, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+		loser, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err1 == nil && err2 == nil && loser >= 0 && winner > loser {
+			return winner, loser
+		}
+	}
+	slog.Warn("Invalid config value, using default", "key", key, "value", value,
+		"default", fmt.Sprintf("%d:%d", defaultWinner, defaultLoser))
+	return defaultWinner, defaultLoser
+}
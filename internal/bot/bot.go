@@ -1,50 +1,143 @@
 package bot
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
+	"errors"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
 	"net/http"
+	"time"
 )
 
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
 var bot *tgbotapi.BotAPI
 
-func SetWebhook(token, webhookURL string) error {
+// Секрет, который Telegram присылает в заголовке каждого запроса на вебхук
+var webhookSecret string
+
+// Init создает клиент Bot API, используемый всеми обработчиками
+func Init(token string) error {
 	var err error
 	bot, err = tgbotapi.NewBotAPI(token)
-	if err != nil {
-		return err
-	}
+	return err
+}
 
-	// Установка вебхука
-	wh, err := tgbotapi.NewWebhook(webhookURL)
-	if err != nil {
-		return err
-	}
+func SetWebhook(webhookURL, secret string) error {
+	// Установка вебхука. WebhookConfig в tgbotapi v5.5.1 не умеет передавать
+	// secret_token, поэтому формируем параметры запроса вручную.
+	params := tgbotapi.Params{"url": webhookURL}
+	params.AddNonEmpty("secret_token", secret)
 
-	_, err = bot.Request(wh)
+	_, err := bot.MakeRequest("setWebhook", params)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("Webhook set successfully")
+	slog.Info("Webhook set successfully")
 	return nil
 }
 
+// SetWebhookSecret задает секрет, который WebhookHandler сверяет с заголовком запроса.
+// Вызывается до запуска веб-сервера, чтобы первые обновления уже проверялись.
+func SetWebhookSecret(secret string) {
+	webhookSecret = secret
+}
+
+// NewWebhookSecret генерирует случайный секрет для вебхука, если он не задан в конфигурации
+func NewWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
 func WebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if webhookSecret != "" {
+		received := r.Header.Get(secretTokenHeader)
+		if subtle.ConstantTimeCompare([]byte(received), []byte(webhookSecret)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
 	var update tgbotapi.Update
 	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if update.Message != nil {
-		HandleMessage(update.Message)
-	} else if update.CallbackQuery != nil {
-		СallbackHandler(update.CallbackQuery)
+	// Обработка выполняется асинхронно, Telegram получает ответ сразу
+	if err := Enqueue(update); err != nil {
+		if errors.Is(err, ErrQueueFull) {
+			slog.Warn("Update rejected", "update_id", update.UpdateID, "err", err)
+		}
+		// Telegram повторит доставку позже
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// StartPolling переключает бота на получение обновлений через getUpdates
+// (для локальной разработки без публичного адреса). Существующий вебхук удаляется,
+// так как Telegram не отдает обновления через getUpdates при установленном вебхуке.
+func StartPolling() error {
+	_, err := bot.Request(tgbotapi.DeleteWebhookConfig{})
+	if err != nil {
+		return err
 	}
+
+	webhookSecret = ""
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 60
+	updatesChan := bot.GetUpdatesChan(u)
+
+	go func() {
+		for update := range updatesChan {
+			// В отличие от вебхука, повторной доставки не будет, поэтому ждем место в очереди
+			for {
+				err := Enqueue(update)
+				if !errors.Is(err, ErrQueueFull) {
+					if err != nil {
+						slog.Warn("Update dropped", "update_id", update.UpdateID, "err", err)
+					}
+					break
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+		}
+	}()
+
+	slog.Info("Long polling started")
+	return nil
+}
+
+// StopPolling останавливает получение обновлений через getUpdates
+func StopPolling() {
+	bot.StopReceivingUpdates()
+}
+
+// GetMe проверяет доступность Bot API
+func GetMe() (tgbotapi.User, error) {
+	return bot.GetMe()
+}
+
+// GetWebhookInfo возвращает текущие настройки вебхука в Telegram
+func GetWebhookInfo() (tgbotapi.WebhookInfo, error) {
+	return bot.GetWebhookInfo()
 }
 
 func Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
 	return bot.Send(c)
 }
+
+func Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	return bot.Request(c)
+}
@@ -0,0 +1,227 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strings"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/services"
+)
+
+// categoriesHandler показывает категории команд с их составом
+func categoriesHandler(ctx context.Context, message *tgbotapi.Message) {
+	categories, err := db.GetTeamCategories()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting team categories", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while getting team categories."))
+		return
+	}
+	if len(categories) == 0 {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "No team categories yet. Add one with /add_team_category."))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString("Team categories:\n")
+	for _, category := range categories {
+		name := category.Name
+		if category.Query != nil {
+			name += " [" + services.DescribeTeamQuery(category.Query) + "]"
+		} else if category.FromCatalog() {
+			name += " [catalog]"
+		}
+		text.WriteString(fmt.Sprintf("\n%s (%d): %s\n", name, len(category.Teams), strings.Join(category.Teams, ", ")))
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, text.String()))
+}
+
+// parseCategoryArgs разбирает аргументы вида "<категория>, <значение>, ..."
+func parseCategoryArgs(arguments string) []string {
+	var args []string
+	for _, arg := range strings.Split(arguments, ",") {
+		if arg = strings.TrimSpace(arg); arg != "" {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+// replyCategoryResult сообщает об успехе или о причине, по которой изменение отклонено
+func replyCategoryResult(ctx context.Context, chatID int64, err error, success string) {
+	if err != nil {
+		slog.InfoContext(ctx, "Team category change rejected", "err", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Cannot change team categories: "+err.Error()))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, success))
+}
+
+func addTeamCategoryHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+	args := parseCategoryArgs(message.CommandArguments())
+	if len(args) < 3 {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /add_team_category <category_name>, <team1>, <team2>, ..."))
+		return
+	}
+
+	err := services.CreateTeamCategory(args[0], args[1:])
+	replyCategoryResult(ctx, message.Chat.ID, err, fmt.Sprintf("Team category %s added with %d teams.", args[0], len(args)-1))
+}
+
+func renameTeamCategoryHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+	oldName, newName, ok := parseNamePair(message.CommandArguments())
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /rename_team_category <old_name>, <new_name>"))
+		return
+	}
+
+	err := services.RenameTeamCategory(oldName, newName)
+	replyCategoryResult(ctx, message.Chat.ID, err, fmt.Sprintf("Team category %s renamed to %s.", oldName, newName))
+}
+
+func addCategoryTeamHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+	category, team, ok := parseNamePair(message.CommandArguments())
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /add_category_team <category_name>, <team>"))
+		return
+	}
+
+	err := services.AddTeamToCategory(category, team)
+	replyCategoryResult(ctx, message.Chat.ID, err, fmt.Sprintf("%s added to category %s.", team, category))
+}
+
+func removeCategoryTeamHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+	category, team, ok := parseNamePair(message.CommandArguments())
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /remove_category_team <category_name>, <team>"))
+		return
+	}
+
+	err := services.RemoveTeamFromCategory(category, team)
+	replyCategoryResult(ctx, message.Chat.ID, err, fmt.Sprintf("%s removed from category %s.", team, category))
+}
+
+func removeTeamCategoryHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+	categoryName := strings.TrimSpace(message.CommandArguments())
+	if categoryName == "" {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /remove_team_category <category_name>"))
+		return
+	}
+
+	err := services.DeleteTeamCategory(categoryName)
+	replyCategoryResult(ctx, message.Chat.ID, err, fmt.Sprintf("Team category %s removed.", categoryName))
+}
+
+// addCatalogCategoryHandler создает категорию из справочника команд: по условиям
+// (stars, league, country) или по списку команд справочника
+func addCatalogCategoryHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+	args := parseCategoryArgs(message.CommandArguments())
+	if len(args) < 2 {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /add_catalog_category <category_name>, stars 4-5, league <league>, country <country>\n"+
+			"or /add_catalog_category <category_name>, <team1>, <team2>, ..."))
+		return
+	}
+
+	name, rest := args[0], args[1:]
+	var err error
+	if services.IsTeamQueryCondition(rest[0]) {
+		var query *db.TeamQuery
+		query, err = services.ParseTeamQuery(rest)
+		if err == nil {
+			err = services.CreateCatalogCategory(name, query, nil)
+		}
+	} else {
+		err = services.CreateCatalogCategory(name, nil, rest)
+	}
+	replyCategoryResult(ctx, message.Chat.ID, err, fmt.Sprintf("Team category %s added.", name))
+}
+
+// teamHandler показывает карточку команды из справочника
+func teamHandler(ctx context.Context, message *tgbotapi.Message) {
+	name := strings.TrimSpace(message.CommandArguments())
+	if name == "" {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /team <team_name>"))
+		return
+	}
+
+	team, err := db.FindTeam(name)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error finding team", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while looking up the team."))
+		return
+	}
+	if team == nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Team %s is not in the catalog.", name)))
+		return
+	}
+
+	card := teamCard(team)
+	if team.Crest != "" {
+		var crest tgbotapi.RequestFileData = tgbotapi.FilePath(team.Crest)
+		if strings.HasPrefix(team.Crest, "http://") || strings.HasPrefix(team.Crest, "https://") {
+			crest = tgbotapi.FileURL(team.Crest)
+		}
+		photo := tgbotapi.NewPhoto(message.Chat.ID, crest)
+		photo.Caption = card
+		if _, err := bot.Send(photo); err == nil {
+			return
+		}
+		slog.WarnContext(ctx, "Error sending team crest", "team", team.Name, "crest", team.Crest)
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, card))
+}
+
+func teamCard(team *db.Team) string {
+	var card strings.Builder
+	card.WriteString(team.Name)
+	if team.ShortCode != "" {
+		card.WriteString(" (" + team.ShortCode + ")")
+	}
+	card.WriteString(fmt.Sprintf("\n%s %g★\n", strings.Repeat("⭐", int(team.Stars)), team.Stars))
+	if team.League != "" || team.Country != "" {
+		card.WriteString(strings.Trim(team.League+", "+team.Country, ", ") + "\n")
+	}
+	if team.Overall > 0 {
+		card.WriteString(fmt.Sprintf("Overall: %d\n", team.Overall))
+	}
+	if team.Attack+team.Midfield+team.Defence > 0 {
+		card.WriteString(fmt.Sprintf("ATT %d · MID %d · DEF %d\n", team.Attack, team.Midfield, team.Defence))
+	}
+	if len(team.Aliases) > 0 {
+		card.WriteString("Also known as: " + strings.Join(team.Aliases, ", ") + "\n")
+	}
+	return card.String()
+}
+
+func teamAliasHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+	team, alias, ok := parseNamePair(message.CommandArguments())
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /team_alias <catalog_team>, <other_name>"))
+		return
+	}
+
+	err := services.AddTeamAlias(team, alias)
+	replyCategoryResult(ctx, message.Chat.ID, err, fmt.Sprintf("%s is now also known as %s.", team, alias))
+}
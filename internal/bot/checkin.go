@@ -0,0 +1,228 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/notifications"
+	"tournament-bot/internal/services"
+)
+
+const defaultCheckInMinutes = 15
+
+func startCheckInHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	minutes := defaultCheckInMinutes
+	if args := strings.TrimSpace(message.CommandArguments()); args != "" {
+		var err error
+		minutes, err = strconv.Atoi(args)
+		if err != nil || minutes <= 0 {
+			bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /start_checkin [minutes]"))
+			return
+		}
+	}
+
+	pending := pendingTournamentOrReply(ctx, message.Chat.ID)
+	if pending == nil {
+		return
+	}
+
+	tournament, err := services.StartCheckIn(pending.ID, time.Duration(minutes)*time.Minute)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Could not start check-in: "+err.Error()))
+		return
+	}
+
+	err = notifications.SendCheckInStartMessage(tournament)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sending check-in start message", "err", err)
+	}
+
+	// Личные напоминания тем, кто привязал аккаунт
+	text := fmt.Sprintf("Check-in for %s is open until %s. Please confirm that you are playing.",
+		tournament.Name, tournament.CheckInDeadline.Format("15:04"))
+	for _, name := range append(tournament.Participants, tournament.Waitlist...) {
+		participant, err := db.FindParticipant(name)
+		if err != nil || participant == nil || participant.TelegramID == 0 {
+			continue
+		}
+		msg := tgbotapi.NewMessage(participant.TelegramID, text)
+		msg.ReplyMarkup = notifications.CheckInKeyboard(tournament.ID)
+		if _, err := bot.Send(msg); err != nil {
+			slog.ErrorContext(ctx, "Error sending check-in reminder", "participant_id", participant.ID, "err", err)
+		}
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Check-in started, closes at %s.", tournament.CheckInDeadline.Format("15:04"))))
+}
+
+func checkInHandler(ctx context.Context, message *tgbotapi.Message) {
+	tournament := pendingTournamentOrReply(ctx, message.Chat.ID)
+	if tournament == nil {
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, checkIn(ctx, tournament.ID, message.From.ID)))
+}
+
+func checkInCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	tournamentID, err := strconv.Atoi(strings.TrimPrefix(callback.Data, "checkin_"))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error converting tournament ID", "err", err)
+		return
+	}
+	bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, checkIn(ctx, tournamentID, callback.From.ID)))
+}
+
+// checkIn отмечает пользователя Telegram и возвращает текст ответа
+func checkIn(ctx context.Context, tournamentID int, telegramID int64) string {
+	participant, err := db.GetParticipantByTelegramID(telegramID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting participant", "err", err)
+		return "An error occurred, please try again later."
+	}
+	if participant == nil {
+		return "Your account is not linked to a participant."
+	}
+
+	err = services.CheckIn(tournamentID, participant.Name)
+	switch {
+	case errors.Is(err, services.ErrNotRegistered):
+		return "You are not registered for this tournament."
+	case errors.Is(err, services.ErrCheckInClosed):
+		return "Check-in is not open."
+	case err != nil:
+		slog.ErrorContext(ctx, "Error checking in", "tournament_id", tournamentID, "err", err)
+		return "An error occurred, please try again later."
+	default:
+		return "You are checked in. See you at the tournament!"
+	}
+}
+
+func closeCheckInHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	tournament := pendingTournamentOrReply(ctx, message.Chat.ID)
+	if tournament == nil {
+		return
+	}
+
+	result, err := services.CloseCheckIn(tournament.ID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Could not close check-in: "+err.Error()))
+		return
+	}
+	announceCheckInResult(ctx, result)
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Check-in closed. Participants: %d, dropped: %d, promoted from waitlist: %d.",
+		len(result.Tournament.Participants), len(result.Dropped), len(result.Promoted))))
+}
+
+// CloseExpiredCheckIns закрывает истекшие подтверждения участия; вызывается по расписанию
+func CloseExpiredCheckIns(ctx context.Context) {
+	results, err := services.CloseExpiredCheckIns()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error closing expired check-ins", "err", err)
+		return
+	}
+	for _, result := range results {
+		announceCheckInResult(ctx, result)
+	}
+}
+
+func announceCheckInResult(ctx context.Context, result *services.CheckInResult) {
+	err := notifications.SendCheckInClosedMessage(result.Tournament, result.Dropped, result.Promoted)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sending check-in closed message", "err", err)
+	}
+
+	for _, name := range result.Promoted {
+		notifyPromoted(ctx, result.Tournament, name)
+	}
+	for _, name := range result.Dropped {
+		participant, err := db.FindParticipant(name)
+		if err != nil || participant == nil || participant.TelegramID == 0 {
+			continue
+		}
+		text := fmt.Sprintf("You did not check in for %s in time and have been removed from the tournament.", result.Tournament.Name)
+		if _, err := bot.Send(tgbotapi.NewMessage(participant.TelegramID, text)); err != nil {
+			slog.ErrorContext(ctx, "Error notifying dropped participant", "participant_id", participant.ID, "err", err)
+		}
+	}
+}
+
+// withdrawPlayerHandler снимает участника: до старта — с освобождением места,
+// после старта — с техническими поражениями в оставшихся матчах
+func withdrawPlayerHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	name := strings.TrimSpace(message.CommandArguments())
+	if name == "" {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /withdraw_player <name>"))
+		return
+	}
+	participant := findParticipantOrReply(ctx, message.Chat.ID, name)
+	if participant == nil {
+		return
+	}
+
+	active, err := services.GetActiveTournament()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting active tournament", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while checking for active tournament."))
+		return
+	}
+
+	if active != nil && active.HasParticipant(participant.Name) {
+		forfeits, err := services.WithdrawWithForfeits(active.ID, participant.Name)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error withdrawing participant", "tournament_id", active.ID, "err", err)
+			bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Could not withdraw participant: "+err.Error()))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("%s has withdrawn. %d remaining matches were awarded as forfeits.", participant.Name, len(forfeits))))
+		return
+	}
+
+	pending := pendingTournamentOrReply(ctx, message.Chat.ID)
+	if pending == nil {
+		return
+	}
+	promoted, err := services.WithdrawFromTournament(pending.ID, participant.Name)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Could not withdraw participant: "+err.Error()))
+		return
+	}
+	notifyPromoted(ctx, pending, promoted)
+
+	text := fmt.Sprintf("%s has been removed from %s.", participant.Name, pending.Name)
+	if promoted != "" {
+		text += fmt.Sprintf(" %s was promoted from the waitlist.", promoted)
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, text))
+}
+
+func pendingTournamentOrReply(ctx context.Context, chatID int64) *db.Tournament {
+	tournament, err := services.GetPendingTournament()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting pending tournament", "err", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "An error occurred while looking for the tournament."))
+		return nil
+	}
+	if tournament == nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "There is no tournament waiting to start."))
+	}
+	return tournament
+}
@@ -0,0 +1,283 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"tournament-bot/internal/logging"
+	"tournament-bot/internal/metrics"
+)
+
+// Сколько последних update_id помним для отсева повторных доставок от Telegram
+const recentUpdatesLimit = 1024
+
+var (
+	ErrQueueFull        = errors.New("update queue is full")
+	ErrDispatcherClosed = errors.New("update dispatcher is not running")
+)
+
+// dispatcher раздает обновления по воркерам так, чтобы все обновления одного чата
+// обрабатывались одним и тем же воркером строго по порядку
+type dispatcher struct {
+	mu      sync.Mutex
+	queues  []chan tgbotapi.Update
+	wg      sync.WaitGroup
+	closed  bool
+	seen    map[int]struct{}
+	seenLog []int
+	seenPos int
+}
+
+var updates *dispatcher
+
+// StartDispatcher запускает пул воркеров для обработки входящих обновлений
+func StartDispatcher(workers, queueSize int) {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	d := &dispatcher{
+		queues:  make([]chan tgbotapi.Update, workers),
+		seen:    make(map[int]struct{}, recentUpdatesLimit),
+		seenLog: make([]int, 0, recentUpdatesLimit),
+	}
+
+	for i := range d.queues {
+		d.queues[i] = make(chan tgbotapi.Update, queueSize)
+		d.wg.Add(1)
+		go d.worker(d.queues[i])
+	}
+
+	updates = d
+}
+
+// Enqueue ставит обновление в очередь воркера соответствующего чата.
+// Повторно доставленные обновления (с уже виденным update_id) молча отбрасываются.
+func Enqueue(update tgbotapi.Update) error {
+	if updates == nil {
+		return ErrDispatcherClosed
+	}
+	return updates.enqueue(update)
+}
+
+// StopDispatcher перестает принимать обновления и дожидается завершения уже начатой обработки
+func StopDispatcher(ctx context.Context) error {
+	if updates == nil {
+		return nil
+	}
+
+	updates.mu.Lock()
+	if !updates.closed {
+		updates.closed = true
+		for _, queue := range updates.queues {
+			close(queue)
+		}
+	}
+	updates.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		updates.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *dispatcher) enqueue(update tgbotapi.Update) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+
+	if _, ok := d.seen[update.UpdateID]; ok {
+		return nil
+	}
+
+	queue := d.queues[queueIndex(updateChatID(update), len(d.queues))]
+	select {
+	case queue <- update:
+	default:
+		// Не запоминаем update_id, чтобы Telegram мог доставить обновление повторно
+		return ErrQueueFull
+	}
+
+	d.remember(update.UpdateID)
+	return nil
+}
+
+func (d *dispatcher) remember(updateID int) {
+	if len(d.seenLog) < recentUpdatesLimit {
+		d.seenLog = append(d.seenLog, updateID)
+	} else {
+		delete(d.seen, d.seenLog[d.seenPos])
+		d.seenLog[d.seenPos] = updateID
+		d.seenPos = (d.seenPos + 1) % recentUpdatesLimit
+	}
+	d.seen[updateID] = struct{}{}
+}
+
+func (d *dispatcher) worker(queue <-chan tgbotapi.Update) {
+	defer d.wg.Done()
+	for update := range queue {
+		processUpdate(update)
+	}
+}
+
+func processUpdate(update tgbotapi.Update) {
+	command := updateCommand(update)
+	ctx := logging.WithAttrs(context.Background(),
+		"update_id", update.UpdateID,
+		"chat_id", updateChatID(update),
+		"user_id", updateUserID(update),
+		"command", command,
+	)
+
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(ctx, "Panic while processing update", "panic", r)
+		}
+		metrics.UpdatesProcessed.WithLabelValues(command).Inc()
+		metrics.HandlerDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+	}()
+
+	if update.Message != nil {
+		HandleMessage(ctx, update.Message)
+	} else if update.CallbackQuery != nil {
+		СallbackHandler(ctx, update.CallbackQuery)
+	}
+}
+
+// Команды, которые попадают в метрики под своим именем. Остальные учитываются
+// как "unknown", чтобы произвольный ввод пользователей не раздувал число серий.
+var knownCommands = map[string]bool{
+	"add_participant":      true,
+	"create_tournament":    true,
+	"end_tournament":       true,
+	"delete_tournament":    true,
+	"categories":           true,
+	"add_team_category":    true,
+	"rename_team_category": true,
+	"add_category_team":    true,
+	"remove_category_team": true,
+	"remove_team_category": true,
+	"add_catalog_category": true,
+	"team":                 true,
+	"team_alias":           true,
+	"add_match":            true,
+	"addadmin":             true,
+	"removeadmin":          true,
+	"tournament_info":      true,
+	"deletelastmatch":      true,
+	"start_playoff":        true,
+	"cancel":               true,
+
+	"register":              true,
+	"approve_link":          true,
+	"reject_link":           true,
+	"join":                  true,
+	"withdraw":              true,
+	"registration_deadline": true,
+
+	"start_checkin":   true,
+	"checkin":         true,
+	"close_checkin":   true,
+	"withdraw_player": true,
+
+	"match_status": true,
+
+	"delete_template": true,
+	"pick_team":       true,
+	"verify_draw":     true,
+	"cancel_draw":     true,
+
+	"rating":              true,
+	"h2h":                 true,
+	"profile":             true,
+	"rating_history":      true,
+	"recalculate_ratings": true,
+
+	"season_start": true,
+	"season_end":   true,
+	"seasons":      true,
+	"season":       true,
+
+	"season_scoring":     true,
+	"recalculate_season": true,
+
+	"rename_participant": true,
+	"merge_participants": true,
+	"rebuild_stats":      true,
+}
+
+// updateCommand возвращает метку обновления для метрик: имя команды,
+// префикс данных callback-кнопки (без идентификаторов) или "message"
+func updateCommand(update tgbotapi.Update) string {
+	switch {
+	case update.Message != nil:
+		if !update.Message.IsCommand() {
+			return "message"
+		}
+		if knownCommands[update.Message.Command()] {
+			return update.Message.Command()
+		}
+		return "unknown"
+	case update.CallbackQuery != nil:
+		var prefix []string
+		for _, part := range strings.Split(update.CallbackQuery.Data, "_") {
+			if _, err := strconv.Atoi(part); err == nil {
+				break
+			}
+			prefix = append(prefix, part)
+		}
+		return "callback:" + strings.Join(prefix, "_")
+	default:
+		return "other"
+	}
+}
+
+// updateChatID возвращает идентификатор чата, по которому упорядочивается обработка
+func updateChatID(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID
+	case update.CallbackQuery != nil:
+		if update.CallbackQuery.Message != nil {
+			return update.CallbackQuery.Message.Chat.ID
+		}
+		return update.CallbackQuery.From.ID
+	default:
+		return 0
+	}
+}
+
+func updateUserID(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil && update.Message.From != nil:
+		return update.Message.From.ID
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.From.ID
+	default:
+		return 0
+	}
+}
+
+func queueIndex(chatID int64, workers int) int {
+	if chatID < 0 {
+		chatID = -chatID
+	}
+	return int(chatID % int64(workers))
+}
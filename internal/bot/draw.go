@@ -0,0 +1,472 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/notifications"
+	"tournament-bot/internal/services"
+)
+
+// teamAssignmentButton — кнопка под списком участников, которая переходит
+// к распределению команд выбранным в турнире способом
+func teamAssignmentButton(tournament *db.Tournament) tgbotapi.InlineKeyboardButton {
+	switch {
+	case tournament.Strategy() == db.DrawFreePick:
+		// Команды выбраны участниками, жеребьевка не нужна
+		return tgbotapi.NewInlineKeyboardButtonData("Start Tournament", fmt.Sprintf("free_choice_start_%d", tournament.ID))
+	case tournament.TeamCategory == "":
+		return tgbotapi.NewInlineKeyboardButtonData("Select Category", fmt.Sprintf("select_category_%d", tournament.ID))
+	case tournament.Strategy() == db.DrawDraft:
+		return tgbotapi.NewInlineKeyboardButtonData("Start Draft", fmt.Sprintf("draft_start_%d", tournament.ID))
+	default:
+		// Категория выбрана в мастере
+		return tgbotapi.NewInlineKeyboardButtonData("Draw Teams & Start",
+			fmt.Sprintf("category_selected_%d_%s", tournament.ID, tournament.TeamCategory))
+	}
+}
+
+// drawTeamsAndStart распределяет команды жеребьевкой и запускает турнир. Для драфта
+// и свободного выбора вместо жеребьевки предлагает игрокам выбрать команды.
+// Если в турнире включена церемония, жеребьевка показывается в канале.
+func drawTeamsAndStart(ctx context.Context, chatID int64, tournamentID int) {
+	tournament, err := services.GetTournament(tournamentID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting tournament", "err", err)
+		return
+	}
+	if services.IsInteractiveDraw(tournament.Strategy()) {
+		if tournament.Strategy() == db.DrawDraft {
+			startDraft(ctx, chatID, tournamentID)
+			return
+		}
+		msg := tgbotapi.NewMessage(chatID, "Players pick their teams with /pick_team. Press the button when everyone has picked.")
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(teamAssignmentButton(tournament)))
+		bot.Send(msg)
+		return
+	}
+
+	if tournament.DrawCeremony {
+		// Хэш публикуется до того, как станет известен результат, а жеребьевка
+		// проводится отдельным шагом по кнопке
+		publishDrawCommitment(ctx, chatID, tournamentID)
+		return
+	}
+	revealDraw(ctx, chatID, tournamentID)
+}
+
+// publishDrawCommitment фиксирует жеребьевку, публикует ее хэш в канале и предлагает
+// провести жеребьевку кнопкой. Опубликованный хэш повторно не публикуется.
+func publishDrawCommitment(ctx context.Context, chatID int64, tournamentID int) {
+	tournament, err := services.CommitDraw(tournamentID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error committing team draw", "err", err)
+		bot.Send(tgbotapi.NewMessage(chatID, drawFailedText(err)))
+		return
+	}
+
+	if tournament.Draw.PublishedAt.IsZero() {
+		err = notifications.SendDrawCommitmentMessage(tournament, services.DrawStrategyLabel(tournament.Strategy()))
+		if err != nil {
+			slog.ErrorContext(ctx, "Error sending draw commitment message", "err", err)
+			bot.Send(tgbotapi.NewMessage(chatID, "Could not publish the draw commitment, please try again."))
+			return
+		}
+		err = services.MarkDrawPublished(tournamentID, tournament.Draw.Commitment)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error marking draw commitment as published", "err", err)
+			bot.Send(tgbotapi.NewMessage(chatID, "Team draw failed: "+err.Error()))
+			return
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "The draw commitment has been published in the channel. Press the button to run the draw.")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Reveal Draw & Start", fmt.Sprintf("draw_reveal_%d", tournamentID))))
+	bot.Send(msg)
+}
+
+// drawRevealCallback проводит жеребьевку, хэш которой уже опубликован
+func drawRevealCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	tournamentID, err := strconv.Atoi(strings.TrimPrefix(callback.Data, "draw_reveal_"))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error converting tournament ID", "err", err)
+		return
+	}
+
+	tournament, err := services.GetTournament(tournamentID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting tournament", "err", err)
+		return
+	}
+	if tournament.IsActive {
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "The tournament has already started."))
+		return
+	}
+	if tournament.Draw == nil || tournament.Draw.PublishedAt.IsZero() {
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "The draw commitment has not been published yet."))
+		return
+	}
+
+	removeKeyboard(callback.Message.Chat.ID, callback.Message.MessageID)
+	revealDraw(ctx, callback.Message.Chat.ID, tournamentID)
+	bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+}
+
+// revealDraw проводит жеребьевку по зафиксированным данным и запускает турнир
+func revealDraw(ctx context.Context, chatID int64, tournamentID int) {
+	drawResult, err := services.PerformTeamDraw(tournamentID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error performing team draw", "err", err)
+		bot.Send(tgbotapi.NewMessage(chatID, drawFailedText(err)))
+		return
+	}
+
+	tournament, err := services.GetTournament(tournamentID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting tournament", "err", err)
+		return
+	}
+	// Зерно позволяет повторить жеребьевку и убедиться, что результат не подменен
+	drawResult += fmt.Sprintf("\nDraw: %s, seed %d\n", services.DrawStrategyLabel(tournament.Strategy()), tournament.DrawSeed)
+	if score, ok := services.BalanceScore(tournament.Draw, tournament.ParticipantTeams); ok {
+		drawResult += fmt.Sprintf("Expected balance: %d/100\n", score)
+	}
+
+	if !tournament.DrawCeremony {
+		startTournamentWithTeams(ctx, chatID, tournamentID, "Team draw result:\n"+drawResult)
+		return
+	}
+
+	// Сообщение о старте турнира уйдет в канал после показа жеребьевки
+	started := startTournament(ctx, chatID, tournamentID, "Team draw result:\n"+drawResult)
+	if started == nil {
+		return
+	}
+	err = notifications.SendDrawCeremony(started, func() error {
+		return notifications.SendTournamentStartMessage(started)
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sending draw ceremony", "err", err)
+	}
+}
+
+// drawFailedText — ответ админу на ошибку жеребьевки
+func drawFailedText(err error) string {
+	if errors.Is(err, services.ErrDrawPublished) {
+		return "Team draw failed: the participants changed after the draw commitment was published. " +
+			"Cancel the draw with /cancel_draw and run it again."
+	}
+	return "Team draw failed: " + err.Error()
+}
+
+// cancelDrawHandler отменяет зафиксированную жеребьевку турнира, который ждет старта.
+// Если хэш уже опубликован, в канале объявляется, что он больше не действует.
+func cancelDrawHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	tournament := pendingTournamentOrReply(ctx, message.Chat.ID)
+	if tournament == nil {
+		return
+	}
+
+	record, err := services.CancelDraw(tournament.ID)
+	if errors.Is(err, services.ErrNoDraw) {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "The draw has not been committed yet, there is nothing to cancel."))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error cancelling team draw", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Could not cancel the draw: "+err.Error()))
+		return
+	}
+
+	if !record.PublishedAt.IsZero() {
+		err = notifications.SendDrawCancelledMessage(tournament, record)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error sending draw cancelled message", "err", err)
+			bot.Send(tgbotapi.NewMessage(message.Chat.ID, "The draw was cancelled, but the announcement could not be sent to the channel."))
+			return
+		}
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, "The draw has been cancelled. Press the button to commit a new draw.")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(teamAssignmentButton(tournament)))
+	bot.Send(msg)
+}
+
+// verifyDrawHandler повторяет жеребьевку турнира по опубликованному зерну
+func verifyDrawHandler(ctx context.Context, message *tgbotapi.Message) {
+	var tournamentID int
+	if args := strings.TrimSpace(message.CommandArguments()); args != "" {
+		id, err := strconv.Atoi(args)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /verify_draw [tournament_id]"))
+			return
+		}
+		tournamentID = id
+	} else {
+		tournament, err := services.GetActiveTournament()
+		if err != nil || tournament == nil {
+			bot.Send(tgbotapi.NewMessage(message.Chat.ID, "No active tournament. Usage: /verify_draw <tournament_id>"))
+			return
+		}
+		tournamentID = tournament.ID
+	}
+
+	verification, err := services.VerifyDraw(tournamentID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Cannot verify the draw: "+err.Error()))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("Draw of tournament %d\nSeed: %d\nCommitment: %s\n\n", tournamentID, verification.Seed, verification.Commitment))
+	if verification.CommitmentOK {
+		text.WriteString("✅ The seed matches the published commitment\n")
+	} else {
+		text.WriteString("❌ The seed does NOT match the published commitment\n")
+	}
+	if verification.ResultOK {
+		text.WriteString("✅ Re-running the draw gives the same teams\n")
+	} else {
+		text.WriteString("❌ Re-running the draw gives different teams:\n")
+		for _, participant := range verification.Participants {
+			text.WriteString(fmt.Sprintf("%s - %s\n", participant, verification.Expected[participant]))
+		}
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, text.String()))
+}
+
+// startTournamentWithTeams запускает турнир, в котором команды уже распределены
+func startTournamentWithTeams(ctx context.Context, chatID int64, tournamentID int, teams string) {
+	updatedTournament := startTournament(ctx, chatID, tournamentID, teams)
+	if updatedTournament == nil {
+		return
+	}
+
+	err := notifications.SendTournamentStartMessage(updatedTournament)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sending tournament start message", "err", err)
+	}
+}
+
+// startTournament запускает турнир и сообщает об этом в чат без сообщения в канале.
+// Возвращает nil, если турнир не запущен.
+func startTournament(ctx context.Context, chatID int64, tournamentID int, teams string) *db.Tournament {
+	updatedTournament, err := services.StartTournament(tournamentID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error starting tournament", "err", err)
+		bot.Send(tgbotapi.NewMessage(chatID, err.Error()))
+		return nil
+	}
+
+	// Отправляем сообщение с результатом распределения и информацией о начале турнира
+	_, err = bot.Send(tgbotapi.NewMessage(chatID, teams+"\nThe tournament has started!"))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sending message", "err", err)
+	}
+	return updatedTournament
+}
+
+func draftStartCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	tournamentID, err := strconv.Atoi(strings.TrimPrefix(callback.Data, "draft_start_"))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error converting tournament ID", "err", err)
+		return
+	}
+	startDraft(ctx, callback.Message.Chat.ID, tournamentID)
+	bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+}
+
+func startDraft(ctx context.Context, chatID int64, tournamentID int) {
+	tournament, err := services.StartDraft(tournamentID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Could not start the draft: "+err.Error()))
+		return
+	}
+
+	text, keyboard, err := draftMessage(tournament)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error building draft message", "err", err)
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = keyboard
+	bot.Send(msg)
+}
+
+// draftPickCallback записывает выбор команды. Выбирать может игрок, чья очередь,
+// или админ за него.
+func draftPickCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	parts := strings.SplitN(callback.Data, "_", 4)
+	if len(parts) != 4 {
+		return
+	}
+	tournamentID, err := strconv.Atoi(parts[2])
+	if err != nil {
+		slog.ErrorContext(ctx, "Error converting tournament ID", "err", err)
+		return
+	}
+	team := parts[3]
+
+	tournament, err := services.GetTournament(tournamentID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting tournament", "err", err)
+		return
+	}
+	picker := services.DraftPicker(tournament)
+	if picker == "" {
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "The draft is already finished."))
+		return
+	}
+
+	isAdmin, err := db.IsAdmin(callback.From.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error checking admin status", "err", err)
+	}
+	if !isAdmin {
+		participant, err := db.GetParticipantByTelegramID(callback.From.ID)
+		if err != nil || participant == nil || participant.Name != picker {
+			bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, fmt.Sprintf("It is %s's turn to pick.", picker)))
+			return
+		}
+	}
+
+	tournament, err = services.DraftPick(tournamentID, picker, team)
+	if err != nil {
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, err.Error()))
+		return
+	}
+	bot.Request(tgbotapi.NewCallback(callback.ID, fmt.Sprintf("%s picked %s", picker, team)))
+
+	if services.DraftPicker(tournament) != "" {
+		text, keyboard, err := draftMessage(tournament)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error building draft message", "err", err)
+			return
+		}
+		bot.Send(tgbotapi.NewEditMessageTextAndMarkup(callback.Message.Chat.ID, callback.Message.MessageID, text, keyboard))
+		return
+	}
+
+	// Все выбрали: создаем таблицу и запускаем турнир
+	removeKeyboard(callback.Message.Chat.ID, callback.Message.MessageID)
+	teams, err := services.CompleteTeamPicks(tournamentID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "Could not finish the draft: "+err.Error()))
+		return
+	}
+	startTournamentWithTeams(ctx, callback.Message.Chat.ID, tournamentID, "Draft result:\n"+teams)
+}
+
+// draftMessage показывает сделанные выборы, чья очередь и свободные команды
+func draftMessage(tournament *db.Tournament) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	available, err := services.DraftAvailableTeams(tournament)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+
+	var text strings.Builder
+	text.WriteString("🎯 Draft\n\n")
+	for i, participant := range tournament.DraftOrder {
+		team := tournament.ParticipantTeams[participant]
+		if team == "" {
+			team = "…"
+		}
+		text.WriteString(fmt.Sprintf("%d. %s - %s\n", i+1, participant, team))
+	}
+	text.WriteString(fmt.Sprintf("\nNow picking: %s", services.DraftPicker(tournament)))
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, team := range available {
+		callbackData := fmt.Sprintf("draft_pick_%d_%s", tournament.ID, team)
+		rows = append(rows, []tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData(team, callbackData)})
+	}
+	return text.String(), tgbotapi.NewInlineKeyboardMarkup(rows...), nil
+}
+
+// pickTeamHandler закрепляет команду за участником турнира со свободным выбором:
+// игрок выбирает себе, админ может указать участника через запятую
+func pickTeamHandler(ctx context.Context, message *tgbotapi.Message) {
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /pick_team <team> (admins: /pick_team <participant>, <team>)"))
+		return
+	}
+
+	var participantName, team string
+	if name, pickedTeam, ok := parseNamePair(args); ok {
+		if !requireAdmin(ctx, message) {
+			return
+		}
+		participant := findParticipantOrReply(ctx, message.Chat.ID, name)
+		if participant == nil {
+			return
+		}
+		participantName, team = participant.Name, pickedTeam
+	} else {
+		participant, err := db.GetParticipantByTelegramID(message.From.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Error getting participant", "err", err)
+			bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred, please try again later."))
+			return
+		}
+		if participant == nil {
+			bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Your account is not linked to a participant yet. Use /register first."))
+			return
+		}
+		participantName, team = participant.Name, args
+	}
+
+	tournament := pendingTournamentOrReply(ctx, message.Chat.ID)
+	if tournament == nil {
+		return
+	}
+
+	err := services.PickTeam(tournament.ID, participantName, team)
+	if errors.Is(err, services.ErrNotRegistered) {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("%s is not registered for %s.", participantName, tournament.Name)))
+		return
+	}
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Could not pick the team: "+err.Error()))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("%s will play as %s.", participantName, strings.TrimSpace(team))))
+}
+
+// freeChoiceStartCallback запускает турнир, в котором участники выбрали команды сами
+func freeChoiceStartCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	tournamentID, err := strconv.Atoi(strings.TrimPrefix(callback.Data, "free_choice_start_"))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error converting tournament ID", "err", err)
+		return
+	}
+
+	tournament, err := services.GetTournament(tournamentID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting tournament", "err", err)
+		return
+	}
+	if tournament.IsActive {
+		bot.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "The tournament has already started."))
+		return
+	}
+
+	teams, err := services.CompleteTeamPicks(tournamentID)
+	if err != nil {
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, err.Error()))
+		return
+	}
+
+	startTournamentWithTeams(ctx, callback.Message.Chat.ID, tournamentID, "Teams:\n"+teams)
+	bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+}
@@ -5,12 +5,13 @@ import (
 	"errors"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"go.mongodb.org/mongo-driver/bson"
-	"log"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log/slog"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"tournament-bot/internal/db"
 	"tournament-bot/internal/notifications"
 	"tournament-bot/internal/services"
@@ -33,57 +34,172 @@ type TeamSelectionState struct {
 	AwaitingScore     bool
 }
 
-var teamSelectionStates = make(map[int64]*TeamSelectionState)
+// Состояния ввода результатов матчей по пользователям. Обновления разных чатов
+// обрабатываются параллельно, поэтому доступ к карте защищен мьютексом.
+type teamSelectionStore struct {
+	mu     sync.Mutex
+	states map[int64]*TeamSelectionState
+}
+
+func (s *teamSelectionStore) Get(userID int64) (*TeamSelectionState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[userID]
+	return state, ok
+}
 
-func HandleMessage(message *tgbotapi.Message) {
+func (s *teamSelectionStore) Set(userID int64, state *TeamSelectionState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[userID] = state
+}
+
+func (s *teamSelectionStore) Delete(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, userID)
+}
+
+var teamSelectionStates = &teamSelectionStore{states: make(map[int64]*TeamSelectionState)}
+
+func HandleMessage(ctx context.Context, message *tgbotapi.Message) {
 	if message.IsCommand() {
 		switch message.Command() {
 		case "add_participant":
-			addParticipantHandler(message)
+			addParticipantHandler(ctx, message)
 		case "create_tournament":
-			createTournamentHandler(message)
+			createTournamentHandler(ctx, message)
 		case "end_tournament":
-			endTournament(message)
+			endTournament(ctx, message)
 		case "delete_tournament":
-			HandleDeleteTournament(message)
+			HandleDeleteTournament(ctx, message)
+		case "categories":
+			categoriesHandler(ctx, message)
 		case "add_team_category":
-			addTeamCategoryHandler(message)
+			addTeamCategoryHandler(ctx, message)
+		case "rename_team_category":
+			renameTeamCategoryHandler(ctx, message)
+		case "add_category_team":
+			addCategoryTeamHandler(ctx, message)
+		case "remove_category_team":
+			removeCategoryTeamHandler(ctx, message)
+		case "remove_team_category":
+			removeTeamCategoryHandler(ctx, message)
+		case "add_catalog_category":
+			addCatalogCategoryHandler(ctx, message)
+		case "team":
+			teamHandler(ctx, message)
+		case "team_alias":
+			teamAliasHandler(ctx, message)
 		case "add_match":
-			addMatchHandler(message)
+			addMatchHandler(ctx, message)
 		case "addadmin":
-			handleAddAdminCommand(message)
+			handleAddAdminCommand(ctx, message)
 		case "removeadmin":
-			handleRemoveAdminCommand(message)
+			handleRemoveAdminCommand(ctx, message)
 
 		case "tournament_info":
-			tournamentInfoHandler(message)
+			tournamentInfoHandler(ctx, message)
 		case "deletelastmatch":
-			deleteLastMatchHandler(message)
+			deleteLastMatchHandler(ctx, message)
 
 		case "start_playoff":
-			startPlayoffHandler(message)
+			startPlayoffHandler(ctx, message)
+
+		case "register":
+			registerHandler(ctx, message)
+		case "approve_link":
+			approveLinkHandler(ctx, message)
+		case "reject_link":
+			rejectLinkHandler(ctx, message)
+		case "join":
+			joinHandler(ctx, message)
+		case "withdraw":
+			withdrawHandler(ctx, message)
+		case "registration_deadline":
+			registrationDeadlineHandler(ctx, message)
+
+		case "start_checkin":
+			startCheckInHandler(ctx, message)
+		case "checkin":
+			checkInHandler(ctx, message)
+		case "close_checkin":
+			closeCheckInHandler(ctx, message)
+		case "withdraw_player":
+			withdrawPlayerHandler(ctx, message)
+
+		case "match_status":
+			matchStatusHandler(ctx, message)
+
+		case "delete_template":
+			deleteTemplateHandler(ctx, message)
+		case "pick_team":
+			pickTeamHandler(ctx, message)
+		case "verify_draw":
+			verifyDrawHandler(ctx, message)
+		case "cancel_draw":
+			cancelDrawHandler(ctx, message)
+
+		case "rating":
+			ratingHandler(ctx, message)
+		case "profile":
+			profileHandler(ctx, message)
+		case "h2h":
+			h2hHandler(ctx, message)
+		case "rating_history":
+			ratingHistoryHandler(ctx, message)
+		case "recalculate_ratings":
+			recalculateRatingsHandler(ctx, message)
+
+		case "season_start":
+			seasonStartHandler(ctx, message)
+		case "season_end":
+			seasonEndHandler(ctx, message)
+		case "seasons":
+			seasonsHandler(ctx, message)
+		case "season":
+			seasonHandler(ctx, message)
+		case "season_scoring":
+			seasonScoringHandler(ctx, message)
+		case "recalculate_season":
+			recalculateSeasonHandler(ctx, message)
+
+		case "rename_participant":
+			renameParticipantHandler(ctx, message)
+		case "merge_participants":
+			mergeParticipantsHandler(ctx, message)
+		case "rebuild_stats":
+			rebuildStatsHandler(ctx, message)
 		case "cancel":
+			if _, ok := tournamentWizards.Get(message.From.ID); ok {
+				tournamentWizards.Delete(message.From.ID)
+				bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Tournament setup cancelled."))
+			}
 			// Проверка наличия активного состояния выбора команд для пользователя
-			_, ok := teamSelectionStates[message.From.ID]
+			_, ok := teamSelectionStates.Get(message.From.ID)
 			if ok {
 				// Отправка сообщения о прерывании процесса
 				msg := tgbotapi.NewMessage(message.Chat.ID, "Текущий процесс добавления результата матча был прерван. Вы можете начать новый процесс с помощью команды /add_match.")
 				bot.Send(msg)
 
 				// Сброс состояния выбора команд для пользователя
-				delete(teamSelectionStates, message.From.ID)
+				teamSelectionStates.Delete(message.From.ID)
 			}
 		}
 
 	} else {
+		// Текстовые значения для мастера создания турнира
+		if handleTournamentWizardInput(ctx, message) {
+			return
+		}
 		// Проверяем, есть ли активное состояние выбора команд для пользователя
-		state, ok := teamSelectionStates[message.From.ID]
+		state, ok := teamSelectionStates.Get(message.From.ID)
 		if ok {
 			switch state.ConversationState {
 			case StateAwaitingScore1, StateAwaitingScore2:
 				// Проверяем, является ли сообщение числом (счетом)
 				if _, err := strconv.Atoi(message.Text); err == nil {
-					handleScoreInput(message)
+					handleScoreInput(ctx, message)
 				} else {
 					// Отправляем сообщение о некорректном вводе
 					msg := tgbotapi.NewMessage(message.Chat.ID, "Пожалуйста, введите корректный счет (целое число).")
@@ -92,7 +208,7 @@ func HandleMessage(message *tgbotapi.Message) {
 			case StateAwaitingOvertimeScore, StateAwaitingPenaltiesScore:
 				// Проверяем, является ли сообщение счетом в формате "команда1:команда2"
 				if strings.Contains(message.Text, ":") {
-					handleScoreInput(message)
+					handleScoreInput(ctx, message)
 				} else {
 					// Отправляем сообщение о некорректном вводе
 					msg := tgbotapi.NewMessage(message.Chat.ID, "Пожалуйста, введите счет в формате 'команда1:команда2'.")
@@ -103,7 +219,7 @@ func HandleMessage(message *tgbotapi.Message) {
 	}
 }
 
-func addParticipantHandler(message *tgbotapi.Message) {
+func addParticipantHandler(ctx context.Context, message *tgbotapi.Message) {
 	// Получаем имя и фамилию участника из аргументов команды
 	participantName := strings.TrimSpace(message.CommandArguments())
 	if participantName == "" {
@@ -120,7 +236,7 @@ func addParticipantHandler(message *tgbotapi.Message) {
 	// Проверяем, что участник еще не был добавлен
 	exists, err := db.ParticipantExists(participantName)
 	if err != nil {
-		log.Printf("Error checking participant existence: %v", err)
+		slog.ErrorContext(ctx, "Error checking participant existence", "err", err)
 		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while checking participant existence."))
 		return
 	}
@@ -131,8 +247,12 @@ func addParticipantHandler(message *tgbotapi.Message) {
 
 	// Добавляем участника в базу данных
 	err = db.AddParticipant(participantName)
+	if mongo.IsDuplicateKeyError(err) {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Participant %s already exists.", participantName)))
+		return
+	}
 	if err != nil {
-		log.Printf("Error adding participant: %v", err)
+		slog.ErrorContext(ctx, "Error adding participant", "err", err)
 		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while adding the participant."))
 		return
 	}
@@ -145,11 +265,11 @@ func isValidName(name string) bool {
 	return regexp.MustCompile(`^[a-zA-Zа-яА-Я\s]+$`).MatchString(name)
 }
 
-func createTournamentHandler(message *tgbotapi.Message) {
+func createTournamentHandler(ctx context.Context, message *tgbotapi.Message) {
 	// Проверяем, является ли пользователь администратором
 	isAdmin, err := db.IsAdmin(message.From.ID)
 	if err != nil {
-		log.Printf("Error checking admin status: %v", err)
+		slog.ErrorContext(ctx, "Error checking admin status", "err", err)
 		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred while checking your admin status.")
 		bot.Send(msg)
 		return
@@ -162,7 +282,7 @@ func createTournamentHandler(message *tgbotapi.Message) {
 	// Проверка наличия активного турнира
 	activeTournament, err := services.GetActiveTournament()
 	if err != nil {
-		log.Printf("Error getting active tournament: %v", err)
+		slog.ErrorContext(ctx, "Error getting active tournament", "err", err)
 		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while checking for active tournament."))
 		return
 	}
@@ -172,29 +292,15 @@ func createTournamentHandler(message *tgbotapi.Message) {
 		return
 	}
 
-	// Создание нового турнира
-	tournament, err := services.CreateTournament()
-	if err != nil {
-		log.Printf("Error creating tournament: %v", err)
-		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while creating the tournament."))
-		return
-	}
-
-	// Отправка сообщения с кнопками для добавления участников
-	msg := tgbotapi.NewMessage(message.Chat.ID, "A new tournament has been created. Add participants:")
-	msg.ReplyMarkup, _ = getParticipantsKeyboard(tournament.ID)
-	_, err = bot.Send(msg)
-	if err != nil {
-		log.Printf("Error sending message: %v", err)
-		return
-	}
+	// Турнир создается по шаблону или через мастер настроек
+	sendTournamentWizardStart(ctx, message.Chat.ID)
 }
 
-func endTournamentHandler(message *tgbotapi.Message) {
+func endTournamentHandler(ctx context.Context, message *tgbotapi.Message) {
 	// Получение активного турнира
 	activeTournament, err := services.GetActiveTournament()
 	if err != nil {
-		log.Printf("Error getting active tournament: %v", err)
+		slog.ErrorContext(ctx, "Error getting active tournament", "err", err)
 		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while checking for active tournament."))
 		return
 	}
@@ -207,7 +313,7 @@ func endTournamentHandler(message *tgbotapi.Message) {
 	// Завершение активного турнира
 	err = services.EndTournament(activeTournament.ID)
 	if err != nil {
-		log.Printf("Error ending tournament: %v", err)
+		slog.ErrorContext(ctx, "Error ending tournament", "err", err)
 		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while ending the tournament."))
 		return
 	}
@@ -215,11 +321,11 @@ func endTournamentHandler(message *tgbotapi.Message) {
 	bot.Send(tgbotapi.NewMessage(message.Chat.ID, "The active tournament has been ended."))
 }
 
-func getParticipantsKeyboard(tournamentID int) (tgbotapi.InlineKeyboardMarkup, error) {
+func getParticipantsKeyboard(ctx context.Context, tournamentID int) (tgbotapi.InlineKeyboardMarkup, error) {
 	// Получаем список всех участников из базы данных
-	participants, err := db.GetAllParticipants()
+	participants, err := db.GetAllParticipantsWithStats()
 	if err != nil {
-		log.Printf("Error getting participants: %v", err)
+		slog.ErrorContext(ctx, "Error getting participants", "err", err)
 		return tgbotapi.InlineKeyboardMarkup{}, err
 	}
 
@@ -227,24 +333,28 @@ func getParticipantsKeyboard(tournamentID int) (tgbotapi.InlineKeyboardMarkup, e
 	for _, participant := range participants {
 		var label string
 		tournament, err := services.GetTournament(tournamentID)
-		if err == nil && tournament.HasParticipant(participant) {
-			label = "✅ " + participant
+		if err == nil && tournament.HasParticipant(participant.Name) {
+			label = "✅ " + participant.Name
+		} else if err == nil && tournament.IsWaitlisted(participant.Name) {
+			label = "⏳ " + participant.Name
 		} else {
-			label = participant
+			label = participant.Name
 		}
-		callbackData := fmt.Sprintf("toggle_participant_%d_%s", tournamentID, participant)
+		// В данных кнопки постоянный ID: имя может быть длинным или измениться
+		callbackData := fmt.Sprintf("toggle_participant_%d_%s", tournamentID, participant.ID)
 		button := tgbotapi.NewInlineKeyboardButtonData(label, callbackData)
 		rows = append(rows, []tgbotapi.InlineKeyboardButton{button})
 	}
 
-	selectCategoryCallbackData := fmt.Sprintf("select_category_%d", tournamentID)
-	selectCategoryButton := tgbotapi.NewInlineKeyboardButtonData("Select Category", selectCategoryCallbackData)
-	rows = append(rows, []tgbotapi.InlineKeyboardButton{selectCategoryButton})
+	tournament, err := services.GetTournament(tournamentID)
+	if err == nil {
+		rows = append(rows, []tgbotapi.InlineKeyboardButton{teamAssignmentButton(tournament)})
+	}
 
 	return tgbotapi.NewInlineKeyboardMarkup(rows...), nil
 }
 
-func endTournament(message *tgbotapi.Message) {
+func endTournament(ctx context.Context, message *tgbotapi.Message) {
 	// Получаем идентификатор турнира из аргументов команды
 	tournamentID, err := strconv.Atoi(message.CommandArguments())
 	if err != nil {
@@ -262,19 +372,28 @@ func endTournament(message *tgbotapi.Message) {
 	bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Tournament ended"))
 }
 
-func СallbackHandler(callback *tgbotapi.CallbackQuery) {
+func СallbackHandler(ctx context.Context, callback *tgbotapi.CallbackQuery) {
 	if strings.HasPrefix(callback.Data, "toggle_participant_") {
 		parts := strings.Split(callback.Data, "_")
 		tournamentID, err := strconv.Atoi(parts[2])
 		if err != nil {
-			log.Printf("Error converting tournament ID: %v", err)
+			slog.ErrorContext(ctx, "Error converting tournament ID", "err", err)
+			return
+		}
+		participant, err := db.GetParticipantByID(parts[3])
+		if err != nil {
+			slog.ErrorContext(ctx, "Error getting participant", "err", err)
+			return
+		}
+		if participant == nil {
+			bot.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "Participant not found. Please reopen the participant list."))
 			return
 		}
-		participantName := parts[3]
+		participantName := participant.Name
 
 		tournament, err := services.GetTournament(tournamentID)
 		if err != nil {
-			log.Printf("Error getting tournament: %v", err)
+			slog.ErrorContext(ctx, "Error getting tournament", "err", err)
 			return
 		}
 
@@ -285,32 +404,51 @@ func СallbackHandler(callback *tgbotapi.CallbackQuery) {
 			return
 		}
 
-		err = services.ToggleParticipant(tournamentID, participantName)
+		promoted, err := services.ToggleParticipant(tournamentID, participantName)
 		if err != nil {
-			log.Printf("Error toggling participant: %v", err)
+			slog.ErrorContext(ctx, "Error toggling participant", "err", err)
 			return
 		}
+		notifyPromoted(ctx, tournament, promoted)
 
 		// Обновляем клавиатуру с участниками
-		keyboard, err := getParticipantsKeyboard(tournamentID)
+		keyboard, err := getParticipantsKeyboard(ctx, tournamentID)
 		if err != nil {
-			log.Printf("Error getting participants keyboard: %v", err)
+			slog.ErrorContext(ctx, "Error getting participants keyboard", "err", err)
 			return
 		}
 		msg := tgbotapi.NewEditMessageReplyMarkup(callback.Message.Chat.ID, callback.Message.MessageID, keyboard)
 		_, err = bot.Send(msg)
 		if err != nil {
-			log.Printf("Error sending message: %v", err)
+			slog.ErrorContext(ctx, "Error sending message", "err", err)
 			return
 		}
 
 		// Отвечаем на callback, чтобы убрать "часики" на кнопке
 		bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	} else if strings.HasPrefix(callback.Data, "join_tournament_") {
+		joinTournamentCallback(ctx, callback)
+	} else if strings.HasPrefix(callback.Data, "withdraw_tournament_") {
+		withdrawTournamentCallback(ctx, callback)
+	} else if strings.HasPrefix(callback.Data, "checkin_") {
+		checkInCallback(ctx, callback)
+	} else if strings.HasPrefix(callback.Data, "wizard_") {
+		tournamentWizardCallback(ctx, callback)
+	} else if strings.HasPrefix(callback.Data, "free_choice_start_") {
+		freeChoiceStartCallback(ctx, callback)
+	} else if strings.HasPrefix(callback.Data, "draw_reveal_") {
+		drawRevealCallback(ctx, callback)
+	} else if strings.HasPrefix(callback.Data, "draft_start_") {
+		draftStartCallback(ctx, callback)
+	} else if strings.HasPrefix(callback.Data, "draft_pick_") {
+		draftPickCallback(ctx, callback)
+	} else if strings.HasPrefix(callback.Data, "profile_") {
+		profilePageCallback(ctx, callback)
 	} else if strings.HasPrefix(callback.Data, "delete_tournament_") {
 		tournamentID, _ := strconv.Atoi(strings.TrimPrefix(callback.Data, "delete_tournament_"))
 		err := services.DeleteTournament(tournamentID)
 		if err != nil {
-			log.Printf("Error deleting tournament: %v", err)
+			slog.ErrorContext(ctx, "Error deleting tournament", "err", err)
 			msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Failed to delete the tournament.")
 			bot.Send(msg)
 		} else {
@@ -321,13 +459,13 @@ func СallbackHandler(callback *tgbotapi.CallbackQuery) {
 		// Получаем идентификатор турнира из callback.Data
 		tournamentID, err := strconv.Atoi(strings.TrimPrefix(callback.Data, "select_category_"))
 		if err != nil {
-			log.Printf("Error converting tournament ID: %v", err)
+			slog.ErrorContext(ctx, "Error converting tournament ID", "err", err)
 			return
 		}
 
 		tournament, err := services.GetTournament(tournamentID)
 		if err != nil {
-			log.Printf("Error getting tournament: %v", err)
+			slog.ErrorContext(ctx, "Error getting tournament", "err", err)
 			return
 		}
 
@@ -339,16 +477,16 @@ func СallbackHandler(callback *tgbotapi.CallbackQuery) {
 		}
 
 		// Отправляем сообщение с клавиатурой выбора категории команд
-		keyboard, err := getTeamCategoriesKeyboard(tournamentID)
+		keyboard, err := getTeamCategoriesKeyboard(ctx, tournamentID)
 		if err != nil {
-			log.Printf("Error getting team categories keyboard: %v", err)
+			slog.ErrorContext(ctx, "Error getting team categories keyboard", "err", err)
 			return
 		}
 		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Select the team category for the tournament:")
 		msg.ReplyMarkup = keyboard
 		_, err = bot.Send(msg)
 		if err != nil {
-			log.Printf("Error sending message: %v", err)
+			slog.ErrorContext(ctx, "Error sending message", "err", err)
 			return
 		}
 
@@ -358,14 +496,14 @@ func СallbackHandler(callback *tgbotapi.CallbackQuery) {
 		parts := strings.Split(callback.Data, "_")
 		tournamentID, err := strconv.Atoi(parts[2])
 		if err != nil {
-			log.Printf("Error converting tournament ID: %v", err)
+			slog.ErrorContext(ctx, "Error converting tournament ID", "err", err)
 			return
 		}
 		categoryName := parts[3]
 
 		tournament, err := services.GetTournament(tournamentID)
 		if err != nil {
-			log.Printf("Error getting tournament: %v", err)
+			slog.ErrorContext(ctx, "Error getting tournament", "err", err)
 			return
 		}
 
@@ -378,44 +516,19 @@ func СallbackHandler(callback *tgbotapi.CallbackQuery) {
 
 		err = services.SetTournamentTeamCategory(tournamentID, categoryName)
 		if err != nil {
-			log.Printf("Error setting tournament team category: %v", err)
+			slog.ErrorContext(ctx, "Error setting tournament team category", "err", err)
+			bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "Could not select the category: "+err.Error()))
 			return
 		}
 
-		// Выполняем жеребьевку команд
-		drawResult, err := services.PerformTeamDraw(tournamentID)
-		if err != nil {
-			log.Printf("Error performing team draw: %v", err)
-			return
-		}
-
-		// Запускаем турнир
-		updatedTournament, err := services.StartTournament(tournamentID)
-		if err != nil {
-			log.Printf("Error starting tournament: %v", err)
-			msg := tgbotapi.NewMessage(callback.Message.Chat.ID, err.Error())
-			bot.Send(msg)
-			return
-		}
-
-		// Отправляем сообщение с результатом жеребьевки и информацией о начале турнира
-		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, "Team draw result:\n"+drawResult+"\nThe tournament has started!")
-		_, err = bot.Send(msg)
-		if err != nil {
-			log.Printf("Error sending message: %v", err)
-			return
-		}
-
-		err = notifications.SendTournamentStartMessage(updatedTournament)
-		if err != nil {
-			log.Printf("Error sending tournament start message: %v", err)
-		}
+		// Распределяем команды и запускаем турнир
+		drawTeamsAndStart(ctx, callback.Message.Chat.ID, tournamentID)
 
 		// Отвечаем на callback, чтобы убрать "часики" на кнопке
 		bot.Request(tgbotapi.NewCallback(callback.ID, ""))
 	} else if strings.HasPrefix(callback.Data, "team_") {
 		// Проверяем, был ли явно инициирован процесс добавления матча
-		state, ok := teamSelectionStates[callback.From.ID]
+		state, ok := teamSelectionStates.Get(callback.From.ID)
 		if !ok {
 			// Если процесс добавления матча не был инициирован, игнорируем нажатие на кнопку
 			return
@@ -426,13 +539,13 @@ func СallbackHandler(callback *tgbotapi.CallbackQuery) {
 		team := parts[2]
 
 		// Получение текущего состояния выбора команд для пользователя
-		state, ok = teamSelectionStates[callback.From.ID]
+		state, ok = teamSelectionStates.Get(callback.From.ID)
 		if !ok {
 			// Создание нового состояния выбора команд, если оно не существует
 			state = &TeamSelectionState{
 				TournamentID: tournamentID,
 			}
-			teamSelectionStates[callback.From.ID] = state
+			teamSelectionStates.Set(callback.From.ID, state)
 		}
 
 		if state.Team1 == "" {
@@ -466,7 +579,7 @@ func СallbackHandler(callback *tgbotapi.CallbackQuery) {
 		// Получение идентификатора текущего активного турнира
 		tournament, err := services.GetActiveTournament()
 		if err != nil {
-			log.Printf("Error getting active tournament: %v", err)
+			slog.ErrorContext(ctx, "Error getting active tournament", "err", err)
 			bot.Request(tgbotapi.NewCallback(callback.ID, "Произошла ошибка при получении активного турнира."))
 			return
 		}
@@ -506,8 +619,15 @@ func СallbackHandler(callback *tgbotapi.CallbackQuery) {
 
 		// Удаление последнего добавленного матча
 		err = services.DeleteLastMatch(tournament.ID, stageType)
+		if errors.Is(err, services.ErrRatingsOutdated) {
+			slog.ErrorContext(ctx, "Error updating ratings after deleting last match", "err", err)
+			bot.Request(tgbotapi.NewCallback(callback.ID, "Последний матч был удален."))
+			bot.Send(tgbotapi.NewMessage(callback.Message.Chat.ID,
+				"Последний матч был удален, но рейтинги игроков не обновились. Выполните /recalculate_ratings."))
+			return
+		}
 		if err != nil {
-			log.Printf("Error deleting last match: %v", err)
+			slog.ErrorContext(ctx, "Error deleting last match", "err", err)
 			bot.Request(tgbotapi.NewCallback(callback.ID, "Произошла ошибка при удалении последнего матча."))
 			return
 		}
@@ -522,11 +642,11 @@ func СallbackHandler(callback *tgbotapi.CallbackQuery) {
 	}
 }
 
-func getTeamCategoriesKeyboard(tournamentID int) (tgbotapi.InlineKeyboardMarkup, error) {
+func getTeamCategoriesKeyboard(ctx context.Context, tournamentID int) (tgbotapi.InlineKeyboardMarkup, error) {
 	// Получаем список всех категорий команд из базы данных
 	categories, err := db.GetTeamCategories()
 	if err != nil {
-		log.Printf("Error getting team categories: %v", err)
+		slog.ErrorContext(ctx, "Error getting team categories", "err", err)
 		return tgbotapi.InlineKeyboardMarkup{}, err
 	}
 
@@ -574,51 +694,11 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-func addTeamCategoryHandler(message *tgbotapi.Message) {
-	args := strings.Split(message.CommandArguments(), ",")
-	if len(args) < 2 {
-		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /add_team_category <category_name> <team1>,<team2>,..."))
-		return
-	}
-
-	categoryName := strings.TrimSpace(args[0])
-	teams := make([]string, len(args)-1)
-	for i, team := range args[1:] {
-		teams[i] = strings.TrimSpace(team)
-	}
-
-	err := db.AddTeamCategory(categoryName, teams)
-	if err != nil {
-		log.Printf("Error adding team category: %v", err)
-		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while adding the team category."))
-		return
-	}
-
-	bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Team category added successfully."))
-}
-
-func removeTeamCategoryHandler(message *tgbotapi.Message) {
-	categoryName := strings.TrimSpace(message.CommandArguments())
-	if categoryName == "" {
-		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /remove_team_category <category_name>"))
-		return
-	}
-
-	err := db.RemoveTeamCategory(categoryName)
-	if err != nil {
-		log.Printf("Error removing team category: %v", err)
-		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while removing the team category."))
-		return
-	}
-
-	bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Team category removed successfully."))
-}
-
-func handleAddAdminCommand(message *tgbotapi.Message) {
+func handleAddAdminCommand(ctx context.Context, message *tgbotapi.Message) {
 	// Проверяем, является ли пользователь администратором
 	isAdmin, err := db.IsAdmin(message.From.ID)
 	if err != nil {
-		log.Printf("Error checking admin status: %v", err)
+		slog.ErrorContext(ctx, "Error checking admin status", "err", err)
 		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred while checking your admin status.")
 		bot.Send(msg)
 		return
@@ -645,8 +725,12 @@ func handleAddAdminCommand(message *tgbotapi.Message) {
 
 	// Добавляем пользователя в список администраторов
 	err = db.AddAdmin(userID)
+	if mongo.IsDuplicateKeyError(err) {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "This user is already an admin."))
+		return
+	}
 	if err != nil {
-		log.Printf("Error adding admin: %v", err)
+		slog.ErrorContext(ctx, "Error adding admin", "err", err)
 		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred while adding the admin.")
 		bot.Send(msg)
 		return
@@ -656,11 +740,11 @@ func handleAddAdminCommand(message *tgbotapi.Message) {
 	bot.Send(msg)
 }
 
-func handleRemoveAdminCommand(message *tgbotapi.Message) {
+func handleRemoveAdminCommand(ctx context.Context, message *tgbotapi.Message) {
 	// Проверяем, является ли пользователь администратором
 	isAdmin, err := db.IsAdmin(message.From.ID)
 	if err != nil {
-		log.Printf("Error checking admin status: %v", err)
+		slog.ErrorContext(ctx, "Error checking admin status", "err", err)
 		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred while checking your admin status.")
 		bot.Send(msg)
 		return
@@ -688,7 +772,7 @@ func handleRemoveAdminCommand(message *tgbotapi.Message) {
 	// Удаляем пользователя из списка администраторов
 	err = db.RemoveAdmin(userID)
 	if err != nil {
-		log.Printf("Error removing admin: %v", err)
+		slog.ErrorContext(ctx, "Error removing admin", "err", err)
 		msg := tgbotapi.NewMessage(message.Chat.ID, "An error occurred while removing the admin.")
 		bot.Send(msg)
 		return
@@ -698,11 +782,11 @@ func handleRemoveAdminCommand(message *tgbotapi.Message) {
 	bot.Send(msg)
 }
 
-func HandleDeleteTournament(message *tgbotapi.Message) {
+func HandleDeleteTournament(ctx context.Context, message *tgbotapi.Message) {
 	// Проверяем, является ли пользователь администратором
 	isAdmin, err := db.IsAdmin(message.From.ID)
 	if err != nil {
-		log.Printf("Error checking admin status: %v", err)
+		slog.ErrorContext(ctx, "Error checking admin status", "err", err)
 		msg := tgbotapi.NewMessage(message.Chat.ID, "Failed to check admin status.")
 		bot.Send(msg)
 		return
@@ -716,7 +800,7 @@ func HandleDeleteTournament(message *tgbotapi.Message) {
 	// Получаем список активных турниров
 	activeTournaments, err := services.GetActiveTournaments()
 	if err != nil {
-		log.Printf("Error getting active tournaments: %v", err)
+		slog.ErrorContext(ctx, "Error getting active tournaments", "err", err)
 		msg := tgbotapi.NewMessage(message.Chat.ID, "Failed to get active tournaments.")
 		bot.Send(msg)
 		return
@@ -741,7 +825,7 @@ func HandleDeleteTournament(message *tgbotapi.Message) {
 	bot.Send(msg)
 }
 
-func addMatchHandler(message *tgbotapi.Message) {
+func addMatchHandler(ctx context.Context, message *tgbotapi.Message) {
 	// Проверка прав доступа пользователя
 	isAdmin, err := db.IsAdmin(message.From.ID)
 	if err != nil {
@@ -755,7 +839,7 @@ func addMatchHandler(message *tgbotapi.Message) {
 		return
 	}
 
-	_, ok := teamSelectionStates[message.From.ID]
+	_, ok := teamSelectionStates.Get(message.From.ID)
 	if ok {
 		msg := tgbotapi.NewMessage(message.Chat.ID, "У вас уже есть активный процесс добавления результата матча. Пожалуйста, завершите его перед началом нового.")
 		bot.Send(msg)
@@ -765,7 +849,7 @@ func addMatchHandler(message *tgbotapi.Message) {
 	// Получение текущего активного турнира
 	tournament, err := services.GetActiveTournament()
 	if err != nil {
-		log.Printf("Error getting active tournament: %v", err)
+		slog.ErrorContext(ctx, "Error getting active tournament", "err", err)
 		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Ошибка при получении активного турнира."))
 		return
 	}
@@ -799,14 +883,14 @@ func addMatchHandler(message *tgbotapi.Message) {
 			Team2:         teams[1],
 			AwaitingScore: true,
 		}
-		teamSelectionStates[message.From.ID] = state
+		teamSelectionStates.Set(message.From.ID, state)
 
 		// Запрашиваем счет первой команды
 		msg = tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Введите счет для команды %s:", teams[0]))
 		bot.Send(msg)
 	} else {
 		// Турнир находится в групповом этапе
-		_, ok := teamSelectionStates[message.From.ID]
+		_, ok := teamSelectionStates.Get(message.From.ID)
 		if ok {
 			msg := tgbotapi.NewMessage(message.Chat.ID, "У вас уже есть активный процесс добавления результата матча. Пожалуйста, завершите его перед началом нового.")
 			bot.Send(msg)
@@ -817,7 +901,7 @@ func addMatchHandler(message *tgbotapi.Message) {
 		state := &TeamSelectionState{
 			TournamentID: tournament.ID,
 		}
-		teamSelectionStates[message.From.ID] = state
+		teamSelectionStates.Set(message.From.ID, state)
 
 		// Отправка сообщения с инструкцией и клавиатурой для выбора команд
 		msg := tgbotapi.NewMessage(message.Chat.ID, "Выберите первую команду:")
@@ -826,9 +910,9 @@ func addMatchHandler(message *tgbotapi.Message) {
 	}
 }
 
-func handleScoreInput(message *tgbotapi.Message) {
+func handleScoreInput(ctx context.Context, message *tgbotapi.Message) {
 	// Получение текущего состояния выбора команд для пользователя
-	state, ok := teamSelectionStates[message.From.ID]
+	state, ok := teamSelectionStates.Get(message.From.ID)
 	if !ok {
 		return
 	}
@@ -866,10 +950,10 @@ func handleScoreInput(message *tgbotapi.Message) {
 		// Получение текущего активного турнира
 		tournament, err := services.GetTournament(state.TournamentID)
 		if err != nil {
-			log.Printf("Error getting tournament: %v", err)
+			slog.ErrorContext(ctx, "Error getting tournament", "err", err)
 			msg := tgbotapi.NewMessage(message.Chat.ID, "Произошла ошибка при получении турнира.")
 			bot.Send(msg)
-			delete(teamSelectionStates, message.From.ID)
+			teamSelectionStates.Delete(message.From.ID)
 			return
 		}
 
@@ -889,7 +973,7 @@ func handleScoreInput(message *tgbotapi.Message) {
 
 			currentStage, err := services.AddPlayoffMatch(state.TournamentID, state.Team1, state.Team2, state.Score1, state.Score2, 0, 0, false, false)
 			if err != nil {
-				log.Printf("Error adding playoff match: %v", err)
+				slog.ErrorContext(ctx, "Error adding playoff match", "err", err)
 				msg := tgbotapi.NewMessage(message.Chat.ID, "Произошла ошибка при сохранении результата матча плей-офф.")
 				bot.Send(msg)
 			} else {
@@ -899,7 +983,7 @@ func handleScoreInput(message *tgbotapi.Message) {
 				// Получаем обновленный турнир из базы данных
 				tournament, err = services.GetTournament(state.TournamentID)
 				if err != nil {
-					log.Printf("Error getting updated tournament: %v", err)
+					slog.ErrorContext(ctx, "Error getting updated tournament", "err", err)
 					msg := tgbotapi.NewMessage(message.Chat.ID, "Произошла ошибка при получении обновленного турнира.")
 					bot.Send(msg)
 				} else {
@@ -943,19 +1027,18 @@ func handleScoreInput(message *tgbotapi.Message) {
 			}
 		} else {
 			// Турнир находится в групповом этапе
-			// Проверка наличия уже добавленного результата матча
-			existingMatch, err := getMatchResult(state.TournamentID, state.Team1, state.Team2)
-			if err == nil && existingMatch != nil {
+			// Проверка, что команды еще не сыграли все матчи между собой по формату турнира
+			if tournament.MatchesBetween(state.Team1, state.Team2) >= tournament.Rounds() {
 				msg := tgbotapi.NewMessage(message.Chat.ID, "Результат матча между этими командами уже был добавлен ранее.")
 				bot.Send(msg)
-				delete(teamSelectionStates, message.From.ID)
+				teamSelectionStates.Delete(message.From.ID)
 				return
 			}
 
 			// Добавление результата матча в групповой этап
 			err = services.AddMatchResult(state.TournamentID, state.Team1, state.Team2, state.Score1, state.Score2)
 			if err != nil {
-				log.Printf("Error adding match result: %v", err)
+				slog.ErrorContext(ctx, "Error adding match result", "err", err)
 				msg := tgbotapi.NewMessage(message.Chat.ID, "Произошла ошибка при сохранении результата матча.")
 				bot.Send(msg)
 			} else {
@@ -965,7 +1048,7 @@ func handleScoreInput(message *tgbotapi.Message) {
 		}
 
 		// Сброс состояния выбора команд
-		delete(teamSelectionStates, message.From.ID)
+		teamSelectionStates.Delete(message.From.ID)
 
 	case StateAwaitingOvertimeScore:
 		// Обработка общего счета матча после овертайма
@@ -1003,7 +1086,7 @@ func handleScoreInput(message *tgbotapi.Message) {
 		// Добавление результата матча плей-офф с овертаймом
 		currentStage, err := services.AddPlayoffMatch(state.TournamentID, state.Team1, state.Team2, state.Score1, state.Score2, 0, 0, true, false)
 		if err != nil {
-			log.Printf("Error adding playoff match: %v", err)
+			slog.ErrorContext(ctx, "Error adding playoff match", "err", err)
 			msg := tgbotapi.NewMessage(message.Chat.ID, "Произошла ошибка при сохранении результата матча плей-офф.")
 			bot.Send(msg)
 		} else {
@@ -1013,7 +1096,7 @@ func handleScoreInput(message *tgbotapi.Message) {
 			// Получаем обновленный турнир из базы данных
 			tournament, err := services.GetTournament(state.TournamentID)
 			if err != nil {
-				log.Printf("Error getting updated tournament: %v", err)
+				slog.ErrorContext(ctx, "Error getting updated tournament", "err", err)
 				msg := tgbotapi.NewMessage(message.Chat.ID, "Произошла ошибка при получении обновленного турнира.")
 				bot.Send(msg)
 			} else {
@@ -1031,7 +1114,7 @@ func handleScoreInput(message *tgbotapi.Message) {
 				// Отправляем уведомление о результате матча плей-офф с овертаймом
 				err = notifications.SendPlayoffMatchResultMessage(tournament, currentStage, match)
 				if err != nil {
-					log.Printf("Error sending playoff match result message: %v", err)
+					slog.ErrorContext(ctx, "Error sending playoff match result message", "err", err)
 				}
 				// Проверяем, завершился ли плей-офф
 				if tournament.Playoff.Winner != "" {
@@ -1063,7 +1146,7 @@ func handleScoreInput(message *tgbotapi.Message) {
 		}
 
 		// Сброс состояния выбора команд
-		delete(teamSelectionStates, message.From.ID)
+		teamSelectionStates.Delete(message.From.ID)
 
 	case StateAwaitingPenaltiesScore:
 		penaltiesScoreParts := strings.Split(message.Text, ":")
@@ -1088,7 +1171,7 @@ func handleScoreInput(message *tgbotapi.Message) {
 		// Добавление результата матча плей-офф с овертаймом и серией пенальти
 		currentStage, err := services.AddPlayoffMatch(state.TournamentID, state.Team1, state.Team2, state.Score1, state.Score2, penaltiesScore1, penaltiesScore2, true, true)
 		if err != nil {
-			log.Printf("Error adding playoff match: %v", err)
+			slog.ErrorContext(ctx, "Error adding playoff match", "err", err)
 			msg := tgbotapi.NewMessage(message.Chat.ID, "Произошла ошибка при сохранении результата матча плей-офф.")
 			bot.Send(msg)
 		} else {
@@ -1098,7 +1181,7 @@ func handleScoreInput(message *tgbotapi.Message) {
 			// Получаем обновленный турнир из базы данных
 			tournament, err := services.GetTournament(state.TournamentID)
 			if err != nil {
-				log.Printf("Error getting updated tournament: %v", err)
+				slog.ErrorContext(ctx, "Error getting updated tournament", "err", err)
 				msg := tgbotapi.NewMessage(message.Chat.ID, "Произошла ошибка при получении обновленного турнира.")
 				bot.Send(msg)
 			} else {
@@ -1116,7 +1199,7 @@ func handleScoreInput(message *tgbotapi.Message) {
 				// Отправляем уведомление о результате матча плей-офф с овертаймом
 				err = notifications.SendPlayoffMatchResultMessage(tournament, currentStage, match)
 				if err != nil {
-					log.Printf("Error sending playoff match result message: %v", err)
+					slog.ErrorContext(ctx, "Error sending playoff match result message", "err", err)
 				}
 				// Проверяем, завершился ли плей-офф
 				if tournament.Playoff.Winner != "" {
@@ -1148,7 +1231,7 @@ func handleScoreInput(message *tgbotapi.Message) {
 		}
 
 		// Сброс состояния выбора команд
-		delete(teamSelectionStates, message.From.ID)
+		teamSelectionStates.Delete(message.From.ID)
 	}
 }
 
@@ -1163,37 +1246,11 @@ func getNextStage(currentStage string) string {
 	}
 }
 
-func getMatchResult(tournamentID int, team1, team2 string) (*db.Match, error) {
-	filter := bson.M{
-		"id": tournamentID,
-		"matches": bson.M{
-			"$elemMatch": bson.M{
-				"team1": team1,
-				"team2": team2,
-			},
-		},
-	}
-
-	var tournament db.Tournament
-	err := db.DB.Collection("tournaments").FindOne(context.TODO(), filter).Decode(&tournament)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, match := range tournament.Matches {
-		if match.Team1 == team1 && match.Team2 == team2 {
-			return &match, nil
-		}
-	}
-
-	return nil, errors.New("match not found")
-}
-
-func tournamentInfoHandler(message *tgbotapi.Message) {
+func tournamentInfoHandler(ctx context.Context, message *tgbotapi.Message) {
 	// Получение идентификатора текущего активного турнира
 	tournament, err := services.GetActiveTournament()
 	if err != nil {
-		log.Printf("Error getting active tournament: %v", err)
+		slog.ErrorContext(ctx, "Error getting active tournament", "err", err)
 		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Произошла ошибка при получении активного турнира."))
 		return
 	}
@@ -1279,10 +1336,7 @@ func tournamentInfoHandler(message *tgbotapi.Message) {
 		messageParts = append(messageParts, "Пока нет сыгранных матчей.")
 	} else {
 		for _, match := range tournament.Matches {
-			messageParts = append(messageParts, fmt.Sprintf(
-				"%s %d - %d %s",
-				match.Team1, match.Score1, match.Score2, match.Team2,
-			))
+			messageParts = append(messageParts, notifications.FormatMatchResult(&match, match.Team1, match.Team2))
 		}
 	}
 
@@ -1293,32 +1347,23 @@ func tournamentInfoHandler(message *tgbotapi.Message) {
 		// Выводим информацию о четвертьфиналах
 		if len(tournament.Playoff.QuarterFinals) > 0 {
 			messageParts = append(messageParts, "Четвертьфиналы:\n")
-			for _, match := range tournament.Playoff.QuarterFinals {
-				messageParts = append(messageParts, fmt.Sprintf(
-					"%s vs %s\n",
-					match.Team1, match.Team2,
-				))
+			for i := range tournament.Playoff.QuarterFinals {
+				messageParts = append(messageParts, playoffMatchLine(&tournament.Playoff.QuarterFinals[i]))
 			}
 		}
 
 		// Выводим информацию о полуфиналах
 		if len(tournament.Playoff.SemiFinals) > 0 {
 			messageParts = append(messageParts, "\nПолуфиналы:\n")
-			for _, match := range tournament.Playoff.SemiFinals {
-				messageParts = append(messageParts, fmt.Sprintf(
-					"%s vs %s\n",
-					match.Team1, match.Team2,
-				))
+			for i := range tournament.Playoff.SemiFinals {
+				messageParts = append(messageParts, playoffMatchLine(&tournament.Playoff.SemiFinals[i]))
 			}
 		}
 
 		// Выводим информацию о финале
 		if tournament.Playoff.Final != nil {
 			messageParts = append(messageParts, "\nФинал:\n")
-			messageParts = append(messageParts, fmt.Sprintf(
-				"%s vs %s\n",
-				tournament.Playoff.Final.Team1, tournament.Playoff.Final.Team2,
-			))
+			messageParts = append(messageParts, playoffMatchLine(tournament.Playoff.Final))
 		}
 
 		// Выводим информацию о победителе
@@ -1341,11 +1386,19 @@ func removeKeyboard(chatID int64, messageID int) {
 	bot.Send(msg)
 }
 
-func startPlayoffHandler(message *tgbotapi.Message) {
+// playoffMatchLine выводит пару плей-офф, а после записи матча — его результат
+func playoffMatchLine(match *db.Match) string {
+	if !match.Counted {
+		return fmt.Sprintf("%s vs %s\n", match.Team1, match.Team2)
+	}
+	return notifications.FormatMatchResult(match, match.Team1, match.Team2) + "\n"
+}
+
+func startPlayoffHandler(ctx context.Context, message *tgbotapi.Message) {
 	// Получение идентификатора текущего активного турнира
 	tournament, err := services.GetActiveTournament()
 	if err != nil {
-		log.Printf("Error getting active tournament: %v", err)
+		slog.ErrorContext(ctx, "Error getting active tournament", "err", err)
 		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Произошла ошибка при получении активного турнира."))
 		return
 	}
@@ -1363,7 +1416,7 @@ func startPlayoffHandler(message *tgbotapi.Message) {
 	// Начинаем плей-офф
 	err = services.StartPlayoff(tournament.ID)
 	if err != nil {
-		log.Printf("Error starting playoff: %v", err)
+		slog.ErrorContext(ctx, "Error starting playoff", "err", err)
 		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Произошла ошибка при начале плей-офф."))
 		return
 	}
@@ -1376,18 +1429,15 @@ func getHeadToHeadResult(team1, team2 string, matches []db.Match) int {
 	team2Wins := 0
 
 	for _, match := range matches {
-		if match.Team1 == team1 && match.Team2 == team2 {
-			if match.Score1 > match.Score2 {
-				team1Wins++
-			} else if match.Score1 < match.Score2 {
-				team2Wins++
-			}
-		} else if match.Team1 == team2 && match.Team2 == team1 {
-			if match.Score1 > match.Score2 {
-				team2Wins++
-			} else if match.Score1 < match.Score2 {
-				team1Wins++
-			}
+		isPair := (match.Team1 == team1 && match.Team2 == team2) || (match.Team1 == team2 && match.Team2 == team1)
+		if !isPair {
+			continue
+		}
+		switch match.WinnerTeam() {
+		case team1:
+			team1Wins++
+		case team2:
+			team2Wins++
 		}
 	}
 
@@ -1400,11 +1450,11 @@ func getHeadToHeadResult(team1, team2 string, matches []db.Match) int {
 	}
 }
 
-func deleteLastMatchHandler(message *tgbotapi.Message) {
+func deleteLastMatchHandler(ctx context.Context, message *tgbotapi.Message) {
 	// Получение идентификатора текущего активного турнира
 	tournament, err := services.GetActiveTournament()
 	if err != nil {
-		log.Printf("Error getting active tournament: %v", err)
+		slog.ErrorContext(ctx, "Error getting active tournament", "err", err)
 		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Произошла ошибка при получении активного турнира."))
 		return
 	}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strings"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/notifications"
+	"tournament-bot/internal/services"
+)
+
+const matchStatusUsage = "Usage: /match_status <forfeit|walkover|abandoned|postponed> <team1>, <team2>[, reason]\n" +
+	"For forfeit and walkover the win is awarded to team1."
+
+// matchStatusHandler записывает матч с нестандартным исходом в активный турнир
+func matchStatusHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	status, rest, _ := strings.Cut(strings.TrimSpace(message.CommandArguments()), " ")
+	parts := strings.SplitN(rest, ",", 3)
+	if !isMatchStatus(status) || len(parts) < 2 {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, matchStatusUsage))
+		return
+	}
+	team1, team2 := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	reason := ""
+	if len(parts) == 3 {
+		reason = strings.TrimSpace(parts[2])
+	}
+	if team1 == "" || team2 == "" || team1 == team2 {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, matchStatusUsage))
+		return
+	}
+
+	tournament, err := services.GetActiveTournament()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting active tournament", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while checking for active tournament."))
+		return
+	}
+	if tournament == nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "There is no active tournament."))
+		return
+	}
+	for _, team := range []string{team1, team2} {
+		if !tournament.HasTeam(team) {
+			bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Team %s is not playing in %s.", team, tournament.Name)))
+			return
+		}
+	}
+
+	match, stage, err := services.RecordMatchStatus(tournament.ID, team1, team2, status, reason)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error recording match status", "tournament_id", tournament.ID, "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Could not record the match: "+err.Error()))
+		return
+	}
+
+	if stage != "" {
+		updated, err := services.GetTournament(tournament.ID)
+		if err == nil {
+			err = notifications.SendPlayoffMatchResultMessage(updated, stage, match)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Error sending playoff match result message", "err", err)
+		}
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Match recorded: "+notifications.FormatMatchResult(match, team1, team2)))
+}
+
+// isMatchStatus проверяет статус матча; обычный сыгранный матч вводится через /add_match
+func isMatchStatus(status string) bool {
+	if status == db.MatchPlayed {
+		return false
+	}
+	for _, known := range db.MatchStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
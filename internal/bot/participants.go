@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strings"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/services"
+)
+
+// requireAdmin проверяет права отправителя и сообщает об отказе в чат
+func requireAdmin(ctx context.Context, message *tgbotapi.Message) bool {
+	isAdmin, err := db.IsAdmin(message.From.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error checking admin status", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while checking your admin status."))
+		return false
+	}
+	if !isAdmin {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "You don't have permission to use this command."))
+		return false
+	}
+	return true
+}
+
+// parseNamePair разбирает аргументы вида "<имя>, <имя>"
+func parseNamePair(arguments string) (string, string, bool) {
+	parts := strings.Split(arguments, ",")
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	first, second := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	return first, second, first != "" && second != ""
+}
+
+// findParticipantOrReply ищет участника по имени или прежнему имени и сообщает, если его нет
+func findParticipantOrReply(ctx context.Context, chatID int64, name string) *db.Participant {
+	participant, err := db.FindParticipant(name)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error finding participant", "err", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "An error occurred while looking up the participant."))
+		return nil
+	}
+	if participant == nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Participant %s not found.", name)))
+	}
+	return participant
+}
+
+func renameParticipantHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	oldName, newName, ok := parseNamePair(message.CommandArguments())
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /rename_participant <current name>, <new name>"))
+		return
+	}
+	if !isValidName(newName) {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Invalid participant name. Please provide a valid name and surname."))
+		return
+	}
+
+	participant := findParticipantOrReply(ctx, message.Chat.ID, oldName)
+	if participant == nil {
+		return
+	}
+
+	err := services.RenameParticipant(participant.ID, newName)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error renaming participant", "participant_id", participant.ID, "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Error renaming participant: "+err.Error()))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Participant %s has been renamed to %s.", participant.Name, newName)))
+}
+
+func mergeParticipantsHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	keepName, duplicateName, ok := parseNamePair(message.CommandArguments())
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /merge_participants <participant to keep>, <duplicate>"))
+		return
+	}
+
+	keep := findParticipantOrReply(ctx, message.Chat.ID, keepName)
+	if keep == nil {
+		return
+	}
+	duplicate := findParticipantOrReply(ctx, message.Chat.ID, duplicateName)
+	if duplicate == nil {
+		return
+	}
+
+	err := services.MergeParticipants(keep.ID, duplicate.ID)
+	if errors.Is(err, services.ErrRatingsOutdated) {
+		slog.ErrorContext(ctx, "Error recalculating ratings after merge", "keep_id", keep.ID, "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(
+			"%s has been merged into %s, but player ratings were not updated. Run /recalculate_ratings.", duplicate.Name, keep.Name)))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error merging participants", "keep_id", keep.ID, "duplicate_id", duplicate.ID, "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Error merging participants: "+err.Error()))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("%s has been merged into %s.", duplicate.Name, keep.Name)))
+}
+
+// Сколько игроков и расхождений у каждого показывать в ответе /rebuild_stats,
+// чтобы ответ поместился в одно сообщение
+const (
+	rebuildStatsReportLimit  = 15
+	rebuildStatsChangesLimit = 8
+)
+
+// rebuildStatsHandler показывает, чем статистика игроков отличается от пересчитанной
+// по турнирам, а с аргументом apply — записывает пересчитанную
+func rebuildStatsHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+	apply := strings.TrimSpace(message.CommandArguments()) == "apply"
+
+	diffs, err := services.RebuildParticipantStats(!apply)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error rebuilding participant stats", "apply", apply, "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Error rebuilding stats: "+err.Error()))
+		return
+	}
+	if len(diffs) == 0 {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Participant stats match the tournament history, nothing to rebuild."))
+		return
+	}
+
+	var text strings.Builder
+	if apply {
+		text.WriteString(fmt.Sprintf("Stats rebuilt for %d participants:\n", len(diffs)))
+	} else {
+		text.WriteString(fmt.Sprintf("Stats differ for %d participants:\n", len(diffs)))
+	}
+	for i, diff := range diffs {
+		if i == rebuildStatsReportLimit {
+			text.WriteString(fmt.Sprintf("\n…and %d more\n", len(diffs)-i))
+			break
+		}
+		text.WriteString(fmt.Sprintf("\n%s:\n", diff.Participant))
+		for j, change := range diff.Changes {
+			if j == rebuildStatsChangesLimit {
+				text.WriteString(fmt.Sprintf("  …and %d more changes\n", len(diff.Changes)-j))
+				break
+			}
+			text.WriteString("  " + change + "\n")
+		}
+	}
+	if !apply {
+		text.WriteString("\nRun /rebuild_stats apply to write the rebuilt stats.")
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, text.String()))
+}
@@ -0,0 +1,261 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/services"
+)
+
+// Сколько турниров показывать на странице профиля
+const profilePageSize = 5
+
+var formIcons = map[string]string{"W": "🟢", "D": "🟡", "L": "🔴"}
+
+// profileHandler показывает карьерную статистику игрока.
+// Без аргумента — игрока, привязанного к аккаунту Telegram.
+func profileHandler(ctx context.Context, message *tgbotapi.Message) {
+	name := strings.TrimSpace(message.CommandArguments())
+	var participant *db.Participant
+	var err error
+	if name != "" {
+		participant, err = db.FindParticipant(name)
+	} else {
+		participant, err = db.GetParticipantByTelegramID(message.From.ID)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error finding participant", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while looking up the participant."))
+		return
+	}
+	if participant == nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Participant not found. Usage: /profile [name]"))
+		return
+	}
+
+	profile, err := services.GetProfile(participant)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error building profile", "participant", participant.Name, "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while building the profile."))
+		return
+	}
+
+	text, keyboard := profilePage(profile, 0)
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ParseMode = "HTML"
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	bot.Send(msg)
+}
+
+// profilePageCallback листает турниры в профиле: profile_<page>_<participant ID>
+func profilePageCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	parts := strings.SplitN(callback.Data, "_", 3)
+	if len(parts) != 3 {
+		return
+	}
+	page, err := strconv.Atoi(parts[1])
+	if err != nil {
+		slog.ErrorContext(ctx, "Error converting profile page", "err", err)
+		return
+	}
+	participant, err := db.GetParticipantByID(parts[2])
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting participant", "err", err)
+		return
+	}
+	if participant == nil {
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "Participant not found."))
+		return
+	}
+
+	profile, err := services.GetProfile(participant)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error building profile", "participant", participant.Name, "err", err)
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "An error occurred while building the profile."))
+		return
+	}
+
+	text, keyboard := profilePage(profile, page)
+	edit := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, text)
+	edit.ParseMode = "HTML"
+	edit.ReplyMarkup = keyboard
+	bot.Send(edit)
+	bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+}
+
+// profilePage формирует профиль со страницей истории турниров и кнопками листания
+func profilePage(profile *services.Profile, page int) (string, *tgbotapi.InlineKeyboardMarkup) {
+	pages := (len(profile.History) + profilePageSize - 1) / profilePageSize
+	page = max(0, min(page, pages-1))
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("<b>👤 %s</b>\n\n", html.EscapeString(profile.Participant.Name)))
+	if profile.Tournaments == 0 {
+		text.WriteString("No completed tournaments yet.\n")
+	} else {
+		text.WriteString(fmt.Sprintf("Tournaments: %d, matches: %d (%d W / %d D / %d L)\n",
+			profile.Tournaments, profile.Matches, profile.Wins, profile.Draws, profile.Losses))
+		text.WriteString(fmt.Sprintf("Win rate: %.0f%%, goals: %d - %d (%.2f per game)\n",
+			profile.WinRate(), profile.GoalsScored, profile.GoalsConceded, profile.GoalsPerGame()))
+		text.WriteString(fmt.Sprintf("🏆 Titles: %d, podiums: %d\n", profile.Titles, profile.Podiums))
+		if profile.Best != nil {
+			text.WriteString(fmt.Sprintf("Best finish: %s, worst: %s\n", finishText(profile.Best), finishText(profile.Worst)))
+		}
+		if profile.FavouriteTeam != nil {
+			text.WriteString(fmt.Sprintf("Favourite team: %s (%d tournaments)\n", html.EscapeString(profile.FavouriteTeam.Team), profile.FavouriteTeam.Tournaments))
+		}
+		if profile.BestTeam != nil && profile.BestTeam.Wins > 0 {
+			text.WriteString(fmt.Sprintf("Most successful team: %s (%d wins)\n", html.EscapeString(profile.BestTeam.Team), profile.BestTeam.Wins))
+		}
+	}
+	if len(profile.Form) > 0 {
+		var form strings.Builder
+		for _, result := range profile.Form {
+			form.WriteString(formIcons[result])
+		}
+		text.WriteString(fmt.Sprintf("Form: %s\n", form.String()))
+	}
+	if r := profile.Participant.Rating; r != nil && r.System == services.RatingSystem() {
+		text.WriteString(fmt.Sprintf("📈 Rating: %s (%+.0f over the last %d matches)\n",
+			formatRating(r), profile.RatingTrend, profile.RatingTrendMatches))
+	}
+
+	if pages == 0 {
+		return text.String(), nil
+	}
+
+	text.WriteString(fmt.Sprintf("\n<b>Tournaments (page %d of %d):</b>\n", page+1, pages))
+	end := min((page+1)*profilePageSize, len(profile.History))
+	for i := range profile.History[page*profilePageSize : end] {
+		entry := &profile.History[page*profilePageSize+i]
+		name := entry.Name
+		if name == "" {
+			name = fmt.Sprintf("Tournament #%d", entry.Stat.TournamentID)
+		}
+		line := html.EscapeString(name)
+		if !entry.Date.IsZero() {
+			line = entry.Date.Format("02.01.2006") + " " + line
+		}
+		line += " — " + finishText(entry)
+		if entry.Team != "" {
+			line += ", " + html.EscapeString(entry.Team)
+		}
+		text.WriteString(fmt.Sprintf("%s, %d-%d-%d, %d pts\n", line,
+			entry.Stat.Wins, entry.Stat.Draws, entry.Stat.Losses, entry.Stat.Points))
+	}
+
+	var buttons []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("◀️",
+			fmt.Sprintf("profile_%d_%s", page-1, profile.Participant.ID)))
+	}
+	if page < pages-1 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData("▶️",
+			fmt.Sprintf("profile_%d_%s", page+1, profile.Participant.ID)))
+	}
+	if len(buttons) == 0 {
+		return text.String(), nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons)
+	return text.String(), &keyboard
+}
+
+// finishText описывает итоговое место в турнире: «2/8» или этап выбывания,
+// если место неизвестно
+func finishText(entry *services.ProfileTournament) string {
+	position := entry.Position()
+	switch {
+	case position == 0:
+		return "group stage"
+	case entry.Participants > 0:
+		return fmt.Sprintf("%d/%d", position, entry.Participants)
+	default:
+		return fmt.Sprintf("place %d", position)
+	}
+}
+
+// h2hHandler показывает личные встречи двух игроков:
+// /h2h <игрок1> <игрок2> или /h2h <игрок 1>, <игрок 2> для имен с пробелами
+func h2hHandler(ctx context.Context, message *tgbotapi.Message) {
+	name1, name2, ok := parseNamePair(message.CommandArguments())
+	if !ok {
+		fields := strings.Fields(message.CommandArguments())
+		if len(fields) != 2 {
+			bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /h2h <player1> <player2> or /h2h <player 1>, <player 2>"))
+			return
+		}
+		name1, name2 = fields[0], fields[1]
+	}
+
+	player1 := findParticipantOrReply(ctx, message.Chat.ID, name1)
+	if player1 == nil {
+		return
+	}
+	player2 := findParticipantOrReply(ctx, message.Chat.ID, name2)
+	if player2 == nil {
+		return
+	}
+	if player1.ID == player2.ID {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Pick two different players."))
+		return
+	}
+
+	h2h, err := services.GetHeadToHead(player1, player2)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting head-to-head", "player1", player1.Name, "player2", player2.Name, "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while getting the head-to-head record."))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, h2hText(h2h))
+	msg.ParseMode = "HTML"
+	bot.Send(msg)
+}
+
+func h2hText(h2h *services.HeadToHead) string {
+	player1, player2 := html.EscapeString(h2h.Player1), html.EscapeString(h2h.Player2)
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("<b>⚔️ %s vs %s</b>\n\n", player1, player2))
+	if h2h.Matches == 0 {
+		text.WriteString("They have never played each other.")
+		return text.String()
+	}
+	text.WriteString(fmt.Sprintf("Matches: %d\n%s wins: %d, draws: %d, %s wins: %d\nGoals: %d - %d\n",
+		h2h.Matches, player1, h2h.Wins1, h2h.Draws, player2, h2h.Wins2, h2h.Goals1, h2h.Goals2))
+	if h2h.BiggestWin1 != nil {
+		text.WriteString(fmt.Sprintf("Biggest win of %s: %s\n", player1, meetingText(h2h.BiggestWin1)))
+	}
+	if h2h.BiggestWin2 != nil {
+		text.WriteString(fmt.Sprintf("Biggest win of %s: %s\n", player2, meetingText(h2h.BiggestWin2)))
+	}
+
+	text.WriteString("\n<b>Recent meetings:</b>\n")
+	for i := range h2h.Recent {
+		text.WriteString(meetingText(&h2h.Recent[i]) + "\n")
+	}
+	return text.String()
+}
+
+// meetingText описывает матч со стороны первого игрока
+func meetingText(meeting *services.Meeting) string {
+	score := fmt.Sprintf("%d:%d", meeting.Score1, meeting.Score2)
+	if meeting.Status != db.MatchPlayed {
+		score = meeting.Status
+		if meeting.Winner == 1 {
+			score += " (W)"
+		} else if meeting.Winner == 2 {
+			score += " (L)"
+		}
+	}
+	return fmt.Sprintf("%s %s %s %s (%s, %s)", meeting.Date.Format("02.01.2006"),
+		html.EscapeString(meeting.Team1), score, html.EscapeString(meeting.Team2),
+		html.EscapeString(meeting.TournamentName), meeting.Stage)
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strings"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/rating"
+	"tournament-bot/internal/services"
+)
+
+const (
+	ratingLeaderboardSize = 20
+	ratingHistorySize     = 10
+)
+
+// ratingHandler показывает таблицу рейтинга игроков
+func ratingHandler(ctx context.Context, message *tgbotapi.Message) {
+	participants, err := services.GetRatingLeaderboard(ratingLeaderboardSize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting rating leaderboard", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while getting the rating."))
+		return
+	}
+	if len(participants) == 0 {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "No rated matches yet."))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("📈 Player rating (%s)\n\n", services.RatingSystem()))
+	for i, participant := range participants {
+		text.WriteString(fmt.Sprintf("%d. %s — %s (%d matches)\n", i+1, participant.Name,
+			formatRating(participant.Rating), participant.Rating.Matches))
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, text.String()))
+}
+
+// formatRating выводит рейтинг; для Glicko-2 — с отклонением
+func formatRating(r *db.PlayerRating) string {
+	if r.System == rating.SystemGlicko2 {
+		return fmt.Sprintf("%.0f ±%.0f", r.Value, 2*r.Deviation)
+	}
+	return fmt.Sprintf("%.0f", r.Value)
+}
+
+// ratingHistoryHandler показывает последние изменения рейтинга игрока.
+// Без аргумента — игрока, привязанного к аккаунту Telegram.
+func ratingHistoryHandler(ctx context.Context, message *tgbotapi.Message) {
+	name := strings.TrimSpace(message.CommandArguments())
+	var participant *db.Participant
+	var err error
+	if name != "" {
+		participant, err = db.FindParticipant(name)
+	} else {
+		participant, err = db.GetParticipantByTelegramID(message.From.ID)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error finding participant", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while looking up the participant."))
+		return
+	}
+	if participant == nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Participant not found. Usage: /rating_history [name]"))
+		return
+	}
+
+	changes, err := services.GetRatingHistory(participant.Name, ratingHistorySize)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting rating history", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while getting the rating history."))
+		return
+	}
+	if len(changes) == 0 || participant.Rating == nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("%s has no rated matches yet.", participant.Name)))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("📈 %s: %s\n\n", participant.Name, formatRating(participant.Rating)))
+	for _, change := range changes {
+		text.WriteString(fmt.Sprintf("%s %s %d:%d %s (%s) %+.0f → %.0f\n",
+			change.Date.Format("02.01"), change.Team, change.Score, change.OpponentScore,
+			change.OpponentTeam, change.Opponent, change.Delta(), change.After.Value))
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, text.String()))
+}
+
+// recalculateRatingsHandler пересчитывает рейтинги по всем турнирам
+func recalculateRatingsHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	rated, err := services.RecalculateRatings()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error recalculating ratings", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while recalculating ratings."))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Ratings recalculated from %d matches.", rated)))
+}
@@ -0,0 +1,285 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/services"
+)
+
+func registerHandler(ctx context.Context, message *tgbotapi.Message) {
+	name := strings.TrimSpace(message.CommandArguments())
+	if name == "" {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /register <name surname>"))
+		return
+	}
+	if !isValidName(name) {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Invalid participant name. Please provide a valid name and surname."))
+		return
+	}
+
+	participant, err := services.RegisterTelegramUser(message.From.ID, message.From.UserName, name)
+	if errors.Is(err, services.ErrLinkPending) {
+		notifyAdminsLinkRequest(ctx, participant)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("%s is already registered. Your request to link your account to this participant has been sent to the admins.", participant.Name)))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error registering participant", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Registration failed: "+err.Error()))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Your account is now linked to %s. Use /join to sign up for the open tournament.", participant.Name)))
+}
+
+// notifyAdminsLinkRequest сообщает админам о запросе на привязку к существующему участнику
+func notifyAdminsLinkRequest(ctx context.Context, participant *db.Participant) {
+	admins, err := db.GetAdminIDs()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting admins", "err", err)
+		return
+	}
+
+	request := participant.LinkRequest
+	account := fmt.Sprintf("Telegram user %d", request.TelegramID)
+	if request.Username != "" {
+		account = fmt.Sprintf("@%s (%d)", request.Username, request.TelegramID)
+	}
+	text := fmt.Sprintf("%s asks to be linked to the existing participant %s.\nApprove with /approve_link %s or decline with /reject_link %s.",
+		account, participant.Name, participant.Name, participant.Name)
+	for _, admin := range admins {
+		_, err := bot.Send(tgbotapi.NewMessage(admin, text))
+		if err != nil {
+			slog.ErrorContext(ctx, "Error notifying admin about link request", "admin_id", admin, "err", err)
+		}
+	}
+}
+
+func approveLinkHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	name := strings.TrimSpace(message.CommandArguments())
+	if name == "" {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /approve_link <participant>"))
+		return
+	}
+	participant := findParticipantOrReply(ctx, message.Chat.ID, name)
+	if participant == nil {
+		return
+	}
+
+	participant, err := services.ApproveLinkRequest(participant.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error approving link request", "name", name, "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Could not link the account: "+err.Error()))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("The Telegram account is now linked to %s.", participant.Name)))
+	_, err = bot.Send(tgbotapi.NewMessage(participant.TelegramID,
+		fmt.Sprintf("Your account is now linked to %s. Use /join to sign up for the open tournament.", participant.Name)))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error notifying linked participant", "participant_id", participant.ID, "err", err)
+	}
+}
+
+func rejectLinkHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	name := strings.TrimSpace(message.CommandArguments())
+	if name == "" {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /reject_link <participant>"))
+		return
+	}
+	participant := findParticipantOrReply(ctx, message.Chat.ID, name)
+	if participant == nil {
+		return
+	}
+
+	request, err := services.RejectLinkRequest(participant.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error rejecting link request", "participant_id", participant.ID, "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Could not reject the request: "+err.Error()))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("The link request for %s has been rejected.", participant.Name)))
+	_, err = bot.Send(tgbotapi.NewMessage(request.TelegramID,
+		fmt.Sprintf("Your request to be linked to %s was declined. Register under your own name with /register <name surname>.", participant.Name)))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error notifying rejected user", "telegram_id", request.TelegramID, "err", err)
+	}
+}
+
+func joinHandler(ctx context.Context, message *tgbotapi.Message) {
+	tournament := openTournamentOrReply(ctx, message.Chat.ID)
+	if tournament == nil {
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, joinTournament(ctx, tournament.ID, message.From.ID)))
+}
+
+func withdrawHandler(ctx context.Context, message *tgbotapi.Message) {
+	tournament := openTournamentOrReply(ctx, message.Chat.ID)
+	if tournament == nil {
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, withdrawFromTournament(ctx, tournament.ID, message.From.ID)))
+}
+
+// registrationDeadlineHandler принимает время в формате "2006-01-02 15:04" или "15:04" (сегодня)
+func registrationDeadlineHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	deadline, err := parseDeadline(strings.TrimSpace(message.CommandArguments()), time.Now())
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /registration_deadline <YYYY-MM-DD HH:MM> or <HH:MM>"))
+		return
+	}
+
+	tournament := openTournamentOrReply(ctx, message.Chat.ID)
+	if tournament == nil {
+		return
+	}
+
+	err = services.SetRegistrationDeadline(tournament.ID, deadline)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error setting registration deadline", "tournament_id", tournament.ID, "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while setting the registration deadline."))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Registration for %s closes at %s.", tournament.Name, deadline.Format("02.01.2006 15:04"))))
+}
+
+func parseDeadline(value string, now time.Time) (time.Time, error) {
+	if deadline, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		return deadline, nil
+	}
+	clock, err := time.ParseInLocation("15:04", value, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, time.Local), nil
+}
+
+func joinTournamentCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	tournamentID, err := strconv.Atoi(strings.TrimPrefix(callback.Data, "join_tournament_"))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error converting tournament ID", "err", err)
+		return
+	}
+	// Кнопка стоит в канале, поэтому отвечаем всплывающим уведомлением
+	bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, joinTournament(ctx, tournamentID, callback.From.ID)))
+}
+
+func withdrawTournamentCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	tournamentID, err := strconv.Atoi(strings.TrimPrefix(callback.Data, "withdraw_tournament_"))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error converting tournament ID", "err", err)
+		return
+	}
+	bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, withdrawFromTournament(ctx, tournamentID, callback.From.ID)))
+}
+
+// joinTournament записывает пользователя Telegram на турнир и возвращает текст ответа
+func joinTournament(ctx context.Context, tournamentID int, telegramID int64) string {
+	participant, err := db.GetParticipantByTelegramID(telegramID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting participant", "err", err)
+		return "An error occurred, please try again later."
+	}
+	if participant == nil {
+		return "Your account is not linked to a participant yet. Send /register <name surname> to the bot in a private chat."
+	}
+
+	result, err := services.JoinTournament(tournamentID, participant.Name)
+	switch {
+	case errors.Is(err, services.ErrAlreadyRegistered):
+		return "You are already registered for this tournament."
+	case errors.Is(err, services.ErrRegistrationClosed):
+		return "Registration for this tournament is closed."
+	case err != nil:
+		slog.ErrorContext(ctx, "Error joining tournament", "tournament_id", tournamentID, "err", err)
+		return "An error occurred, please try again later."
+	case result.Waitlisted:
+		return fmt.Sprintf("The tournament is full. You are #%d on the waitlist.", result.Position)
+	default:
+		return "You are registered for the tournament!"
+	}
+}
+
+// withdrawFromTournament снимает пользователя Telegram с турнира и возвращает текст ответа
+func withdrawFromTournament(ctx context.Context, tournamentID int, telegramID int64) string {
+	participant, err := db.GetParticipantByTelegramID(telegramID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting participant", "err", err)
+		return "An error occurred, please try again later."
+	}
+	if participant == nil {
+		return "Your account is not linked to a participant."
+	}
+
+	promoted, err := services.WithdrawFromTournament(tournamentID, participant.Name)
+	if errors.Is(err, services.ErrNotRegistered) {
+		return "You are not registered for this tournament."
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error withdrawing from tournament", "tournament_id", tournamentID, "err", err)
+		return "Could not withdraw: " + err.Error()
+	}
+
+	tournament, err := services.GetTournament(tournamentID)
+	if err == nil {
+		notifyPromoted(ctx, tournament, promoted)
+	}
+	return "You have withdrawn from the tournament."
+}
+
+// notifyPromoted сообщает игроку, что он перешел из листа ожидания в участники
+func notifyPromoted(ctx context.Context, tournament *db.Tournament, participantName string) {
+	if participantName == "" {
+		return
+	}
+
+	participant, err := db.FindParticipant(participantName)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting promoted participant", "err", err)
+		return
+	}
+	if participant == nil || participant.TelegramID == 0 {
+		return
+	}
+
+	text := fmt.Sprintf("A spot opened up in %s — you have been moved from the waitlist to the participants!", tournament.Name)
+	_, err = bot.Send(tgbotapi.NewMessage(participant.TelegramID, text))
+	if err != nil {
+		slog.ErrorContext(ctx, "Error notifying promoted participant", "participant_id", participant.ID, "err", err)
+	}
+}
+
+func openTournamentOrReply(ctx context.Context, chatID int64) *db.Tournament {
+	tournament, err := services.GetOpenTournament()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting open tournament", "err", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "An error occurred while looking for an open tournament."))
+		return nil
+	}
+	if tournament == nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "There is no tournament open for registration."))
+	}
+	return tournament
+}
@@ -0,0 +1,194 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strings"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/notifications"
+	"tournament-bot/internal/services"
+)
+
+func seasonStartHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+	name := strings.TrimSpace(message.CommandArguments())
+	if name == "" {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /season_start <season_name>"))
+		return
+	}
+
+	season, err := services.StartSeason(name)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Cannot start the season: "+err.Error()))
+		return
+	}
+
+	if err := notifications.SendSeasonStartMessage(season); err != nil {
+		slog.ErrorContext(ctx, "Error sending season start message", "err", err)
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Season %s has started.", season.Name)))
+}
+
+func seasonEndHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	season, err := services.EndSeason()
+	if errors.Is(err, services.ErrNoActiveSeason) {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "No season is in progress. Start one with /season_start."))
+		return
+	}
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Cannot end the season: "+err.Error()))
+		return
+	}
+
+	if err := notifications.SendSeasonEndMessage(season); err != nil {
+		slog.ErrorContext(ctx, "Error sending season end message", "err", err)
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Season %s has ended, the final table is saved.", season.Name)))
+}
+
+// seasonsHandler показывает список сезонов
+func seasonsHandler(ctx context.Context, message *tgbotapi.Message) {
+	seasons, err := services.GetSeasons()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting seasons", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while getting seasons."))
+		return
+	}
+	if len(seasons) == 0 {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "No seasons yet."))
+		return
+	}
+
+	var text strings.Builder
+	text.WriteString("Seasons:\n\n")
+	for _, season := range seasons {
+		text.WriteString(fmt.Sprintf("%d. %s — %s\n", season.ID, season.Name, seasonPeriod(&season)))
+	}
+	text.WriteString("\nShow a season table with /season <id>")
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, text.String()))
+}
+
+func seasonPeriod(season *db.Season) string {
+	start := "…"
+	if !season.StartedAt.IsZero() {
+		start = season.StartedAt.Format("02.01.2006")
+	}
+	if season.IsActive {
+		return start + " — in progress"
+	}
+	return start + " – " + season.EndedAt.Format("02.01.2006")
+}
+
+// seasonHandler показывает таблицу сезона: указанного или текущего
+func seasonHandler(ctx context.Context, message *tgbotapi.Message) {
+	var season *db.Season
+	var err error
+	if arg := strings.TrimSpace(message.CommandArguments()); arg != "" {
+		season, err = services.FindSeason(arg)
+	} else {
+		season, err = services.GetActiveSeason()
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting season", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while getting the season."))
+		return
+	}
+	if season == nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Season not found. See /seasons."))
+		return
+	}
+
+	table, err := services.SeasonTable(season)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting season table", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while getting the season table."))
+		return
+	}
+
+	text := fmt.Sprintf("<b>%s</b> (%s)\n\n", season.Name, seasonPeriod(season))
+	if len(table) == 0 {
+		text += "No tournaments played yet."
+	} else {
+		text += notifications.SeasonTableText(table)
+	}
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ParseMode = "HTML"
+	bot.Send(msg)
+}
+
+// seasonScoringHandler показывает правила начисления очков сезона, а админу позволяет
+// их изменить: /season_scoring places=8,4,2 group=2,2,2 participation=0 win=0 draw=0
+func seasonScoringHandler(ctx context.Context, message *tgbotapi.Message) {
+	season, err := services.GetActiveSeason()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting active season", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while getting the season."))
+		return
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		scoring := db.DefaultSeasonScoring
+		title := "Очки за турнир вне сезона"
+		if season != nil {
+			scoring = season.Scoring
+			title = "Очки за турнир в сезоне " + season.Name
+		}
+		msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("<b>%s:</b>\n%s", title, notifications.SeasonScoringText(scoring)))
+		msg.ParseMode = "HTML"
+		bot.Send(msg)
+		return
+	}
+
+	if !requireAdmin(ctx, message) {
+		return
+	}
+	if season == nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "No season is in progress. Start one with /season_start."))
+		return
+	}
+	scoring, err := services.ParseSeasonScoring(season.Scoring, args)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Invalid rules: "+err.Error()+
+			"\nUsage: /season_scoring places=8,4,2 group=2,2,2 participation=0 win=0 draw=0"))
+		return
+	}
+	season, err = services.SetSeasonScoring(scoring)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Cannot change the rules: "+err.Error()))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf(
+		"<b>Очки за турнир в сезоне %s:</b>\n%s\nNew rules apply to the next tournaments. Run /recalculate_season to rescore tournaments already played.",
+		season.Name, notifications.SeasonScoringText(season.Scoring)))
+	msg.ParseMode = "HTML"
+	bot.Send(msg)
+}
+
+func recalculateSeasonHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	changed, err := services.RecalculateSeasonPoints()
+	if errors.Is(err, services.ErrNoActiveSeason) {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "No season is in progress. Start one with /season_start."))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error recalculating season points", "err", err)
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "An error occurred while recalculating season points."))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Season points recalculated, %d players changed.", changed)))
+}
@@ -0,0 +1,397 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/notifications"
+	"tournament-bot/internal/services"
+)
+
+// Поля мастера, которые вводятся текстом
+const (
+	wizardAwaitingName     = "name"
+	wizardAwaitingPoints   = "points"
+	wizardAwaitingTemplate = "template"
+)
+
+// tournamentWizard — настройки нового турнира, которые админ меняет кнопками
+type tournamentWizard struct {
+	Settings db.TournamentSettings
+	// Awaiting — поле, значение которого ждем следующим сообщением
+	Awaiting string
+}
+
+type tournamentWizardStore struct {
+	mu      sync.Mutex
+	wizards map[int64]*tournamentWizard
+}
+
+func (s *tournamentWizardStore) Get(userID int64) (*tournamentWizard, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wizard, ok := s.wizards[userID]
+	return wizard, ok
+}
+
+func (s *tournamentWizardStore) Set(userID int64, wizard *tournamentWizard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wizards[userID] = wizard
+}
+
+func (s *tournamentWizardStore) Delete(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.wizards, userID)
+}
+
+var tournamentWizards = &tournamentWizardStore{wizards: make(map[int64]*tournamentWizard)}
+
+// sendTournamentWizardStart предлагает создать турнир по шаблону в одно нажатие
+// или открыть мастер с настройками по умолчанию
+func sendTournamentWizardStart(ctx context.Context, chatID int64) {
+	templates, err := services.GetTournamentTemplates()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting tournament templates", "err", err)
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, template := range templates {
+		callbackData := fmt.Sprintf("wizard_template_%d_%s", i, template.Name)
+		button := tgbotapi.NewInlineKeyboardButtonData("📋 "+template.Name, callbackData)
+		rows = append(rows, []tgbotapi.InlineKeyboardButton{button})
+	}
+	rows = append(rows, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("⚙️ Custom setup", "wizard_new"),
+	})
+
+	msg := tgbotapi.NewMessage(chatID, "Create a tournament from a template or set it up manually:")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	_, err = bot.Send(msg)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sending message", "err", err)
+	}
+}
+
+func tournamentWizardCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	isAdmin, err := db.IsAdmin(callback.From.ID)
+	if err != nil || !isAdmin {
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "You don't have permission to create tournaments."))
+		return
+	}
+
+	action := strings.TrimPrefix(callback.Data, "wizard_")
+	if strings.HasPrefix(action, "template_") {
+		// В данных кнопки номер шаблона и его имя; шаблон ищем по имени
+		parts := strings.SplitN(action, "_", 3)
+		if len(parts) != 3 {
+			return
+		}
+		createTournamentFromTemplate(ctx, callback, parts[2])
+		return
+	}
+
+	if action == "new" {
+		settings := services.DefaultTournamentSettings()
+		categories, err := db.GetTeamCategories()
+		if err == nil && len(categories) > 0 {
+			settings.TeamCategory = categories[0].Name
+		} else {
+			settings.FreeChoice = true
+		}
+		wizard := &tournamentWizard{Settings: settings}
+		tournamentWizards.Set(callback.From.ID, wizard)
+
+		msg := tgbotapi.NewMessage(callback.Message.Chat.ID, wizardText(wizard))
+		msg.ReplyMarkup = wizardKeyboard()
+		bot.Send(msg)
+		bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+		return
+	}
+
+	wizard, ok := tournamentWizards.Get(callback.From.ID)
+	if !ok {
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "This setup has expired. Use /create_tournament to start again."))
+		return
+	}
+
+	settings := &wizard.Settings
+	switch action {
+	case "name":
+		wizard.Awaiting = wizardAwaitingName
+		bot.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "Send the tournament name. It will be used as \"<date> <name> #N\"."))
+	case "points":
+		wizard.Awaiting = wizardAwaitingPoints
+		bot.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "Send points for a win, draw and loss, e.g. 3/1/0."))
+	case "save":
+		wizard.Awaiting = wizardAwaitingTemplate
+		bot.Send(tgbotapi.NewMessage(callback.Message.Chat.ID, "Send a name for the template."))
+	case "min_dec":
+		settings.MinParticipants--
+	case "min_inc":
+		settings.MinParticipants++
+	case "max_dec":
+		settings.MaxParticipants--
+	case "max_inc":
+		settings.MaxParticipants++
+	case "format":
+		if settings.Format == db.FormatRoundRobin {
+			settings.Format = db.FormatDoubleRoundRobin
+		} else {
+			settings.Format = db.FormatRoundRobin
+		}
+	case "playoff":
+		if settings.PlayoffQualifiers >= 4 {
+			settings.PlayoffQualifiers = 2
+		} else {
+			settings.PlayoffQualifiers++
+		}
+	case "category":
+		nextWizardCategory(ctx, settings)
+	case "draw":
+		settings.DrawStrategy = nextDrawStrategy(settings.DrawStrategy)
+	case "ceremony":
+		settings.DrawCeremony = !settings.DrawCeremony
+	case "create":
+		tournament, err := services.CreateTournament(wizard.Settings)
+		if err != nil {
+			bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "Cannot create the tournament: "+err.Error()))
+			return
+		}
+		tournamentWizards.Delete(callback.From.ID)
+		removeKeyboard(callback.Message.Chat.ID, callback.Message.MessageID)
+		announceNewTournament(ctx, callback.Message.Chat.ID, tournament)
+		bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+		return
+	case "cancel":
+		tournamentWizards.Delete(callback.From.ID)
+		bot.Send(tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, "Tournament setup cancelled."))
+		bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+		return
+	}
+
+	// Минимум не может быть меньше 2, а максимум — меньше минимума
+	settings.MinParticipants = max(settings.MinParticipants, 2)
+	settings.MaxParticipants = max(settings.MaxParticipants, settings.MinParticipants)
+
+	refreshWizardMessage(callback.Message.Chat.ID, callback.Message.MessageID, wizard)
+	bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+}
+
+// nextWizardCategory переключает категорию команд по кругу; после последней категории
+// идет свободный выбор команд
+func nextWizardCategory(ctx context.Context, settings *db.TournamentSettings) {
+	categories, err := db.GetTeamCategories()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting team categories", "err", err)
+		return
+	}
+
+	next := 0
+	if !settings.FreeChoice {
+		for i, category := range categories {
+			if category.Name == settings.TeamCategory {
+				next = i + 1
+			}
+		}
+	}
+
+	if next < len(categories) {
+		settings.TeamCategory = categories[next].Name
+		settings.FreeChoice = false
+	} else {
+		settings.TeamCategory = ""
+		settings.FreeChoice = true
+	}
+}
+
+// nextDrawStrategy переключает способ распределения команд по кругу
+func nextDrawStrategy(current string) string {
+	for i, strategy := range db.DrawStrategies {
+		if strategy == current {
+			return db.DrawStrategies[(i+1)%len(db.DrawStrategies)]
+		}
+	}
+	return db.DrawStrategies[0]
+}
+
+// handleTournamentWizardInput принимает текстовые значения для мастера.
+// Возвращает false, если мастер не ждет ввода от пользователя.
+func handleTournamentWizardInput(ctx context.Context, message *tgbotapi.Message) bool {
+	wizard, ok := tournamentWizards.Get(message.From.ID)
+	if !ok || wizard.Awaiting == "" {
+		return false
+	}
+
+	text := strings.TrimSpace(message.Text)
+	switch wizard.Awaiting {
+	case wizardAwaitingName:
+		if text == "" || len(text) > 64 {
+			bot.Send(tgbotapi.NewMessage(message.Chat.ID, "The name must be 1-64 characters long."))
+			return true
+		}
+		wizard.Settings.Name = text
+	case wizardAwaitingPoints:
+		rules, err := parsePointsRules(text)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Send points as win/draw/loss, e.g. 3/1/0."))
+			return true
+		}
+		wizard.Settings.PointsRules = rules
+	case wizardAwaitingTemplate:
+		err := services.SaveTournamentTemplate(text, wizard.Settings)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Cannot save the template: "+err.Error()))
+			return true
+		}
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Template %s saved. It will be offered by /create_tournament.", text)))
+	}
+	wizard.Awaiting = ""
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, wizardText(wizard))
+	msg.ReplyMarkup = wizardKeyboard()
+	bot.Send(msg)
+	return true
+}
+
+func parsePointsRules(value string) (db.PointsRules, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 3 {
+		return db.PointsRules{}, errors.New("expected win/draw/loss")
+	}
+
+	var points [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return db.PointsRules{}, err
+		}
+		points[i] = n
+	}
+	return db.PointsRules{Win: points[0], Draw: points[1], Loss: points[2]}, nil
+}
+
+func createTournamentFromTemplate(ctx context.Context, callback *tgbotapi.CallbackQuery, name string) {
+	template, err := services.GetTournamentTemplate(name)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error getting tournament template", "template", name, "err", err)
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "An error occurred while loading the template."))
+		return
+	}
+	if template == nil {
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "Template not found. Use /create_tournament to see the current list."))
+		return
+	}
+
+	tournament, err := services.CreateTournament(template.Settings)
+	if err != nil {
+		bot.Request(tgbotapi.NewCallbackWithAlert(callback.ID, "Cannot create the tournament: "+err.Error()))
+		return
+	}
+
+	removeKeyboard(callback.Message.Chat.ID, callback.Message.MessageID)
+	announceNewTournament(ctx, callback.Message.Chat.ID, tournament)
+	bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+}
+
+// announceNewTournament объявляет набор в канале и показывает админу список участников
+func announceNewTournament(ctx context.Context, chatID int64, tournament *db.Tournament) {
+	err := notifications.SendRegistrationOpenMessage(tournament)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sending registration open message", "err", err)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Tournament %s has been created. Add participants:", tournament.Name))
+	msg.ReplyMarkup, _ = getParticipantsKeyboard(ctx, tournament.ID)
+	_, err = bot.Send(msg)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error sending message", "err", err)
+	}
+}
+
+func refreshWizardMessage(chatID int64, messageID int, wizard *tournamentWizard) {
+	msg := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, wizardText(wizard), wizardKeyboard())
+	bot.Send(msg)
+}
+
+func wizardText(wizard *tournamentWizard) string {
+	settings := wizard.Settings
+
+	format := "single round robin"
+	if settings.Format == db.FormatDoubleRoundRobin {
+		format = "double round robin"
+	}
+	teams := "free choice"
+	if !settings.FreeChoice {
+		teams = fmt.Sprintf("%s (%s)", settings.TeamCategory, services.DrawStrategyLabel(settings.DrawStrategy))
+		if settings.DrawCeremony {
+			teams += ", draw shown in the channel"
+		}
+	}
+
+	return fmt.Sprintf("⚙️ Tournament setup\n\n"+
+		"Name: <date> %s #N\n"+
+		"Players: %d-%d\n"+
+		"Format: %s\n"+
+		"Playoff: top %d\n"+
+		"Points: win %d, draw %d, loss %d\n"+
+		"Teams: %s",
+		settings.Name, settings.MinParticipants, settings.MaxParticipants, format,
+		settings.PlayoffQualifiers, settings.PointsRules.Win, settings.PointsRules.Draw, settings.PointsRules.Loss,
+		teams)
+}
+
+func wizardKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Name", "wizard_name"),
+			tgbotapi.NewInlineKeyboardButtonData("🔢 Points", "wizard_points"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Min −", "wizard_min_dec"),
+			tgbotapi.NewInlineKeyboardButtonData("Min +", "wizard_min_inc"),
+			tgbotapi.NewInlineKeyboardButtonData("Max −", "wizard_max_dec"),
+			tgbotapi.NewInlineKeyboardButtonData("Max +", "wizard_max_inc"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔁 Format", "wizard_format"),
+			tgbotapi.NewInlineKeyboardButtonData("🏆 Playoff", "wizard_playoff"),
+			tgbotapi.NewInlineKeyboardButtonData("🎽 Teams", "wizard_category"),
+			tgbotapi.NewInlineKeyboardButtonData("🎲 Draw", "wizard_draw"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🎥 Draw ceremony", "wizard_ceremony"),
+			tgbotapi.NewInlineKeyboardButtonData("💾 Save as template", "wizard_save"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Create", "wizard_create"),
+			tgbotapi.NewInlineKeyboardButtonData("✖️ Cancel", "wizard_cancel"),
+		),
+	)
+}
+
+func deleteTemplateHandler(ctx context.Context, message *tgbotapi.Message) {
+	if !requireAdmin(ctx, message) {
+		return
+	}
+
+	name := strings.TrimSpace(message.CommandArguments())
+	if name == "" {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Usage: /delete_template <name>"))
+		return
+	}
+
+	err := services.DeleteTournamentTemplate(name)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(message.Chat.ID, "Could not delete the template: "+err.Error()))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("Template %s deleted.", name)))
+}
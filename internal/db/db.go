@@ -2,34 +2,65 @@ package db
 
 import (
 	"context"
+	"errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"log"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"log/slog"
+	"os"
 )
 
 var DB *mongo.Database
 
+var client *mongo.Client
+
 func InitDB(mongoURI string) {
 
 	clientOptions := options.Client().ApplyURI(mongoURI)
-	client, err := mongo.Connect(context.TODO(), clientOptions)
+	var err error
+	client, err = mongo.Connect(context.TODO(), clientOptions)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("Error connecting to MongoDB", "err", err)
+		os.Exit(1)
 	}
 
 	err = client.Ping(context.TODO(), nil)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("Error pinging MongoDB", "err", err)
+		os.Exit(1)
 	}
 
 	DB = client.Database("tournament")
-	log.Println("Connected to MongoDB!")
+	slog.Info("Connected to MongoDB")
+
+	// Индексы и валидаторы: ошибки не фатальны, бот может работать и без них
+	drift, err := EnsureSchema(context.TODO())
+	logSchemaDrift(drift)
+	if err != nil {
+		slog.Error("Error ensuring database schema", "err", err)
+	}
+}
+
+// Ping проверяет соединение с MongoDB
+func Ping(ctx context.Context) error {
+	if client == nil {
+		return errors.New("database is not initialized")
+	}
+	return client.Ping(ctx, readpref.Primary())
+}
+
+// Close закрывает соединение с MongoDB
+func Close(ctx context.Context) error {
+	if client == nil {
+		return nil
+	}
+	return client.Disconnect(ctx)
 }
 
 func AddParticipant(name string) error {
 	// Добавляем участника в базу данных
-	_, err := DB.Collection("participants").InsertOne(context.Background(), bson.M{"name": name})
+	_, err := DB.Collection("participants").InsertOne(context.Background(), bson.M{"_id": NewParticipantID(), "name": name})
 	return err
 }
 
@@ -100,6 +131,9 @@ func GetTeamCategories() ([]TeamCategory, error) {
 		if err := cursor.Decode(&category); err != nil {
 			return nil, err
 		}
+		if err := ResolveCategoryTeams(&category); err != nil {
+			return nil, err
+		}
 		categories = append(categories, category)
 	}
 	return categories, nil
@@ -111,6 +145,9 @@ func GetTeamCategoryByName(name string) (*TeamCategory, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := ResolveCategoryTeams(&category); err != nil {
+		return nil, err
+	}
 	return &category, nil
 }
 
@@ -139,10 +176,27 @@ func IsAdmin(userID int64) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	log.Printf("Admin check result for user %d: %v", userID, count > 0)
+	slog.Debug("Admin check", "user_id", userID, "is_admin", count > 0)
 	return count > 0, nil
 }
 
+// GetAdminIDs возвращает Telegram ID всех админов
+func GetAdminIDs() ([]int64, error) {
+	cursor, err := DB.Collection("admins").Find(context.Background(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var admins []Admin
+	if err := cursor.All(context.Background(), &admins); err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(admins))
+	for _, admin := range admins {
+		ids = append(ids, admin.UserID)
+	}
+	return ids, nil
+}
+
 func AddAdmin(userID int64) error {
 	admin := Admin{UserID: userID}
 	_, err := DB.Collection("admins").InsertOne(context.Background(), admin)
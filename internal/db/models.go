@@ -4,10 +4,54 @@ import (
 	"time"
 )
 
+// Participant хранится под постоянным строковым ID. Турниры ссылаются на участников
+// по имени, поэтому переименование и слияние переписывают имена во всех турнирах.
 type Participant struct {
-	ID    string           `bson:"_id"`
-	Name  string           `bson:"name"`
-	Stats ParticipantStats `bson:"stats"`
+	ID         string           `bson:"_id"`
+	Name       string           `bson:"name"`
+	TelegramID int64            `bson:"telegram_id,omitempty"`
+	Username   string           `bson:"username,omitempty"`
+	Aliases    []string         `bson:"aliases,omitempty"`
+	Stats      ParticipantStats `bson:"stats"`
+	Rating     *PlayerRating    `bson:"rating,omitempty"`
+	// LinkRequest — запрос на привязку аккаунта Telegram, ждущий подтверждения админа
+	LinkRequest *LinkRequest `bson:"link_request,omitempty"`
+}
+
+// LinkRequest — просьба пользователя Telegram привязать его к существующему участнику
+type LinkRequest struct {
+	TelegramID  int64     `bson:"telegram_id"`
+	Username    string    `bson:"username,omitempty"`
+	RequestedAt time.Time `bson:"requested_at"`
+}
+
+// PlayerRating — текущий рейтинг игрока с учетом силы соперников
+type PlayerRating struct {
+	System     string    `bson:"system"`
+	Value      float64   `bson:"value"`
+	Deviation  float64   `bson:"deviation,omitempty"`
+	Volatility float64   `bson:"volatility,omitempty"`
+	Matches    int       `bson:"matches"`
+	UpdatedAt  time.Time `bson:"updated_at"`
+}
+
+// RatingChange — изменение рейтинга игрока после одного матча (коллекция rating_history)
+type RatingChange struct {
+	Participant   string       `bson:"participant"`
+	TournamentID  int          `bson:"tournament_id"`
+	Opponent      string       `bson:"opponent"`
+	Team          string       `bson:"team"`
+	OpponentTeam  string       `bson:"opponent_team"`
+	Score         int          `bson:"score"`
+	OpponentScore int          `bson:"opponent_score"`
+	Before        PlayerRating `bson:"before"`
+	After         PlayerRating `bson:"after"`
+	Date          time.Time    `bson:"date"`
+}
+
+// Delta — изменение рейтинга за матч
+func (c *RatingChange) Delta() float64 {
+	return c.After.Value - c.Before.Value
 }
 
 type ParticipantStats struct {
@@ -23,15 +67,19 @@ type ParticipantStats struct {
 }
 
 type TournamentStat struct {
-	TournamentID  int    `bson:"tournament_id"`
-	Place         string `bson:"place"`
-	Points        int    `bson:"points"`
-	GoalsScored   int    `bson:"goals_scored"`
-	GoalsConceded int    `bson:"goals_conceded"`
-	Wins          int    `bson:"wins"`
-	Losses        int    `bson:"losses"`
-	Draws         int    `bson:"draws"`
-	MatchesPlayed int    `bson:"matches_played"`
+	TournamentID int `bson:"tournament_id"`
+	SeasonID     int `bson:"season_id,omitempty"`
+	// Place — этап, на котором участник выбыл: first, second, third, quarter_final или group
+	Place string `bson:"place"`
+	// Position — итоговое место в турнире (с 1); 0 у записей, сохраненных до подсчета мест
+	Position      int `bson:"position,omitempty"`
+	Points        int `bson:"points"`
+	GoalsScored   int `bson:"goals_scored"`
+	GoalsConceded int `bson:"goals_conceded"`
+	Wins          int `bson:"wins"`
+	Losses        int `bson:"losses"`
+	Draws         int `bson:"draws"`
+	MatchesPlayed int `bson:"matches_played"`
 }
 
 type Tournament struct {
@@ -49,6 +97,183 @@ type Tournament struct {
 	CreatedAt        time.Time         `bson:"created_at"`
 	Playoff          *Playoff          `bson:"playoff,omitempty"`
 	IsCompleted      bool              `bson:"is_completed"`
+
+	// Саморегистрация: после RegistrationDeadline записаться нельзя,
+	// а сверх MaxParticipants игроки попадают в лист ожидания
+	RegistrationDeadline time.Time `bson:"registration_deadline,omitempty"`
+	Waitlist             []string  `bson:"waitlist,omitempty"`
+
+	// Подтверждение участия перед стартом: кто не отметился до CheckInDeadline,
+	// выбывает, а его место получает отметившийся игрок из листа ожидания
+	CheckInDeadline time.Time `bson:"check_in_deadline,omitempty"`
+	CheckInClosed   bool      `bson:"check_in_closed,omitempty"`
+	CheckedIn       []string  `bson:"checked_in,omitempty"`
+
+	// Участники, снявшиеся по ходу турнира; их оставшиеся матчи засчитаны техническими поражениями
+	Withdrawn []string `bson:"withdrawn,omitempty"`
+
+	// Настройки из мастера создания. У турниров, созданных до мастера, полей нет,
+	// и действуют значения по умолчанию: один круг, 4 команды в плей-офф, очки 3/1/0.
+	Format            string      `bson:"format,omitempty"`
+	PlayoffQualifiers int         `bson:"playoff_qualifiers,omitempty"`
+	PointsRules       PointsRules `bson:"points_rules,omitempty"`
+	// FreeChoice — участники выбирают команды сами, без жеребьевки
+	FreeChoice bool `bson:"free_choice,omitempty"`
+
+	// DrawStrategy — способ распределения команд; DrawSeed — зерно генератора жеребьевки,
+	// по которому ее результат можно воспроизвести
+	DrawStrategy string `bson:"draw_strategy,omitempty"`
+	DrawSeed     int64  `bson:"draw_seed,omitempty"`
+	// DraftOrder — очередность выбора команд при драфте
+	DraftOrder []string `bson:"draft_order,omitempty"`
+	// DrawCeremony — показывать жеребьевку в канале по одной паре
+	DrawCeremony bool `bson:"draw_ceremony,omitempty"`
+	// Draw — зафиксированные до жеребьевки входные данные и хэш зерна
+	Draw *DrawRecord `bson:"draw,omitempty"`
+	// SeasonID — сезон, к которому относится турнир; 0 — турнир создан вне сезона
+	SeasonID int `bson:"season_id,omitempty"`
+}
+
+// Season — сезон: турниры между /season_start и /season_end. Очки игроков за сезон
+// копятся в Participant.Stats, а при завершении сезона таблица замораживается в Standings
+// и статистика игроков обнуляется.
+type Season struct {
+	ID        int           `bson:"id"`
+	Name      string        `bson:"name"`
+	StartedAt time.Time     `bson:"started_at"`
+	EndedAt   time.Time     `bson:"ended_at,omitempty"`
+	IsActive  bool          `bson:"is_active"`
+	Scoring   SeasonScoring `bson:"scoring"`
+	// Standings — итоговая таблица, сохраненная при завершении сезона
+	Standings []SeasonStanding `bson:"standings,omitempty"`
+}
+
+// SeasonScoring — правила начисления очков сезона за турнир
+type SeasonScoring struct {
+	// Places — очки за итоговое место: первый элемент за 1 место, второй за 2 и т.д.
+	Places []int `bson:"places"`
+	// GroupBonus — бонус за место в групповом этапе: первый элемент за 1 место в группе
+	GroupBonus []int `bson:"group_bonus"`
+	// Participation — очки за участие в турнире
+	Participation int `bson:"participation"`
+	// Win и Draw — очки за каждую победу и ничью в турнире
+	Win  int `bson:"win"`
+	Draw int `bson:"draw"`
+}
+
+// IsZero сообщает, что правила не заданы: ни одно действие не приносит очков
+func (s SeasonScoring) IsZero() bool {
+	for _, points := range s.Places {
+		if points != 0 {
+			return false
+		}
+	}
+	for _, points := range s.GroupBonus {
+		if points != 0 {
+			return false
+		}
+	}
+	return s.Participation == 0 && s.Win == 0 && s.Draw == 0
+}
+
+// DefaultSeasonScoring — очки, которые начислялись до появления настраиваемых правил:
+// 8, 4 и 2 за призовые места и 2 за тройку группового этапа
+var DefaultSeasonScoring = SeasonScoring{Places: []int{8, 4, 2}, GroupBonus: []int{2, 2, 2}}
+
+// SeasonStanding — строка итоговой таблицы сезона
+type SeasonStanding struct {
+	Position          int    `bson:"position"`
+	Participant       string `bson:"participant"`
+	Points            int    `bson:"points"`
+	TournamentsPlayed int    `bson:"tournaments_played"`
+	Wins              int    `bson:"wins"`
+	Draws             int    `bson:"draws"`
+	Losses            int    `bson:"losses"`
+	GoalsScored       int    `bson:"goals_scored"`
+	GoalsConceded     int    `bson:"goals_conceded"`
+}
+
+// DrawRecord фиксирует все, от чего зависит результат жеребьевки. Commitment — хэш
+// входных данных вместе с зерном — публикуется до жеребьевки, а зерно — после, поэтому
+// любой может повторить жеребьевку и убедиться, что ее не перезапускали и не меняли данные.
+type DrawRecord struct {
+	Strategy    string    `bson:"strategy"`
+	Commitment  string    `bson:"commitment"`
+	CommittedAt time.Time `bson:"committed_at"`
+	// PublishedAt — когда хэш опубликован в канале; после этого жеребьевка не фиксируется заново
+	PublishedAt time.Time `bson:"published_at,omitempty"`
+	// Inputs — канонический JSON входных данных на момент фиксации, именно он хэшируется.
+	// Поля ниже хранят те же данные для чтения: переименование участника меняет их, но не Inputs.
+	Inputs       string   `bson:"inputs"`
+	Participants []string `bson:"participants"`
+	// Teams — команды категории в порядке справочника
+	Teams []string `bson:"teams"`
+	// Ranking — участники по рейтингу сезона на момент жеребьевки, сильнейшие первыми
+	Ranking []string `bson:"ranking,omitempty"`
+	// Recent — клубы участников в последних турнирах на момент жеребьевки
+	Recent map[string][]string `bson:"recent,omitempty"`
+	// Skills — сила участников по истории выступлений, в порядке Participants
+	Skills []float64 `bson:"skills,omitempty"`
+	// Strengths — рейтинги команд из справочника в порядке Teams, 0 — рейтинга нет
+	Strengths []float64 `bson:"strengths,omitempty"`
+}
+
+// Способы распределения команд между участниками
+const (
+	// DrawRandom — случайная жеребьевка
+	DrawRandom = "random"
+	// DrawNoRepeat — случайная жеребьевка без повторения клубов из последних турниров игрока
+	DrawNoRepeat = "no_repeat"
+	// DrawPots — жеребьевка по корзинам: сильнейшему по рейтингу сезона достается слабейшая корзина
+	DrawPots = "pots"
+	// DrawDraft — игроки по очереди выбирают команды, начиная с самого слабого по рейтингу
+	DrawDraft = "draft"
+	// DrawFreePick — игроки выбирают команды сами, каждую команду может взять только один
+	DrawFreePick = "free_pick"
+	// DrawBalanced — сильным игрокам слабые команды и наоборот, чтобы силы пар были равны
+	DrawBalanced = "balanced"
+)
+
+var DrawStrategies = []string{DrawRandom, DrawNoRepeat, DrawPots, DrawBalanced, DrawDraft, DrawFreePick}
+
+// Форматы группового этапа
+const (
+	FormatRoundRobin       = "round_robin"
+	FormatDoubleRoundRobin = "double_round_robin"
+)
+
+var Formats = []string{FormatRoundRobin, FormatDoubleRoundRobin}
+
+// PointsRules — очки в турнирной таблице за победу, ничью и поражение
+type PointsRules struct {
+	Win  int `bson:"win"`
+	Draw int `bson:"draw"`
+	Loss int `bson:"loss"`
+}
+
+var DefaultPointsRules = PointsRules{Win: 3, Draw: 1, Loss: 0}
+
+// TournamentSettings — параметры нового турнира, которые задаются в мастере
+// и сохраняются в шаблонах
+type TournamentSettings struct {
+	// Name — основа названия: турнир получит имя "<дата> <Name> #N"
+	Name              string      `bson:"name"`
+	MinParticipants   int         `bson:"min_participants"`
+	MaxParticipants   int         `bson:"max_participants"`
+	Format            string      `bson:"format"`
+	PlayoffQualifiers int         `bson:"playoff_qualifiers"`
+	PointsRules       PointsRules `bson:"points_rules"`
+	TeamCategory      string      `bson:"team_category,omitempty"`
+	FreeChoice        bool        `bson:"free_choice,omitempty"`
+	DrawStrategy      string      `bson:"draw_strategy,omitempty"`
+	DrawCeremony      bool        `bson:"draw_ceremony,omitempty"`
+}
+
+// TournamentTemplate — сохраненные настройки для быстрого создания типового турнира
+type TournamentTemplate struct {
+	Name      string             `bson:"name"`
+	Settings  TournamentSettings `bson:"settings"`
+	UpdatedAt time.Time          `bson:"updated_at"`
 }
 
 type Playoff struct {
@@ -59,9 +284,60 @@ type Playoff struct {
 	Winner        string  `bson:"winner"`
 }
 
+// TeamCategory — набор команд для жеребьевки. Команды задаются списком названий (Teams),
+// списком ID из справочника (TeamIDs) или запросом к справочнику (Query). Для категорий
+// из справочника Teams заполняется при чтении, сильнейшие команды первыми.
 type TeamCategory struct {
-	Name  string   `bson:"name"`
-	Teams []string `bson:"teams"`
+	Name    string     `bson:"name"`
+	Teams   []string   `bson:"teams,omitempty"`
+	TeamIDs []int      `bson:"team_ids,omitempty"`
+	Query   *TeamQuery `bson:"query,omitempty"`
+}
+
+// FromCatalog сообщает, что состав категории берется из справочника команд
+func (c *TeamCategory) FromCatalog() bool {
+	return c.Query != nil || len(c.TeamIDs) > 0
+}
+
+// TeamQuery — сохраненный запрос к справочнику команд. Пустые поля не ограничивают выборку.
+type TeamQuery struct {
+	MinStars float64 `bson:"min_stars,omitempty"`
+	MaxStars float64 `bson:"max_stars,omitempty"`
+	League   string  `bson:"league,omitempty"`
+	Country  string  `bson:"country,omitempty"`
+}
+
+// Team — команда из справочника с рейтингами из игры
+type Team struct {
+	// ID выдается счетчиком, на него ссылаются категории
+	ID int `bson:"id"`
+	// SourceID — ID команды во внешней выгрузке (sofifa), 0 — команда добавлена не из выгрузки
+	SourceID  int    `bson:"source_id,omitempty"`
+	Name      string `bson:"name"`
+	ShortCode string `bson:"short_code,omitempty"`
+	Country   string `bson:"country,omitempty"`
+	League    string `bson:"league,omitempty"`
+	// Aliases — другие названия команды, например русские названия из старых категорий
+	Aliases  []string `bson:"aliases,omitempty"`
+	Stars    float64  `bson:"stars"`
+	Overall  int      `bson:"overall,omitempty"`
+	Attack   int      `bson:"attack,omitempty"`
+	Midfield int      `bson:"midfield,omitempty"`
+	Defence  int      `bson:"defence,omitempty"`
+	// Crest — путь к файлу эмблемы
+	Crest     string    `bson:"crest,omitempty"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// Strength — общая сила команды: рейтинг из игры или, если его нет, среднее по линиям
+func (t *Team) Strength() int {
+	if t.Overall > 0 {
+		return t.Overall
+	}
+	if t.Attack+t.Midfield+t.Defence > 0 {
+		return (t.Attack + t.Midfield + t.Defence) / 3
+	}
+	return 0
 }
 
 type Match struct {
@@ -77,6 +353,64 @@ type Match struct {
 	PenaltyScore2 int       `bson:"penalty_score2"`
 	Date          time.Time `bson:"date"`
 	Counted       bool      `bson:"counted"`
+	// Status — исход матча; пустой статус у старых матчей означает MatchPlayed
+	Status string `bson:"status,omitempty"`
+	Reason string `bson:"reason,omitempty"`
+	// Winner — команда, которой присуждена победа при техническом поражении или неявке
+	Winner string `bson:"winner,omitempty"`
+}
+
+// Статусы матча
+const (
+	MatchPlayed = "played"
+	// MatchForfeit — техническое поражение с фиксированным счетом, голы идут в таблицу
+	MatchForfeit = "forfeit"
+	// MatchWalkover — победа из-за неявки соперника, без счета
+	MatchWalkover = "walkover"
+	// MatchAbandoned и MatchPostponed — матч не состоялся и не влияет на таблицу
+	MatchAbandoned = "abandoned"
+	MatchPostponed = "postponed"
+)
+
+// MatchStatuses — статусы, которые может указать администратор
+var MatchStatuses = []string{MatchPlayed, MatchForfeit, MatchWalkover, MatchAbandoned, MatchPostponed}
+
+func (m Match) StatusOrPlayed() string {
+	if m.Status == "" {
+		return MatchPlayed
+	}
+	return m.Status
+}
+
+// Decided сообщает, определен ли исход матча (сыгран или присужден)
+func (m Match) Decided() bool {
+	switch m.StatusOrPlayed() {
+	case MatchPlayed, MatchForfeit, MatchWalkover:
+		return true
+	default:
+		return false
+	}
+}
+
+// WinnerTeam возвращает победителя матча или пустую строку при ничьей и несостоявшемся матче
+func (m Match) WinnerTeam() string {
+	if !m.Decided() {
+		return ""
+	}
+	if m.Winner != "" {
+		return m.Winner
+	}
+	switch {
+	case m.Score1 > m.Score2:
+		return m.Team1
+	case m.Score1 < m.Score2:
+		return m.Team2
+	case m.Penalties && m.PenaltyScore1 > m.PenaltyScore2:
+		return m.Team1
+	case m.Penalties && m.PenaltyScore1 < m.PenaltyScore2:
+		return m.Team2
+	}
+	return ""
 }
 
 type Standing struct {
@@ -100,6 +434,107 @@ func (t *Tournament) HasParticipant(participantName string) bool {
 	return false
 }
 
+func (t *Tournament) IsWaitlisted(participantName string) bool {
+	for _, p := range t.Waitlist {
+		if p == participantName {
+			return true
+		}
+	}
+	return false
+}
+
+// RegistrationOpen сообщает, можно ли сейчас записаться на турнир
+func (t *Tournament) RegistrationOpen(now time.Time) bool {
+	if t.IsActive || t.SetupCompleted || t.IsCompleted || t.CheckInClosed {
+		return false
+	}
+	return t.RegistrationDeadline.IsZero() || now.Before(t.RegistrationDeadline)
+}
+
+// CheckInInProgress сообщает, что подтверждение участия начато и еще не закрыто
+func (t *Tournament) CheckInInProgress() bool {
+	return !t.CheckInDeadline.IsZero() && !t.CheckInClosed
+}
+
+func (t *Tournament) IsCheckedIn(participantName string) bool {
+	for _, p := range t.CheckedIn {
+		if p == participantName {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Tournament) HasTeam(team string) bool {
+	for _, participantTeam := range t.ParticipantTeams {
+		if participantTeam == team {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTeamWithdrawn сообщает, снялся ли с турнира участник, игравший за команду
+func (t *Tournament) IsTeamWithdrawn(team string) bool {
+	for _, p := range t.Withdrawn {
+		if t.ParticipantTeams[p] == team {
+			return true
+		}
+	}
+	return false
+}
+
+// Rounds — сколько раз каждая пара команд встречается в групповом этапе
+func (t *Tournament) Rounds() int {
+	if t.Format == FormatDoubleRoundRobin {
+		return 2
+	}
+	return 1
+}
+
+// PlayoffTeams — сколько команд выходит в плей-офф
+func (t *Tournament) PlayoffTeams() int {
+	if t.PlayoffQualifiers == 0 {
+		return 4
+	}
+	return t.PlayoffQualifiers
+}
+
+// Points возвращает правила начисления очков турнира
+func (t *Tournament) Points() PointsRules {
+	if t.PointsRules == (PointsRules{}) {
+		return DefaultPointsRules
+	}
+	return t.PointsRules
+}
+
+// Strategy возвращает способ распределения команд. Без категории команд игроки
+// всегда выбирают сами; у старых турниров жеребьевка случайная.
+func (t *Tournament) Strategy() string {
+	if t.FreeChoice {
+		return DrawFreePick
+	}
+	if t.DrawStrategy == "" {
+		return DrawRandom
+	}
+	return t.DrawStrategy
+}
+
+// MatchesBetween считает состоявшиеся матчи группового этапа между двумя командами
+// в любом порядке; перенесенные и прерванные матчи можно переиграть
+func (t *Tournament) MatchesBetween(team1, team2 string) int {
+	count := 0
+	for _, match := range t.Matches {
+		if !match.Decided() {
+			continue
+		}
+		if (match.Team1 == team1 && match.Team2 == team2) || (match.Team1 == team2 && match.Team2 == team1) {
+			count++
+		}
+	}
+	return count
+}
+
 type Admin struct {
 	UserID int64 `bson:"user_id"`
 }
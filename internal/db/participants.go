@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewParticipantID возвращает новый постоянный ID участника
+func NewParticipantID() string {
+	return primitive.NewObjectID().Hex()
+}
+
+func GetParticipantByID(id string) (*Participant, error) {
+	return findParticipant(bson.M{"_id": id})
+}
+
+// FindParticipant ищет участника по текущему имени или по одному из прежних имен
+func FindParticipant(name string) (*Participant, error) {
+	return findParticipant(bson.M{"$or": []bson.M{
+		{"name": name},
+		{"aliases": name},
+	}})
+}
+
+func GetParticipantByTelegramID(telegramID int64) (*Participant, error) {
+	return findParticipant(bson.M{"telegram_id": telegramID})
+}
+
+// findParticipant возвращает nil без ошибки, если участник не найден
+func findParticipant(filter bson.M) (*Participant, error) {
+	var participant Participant
+	err := DB.Collection("participants").FindOne(context.Background(), filter).Decode(&participant)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &participant, nil
+}
@@ -0,0 +1,345 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+type indexSpec struct {
+	Name   string
+	Keys   bson.D
+	Unique bool
+	// PartialExists ограничивает индекс документами, в которых есть это поле
+	PartialExists string
+}
+
+// Индексы, которые должны существовать в каждой коллекции
+var collectionIndexes = map[string][]indexSpec{
+	"tournaments": {
+		{Name: "id_unique", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+		{Name: "is_active_created_at", Keys: bson.D{{Key: "is_active", Value: 1}, {Key: "created_at", Value: -1}}},
+	},
+	"participants": {
+		{Name: "name_unique", Keys: bson.D{{Key: "name", Value: 1}}, Unique: true},
+		{Name: "aliases", Keys: bson.D{{Key: "aliases", Value: 1}}},
+		{Name: "telegram_id_unique", Keys: bson.D{{Key: "telegram_id", Value: 1}}, Unique: true, PartialExists: "telegram_id"},
+	},
+	"admins": {
+		{Name: "user_id_unique", Keys: bson.D{{Key: "user_id", Value: 1}}, Unique: true},
+	},
+	"tournament_counters": {
+		{Name: "date_unique", Keys: bson.D{{Key: "date", Value: 1}}, Unique: true},
+	},
+	"team_categories": {
+		{Name: "name_unique", Keys: bson.D{{Key: "name", Value: 1}}, Unique: true},
+	},
+	"seasons": {
+		{Name: "id_unique", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+		{Name: "is_active", Keys: bson.D{{Key: "is_active", Value: 1}}},
+	},
+	"rating_history": {
+		{Name: "participant_date", Keys: bson.D{{Key: "participant", Value: 1}, {Key: "date", Value: -1}}},
+		{Name: "tournament_id", Keys: bson.D{{Key: "tournament_id", Value: 1}}},
+	},
+	"teams": {
+		{Name: "id_unique", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+		{Name: "name_unique", Keys: bson.D{{Key: "name", Value: 1}}, Unique: true},
+		{Name: "source_id_unique", Keys: bson.D{{Key: "source_id", Value: 1}}, Unique: true, PartialExists: "source_id"},
+		{Name: "aliases", Keys: bson.D{{Key: "aliases", Value: 1}}},
+	},
+	"tournament_templates": {
+		{Name: "name_unique", Keys: bson.D{{Key: "name", Value: 1}}, Unique: true},
+	},
+}
+
+type collectionSchema struct {
+	Model    interface{}
+	Required []string
+}
+
+// Модели, из которых строятся валидаторы $jsonSchema, и обязательные поля документов
+var collectionSchemas = map[string]collectionSchema{
+	"tournaments":          {Model: Tournament{}, Required: []string{"id", "name"}},
+	"participants":         {Model: Participant{}, Required: []string{"name"}},
+	"admins":               {Model: Admin{}, Required: []string{"user_id"}},
+	"team_categories":      {Model: TeamCategory{}, Required: []string{"name"}},
+	"teams":                {Model: Team{}, Required: []string{"id", "name"}},
+	"seasons":              {Model: Season{}, Required: []string{"id", "name"}},
+	"rating_history":       {Model: RatingChange{}, Required: []string{"participant", "tournament_id", "date"}},
+	"tournament_templates": {Model: TournamentTemplate{}, Required: []string{"name", "settings"}},
+}
+
+// EnsureSchema создает недостающие индексы и обновляет валидаторы коллекций.
+// Возвращает список расхождений между ожидаемой и фактической схемой; расхождения,
+// которые не удалось исправить (например, дубликаты при создании уникального индекса),
+// остаются в отчете, но не прерывают запуск.
+func EnsureSchema(ctx context.Context) ([]string, error) {
+	var drift []string
+
+	names := make([]string, 0, len(collectionIndexes))
+	for name := range collectionIndexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if schema, ok := collectionSchemas[name]; ok {
+			report, err := ensureValidator(ctx, name, schema)
+			if err != nil {
+				return drift, fmt.Errorf("validator for %s: %v", name, err)
+			}
+			drift = append(drift, report...)
+		}
+
+		report, err := ensureIndexes(ctx, name, collectionIndexes[name])
+		if err != nil {
+			return drift, fmt.Errorf("indexes for %s: %v", name, err)
+		}
+		drift = append(drift, report...)
+	}
+
+	return drift, nil
+}
+
+func ensureIndexes(ctx context.Context, collection string, expected []indexSpec) ([]string, error) {
+	cursor, err := DB.Collection(collection).Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing []bson.M
+	if err := cursor.All(ctx, &existing); err != nil {
+		return nil, err
+	}
+
+	existingByName := make(map[string]bson.M, len(existing))
+	for _, index := range existing {
+		name, _ := index["name"].(string)
+		existingByName[name] = index
+	}
+
+	var drift []string
+	expectedNames := make(map[string]bool, len(expected))
+	for _, spec := range expected {
+		expectedNames[spec.Name] = true
+
+		if index, ok := existingByName[spec.Name]; ok {
+			unique, _ := index["unique"].(bool)
+			if !sameKeys(index["key"], spec.Keys) || unique != spec.Unique {
+				drift = append(drift, fmt.Sprintf("%s: index %s differs from expected definition", collection, spec.Name))
+			}
+			continue
+		}
+
+		opts := options.Index().SetName(spec.Name).SetUnique(spec.Unique)
+		if spec.PartialExists != "" {
+			opts.SetPartialFilterExpression(bson.M{spec.PartialExists: bson.M{"$exists": true}})
+		}
+		model := mongo.IndexModel{Keys: spec.Keys, Options: opts}
+		_, err := DB.Collection(collection).Indexes().CreateOne(ctx, model)
+		if err != nil {
+			drift = append(drift, fmt.Sprintf("%s: failed to create index %s: %v", collection, spec.Name, err))
+			continue
+		}
+		drift = append(drift, fmt.Sprintf("%s: created missing index %s", collection, spec.Name))
+	}
+
+	for name := range existingByName {
+		if name != "_id_" && !expectedNames[name] {
+			drift = append(drift, fmt.Sprintf("%s: unexpected index %s", collection, name))
+		}
+	}
+
+	return drift, nil
+}
+
+func sameKeys(actual interface{}, expected bson.D) bool {
+	keys, ok := actual.(bson.M)
+	if !ok || len(keys) != len(expected) {
+		return false
+	}
+	for _, e := range expected {
+		value, ok := keys[e.Key]
+		if !ok || fmt.Sprint(value) != fmt.Sprint(e.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func ensureValidator(ctx context.Context, collection string, schema collectionSchema) ([]string, error) {
+	jsonSchema := modelSchema(reflect.TypeOf(schema.Model))
+	if len(schema.Required) > 0 {
+		jsonSchema["required"] = schema.Required
+	}
+	validator := bson.M{"$jsonSchema": jsonSchema}
+
+	specs, err := DB.ListCollectionSpecifications(ctx, bson.M{"name": collection})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(specs) == 0 {
+		opts := options.CreateCollection().
+			SetValidator(validator).
+			SetValidationLevel("moderate").
+			SetValidationAction("error")
+		if err := DB.CreateCollection(ctx, collection, opts); err != nil {
+			return nil, err
+		}
+		return []string{fmt.Sprintf("%s: created collection with validator", collection)}, nil
+	}
+
+	var current struct {
+		Validator bson.M `bson:"validator"`
+	}
+	if specs[0].Options != nil {
+		if err := bson.Unmarshal(specs[0].Options, &current); err != nil {
+			return nil, err
+		}
+	}
+
+	same, err := sameDocument(current.Validator, validator)
+	if err != nil {
+		return nil, err
+	}
+	if same {
+		return nil, nil
+	}
+
+	command := bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+		{Key: "validationAction", Value: "error"},
+	}
+	if err := DB.RunCommand(ctx, command).Err(); err != nil {
+		return nil, err
+	}
+
+	if current.Validator == nil {
+		return []string{fmt.Sprintf("%s: added missing validator", collection)}, nil
+	}
+	return []string{fmt.Sprintf("%s: validator differed from models and was updated", collection)}, nil
+}
+
+// sameDocument сравнивает документы после приведения к одинаковому BSON-представлению
+func sameDocument(a, b bson.M) (bool, error) {
+	if a == nil || b == nil {
+		return a == nil && b == nil, nil
+	}
+
+	normalize := func(doc bson.M) (bson.M, error) {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		var out bson.M
+		err = bson.Unmarshal(raw, &out)
+		return out, err
+	}
+
+	na, err := normalize(a)
+	if err != nil {
+		return false, err
+	}
+	nb, err := normalize(b)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(na, nb), nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// modelSchema строит $jsonSchema документа по bson-тегам структуры.
+// Поле _id не описывается: у старых документов это ObjectId, у новых — строка.
+func modelSchema(t reflect.Type) bson.M {
+	properties := bson.M{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("bson"), ",")[0]
+		if name == "-" || name == "_id" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		properties[name] = typeSchema(field.Type)
+	}
+
+	return bson.M{
+		"bsonType":   "object",
+		"properties": properties,
+	}
+}
+
+func typeSchema(t reflect.Type) bson.M {
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := typeSchema(t.Elem())
+		schema["bsonType"] = withNull(schema["bsonType"])
+		return schema
+	case reflect.Struct:
+		if t == timeType {
+			return bson.M{"bsonType": "date"}
+		}
+		return modelSchema(t)
+	case reflect.Slice, reflect.Array:
+		return bson.M{
+			"bsonType": bson.A{"array", "null"},
+			"items":    typeSchema(t.Elem()),
+		}
+	case reflect.Map:
+		return bson.M{
+			"bsonType":             bson.A{"object", "null"},
+			"additionalProperties": typeSchema(t.Elem()),
+		}
+	case reflect.String:
+		return bson.M{"bsonType": "string"}
+	case reflect.Bool:
+		return bson.M{"bsonType": "bool"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return bson.M{"bsonType": "number"}
+	default:
+		return bson.M{}
+	}
+}
+
+func withNull(bsonType interface{}) bson.A {
+	switch v := bsonType.(type) {
+	case string:
+		return bson.A{v, "null"}
+	case bson.A:
+		for _, item := range v {
+			if item == "null" {
+				return v
+			}
+		}
+		return append(v, "null")
+	default:
+		return bson.A{"null"}
+	}
+}
+
+// logSchemaDrift выводит отчет о расхождениях схемы
+func logSchemaDrift(drift []string) {
+	for _, line := range drift {
+		slog.Warn("Schema drift", "detail", line)
+	}
+}
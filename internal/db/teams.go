@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+)
+
+// Команды из справочника идут от сильнейших к слабейшим: этот порядок используют
+// корзины жеребьевки
+var teamsByStrength = options.Find().SetSort(bson.D{
+	{Key: "stars", Value: -1}, {Key: "overall", Value: -1}, {Key: "name", Value: 1},
+})
+
+func findTeams(filter bson.M) ([]Team, error) {
+	cursor, err := DB.Collection("teams").Find(context.Background(), filter, teamsByStrength)
+	if err != nil {
+		return nil, err
+	}
+	var teams []Team
+	if err := cursor.All(context.Background(), &teams); err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+// teamQueryFilter строит фильтр справочника по сохраненному запросу категории
+func teamQueryFilter(query *TeamQuery) bson.M {
+	filter := bson.M{}
+	stars := bson.M{}
+	if query.MinStars > 0 {
+		stars["$gte"] = query.MinStars
+	}
+	if query.MaxStars > 0 {
+		stars["$lte"] = query.MaxStars
+	}
+	if len(stars) > 0 {
+		filter["stars"] = stars
+	}
+	if query.League != "" {
+		filter["league"] = caseInsensitive(query.League)
+	}
+	if query.Country != "" {
+		filter["country"] = caseInsensitive(query.Country)
+	}
+	return filter
+}
+
+func caseInsensitive(value string) bson.M {
+	return bson.M{"$regex": "^" + regexp.QuoteMeta(value) + "$", "$options": "i"}
+}
+
+// FindTeams возвращает команды справочника по запросу, сильнейшие первыми
+func FindTeams(query *TeamQuery) ([]Team, error) {
+	return findTeams(teamQueryFilter(query))
+}
+
+// GetTeamsByIDs возвращает команды справочника с указанными ID, сильнейшие первыми
+func GetTeamsByIDs(ids []int) ([]Team, error) {
+	return findTeams(bson.M{"id": bson.M{"$in": ids}})
+}
+
+// FindTeam ищет команду справочника по названию или другому названию без учета регистра.
+// Возвращает nil, если такой команды нет.
+func FindTeam(name string) (*Team, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"name": caseInsensitive(name)},
+		bson.M{"aliases": caseInsensitive(name)},
+	}}
+	var team Team
+	err := DB.Collection("teams").FindOne(context.Background(), filter).Decode(&team)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// ResolveCategoryTeams заполняет Teams категории из справочника
+func ResolveCategoryTeams(category *TeamCategory) error {
+	if !category.FromCatalog() {
+		return nil
+	}
+
+	var teams []Team
+	var err error
+	if category.Query != nil {
+		teams, err = FindTeams(category.Query)
+	} else {
+		teams, err = GetTeamsByIDs(category.TeamIDs)
+	}
+	if err != nil {
+		return err
+	}
+
+	category.Teams = make([]string, 0, len(teams))
+	for _, team := range teams {
+		category.Teams = append(category.Teams, team.Name)
+	}
+	return nil
+}
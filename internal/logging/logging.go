@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const redacted = "<redacted>"
+
+type ctxKey struct{}
+
+// Setup настраивает логгер по умолчанию: уровень, формат вывода (json или text)
+// и скрытие токена бота во всех сообщениях и атрибутах. Стандартный пакет log
+// и логгер tgbotapi после вызова пишут через тот же обработчик.
+func Setup(level, format, botToken string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q: %v", level, err)
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       lvl,
+		ReplaceAttr: redactAttr(botToken),
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	case "text":
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	default:
+		return fmt.Errorf("invalid log format %q", format)
+	}
+
+	slog.SetDefault(slog.New(&contextHandler{next: handler}))
+	tgbotapi.SetLogger(botLogger{})
+	return nil
+}
+
+// WithAttrs добавляет в контекст поля корреляции, которые будут
+// прикреплены ко всем записям, сделанным с этим контекстом
+func WithAttrs(ctx context.Context, args ...any) context.Context {
+	var attrs []slog.Attr
+	if existing, ok := ctx.Value(ctxKey{}).([]slog.Attr); ok {
+		attrs = append(attrs, existing...)
+	}
+	attrs = append(attrs, argsToAttrs(args)...)
+	return context.WithValue(ctx, ctxKey{}, attrs)
+}
+
+func argsToAttrs(args []any) []slog.Attr {
+	var r slog.Record
+	r.Add(args...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
+
+// contextHandler дописывает к записи поля корреляции из контекста
+type contextHandler struct {
+	next slog.Handler
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(ctxKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name)}
+}
+
+// redactAttr заменяет токен бота в сообщениях, строках и ошибках: tgbotapi
+// включает его в URL запросов, которые попадают в тексты ошибок
+func redactAttr(token string) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if token == "" {
+			return a
+		}
+
+		switch a.Value.Kind() {
+		case slog.KindString:
+			if strings.Contains(a.Value.String(), token) {
+				a.Value = slog.StringValue(strings.ReplaceAll(a.Value.String(), token, redacted))
+			}
+		case slog.KindAny:
+			if err, ok := a.Value.Any().(error); ok && strings.Contains(err.Error(), token) {
+				a.Value = slog.StringValue(strings.ReplaceAll(err.Error(), token, redacted))
+			}
+		}
+		return a
+	}
+}
+
+// botLogger перенаправляет сообщения tgbotapi в slog
+type botLogger struct{}
+
+func (botLogger) Println(v ...interface{}) {
+	slog.Warn(strings.TrimSuffix(fmt.Sprintln(v...), "\n"), "component", "tgbotapi")
+}
+
+func (botLogger) Printf(format string, v ...interface{}) {
+	slog.Warn(strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"), "component", "tgbotapi")
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/bson"
+	"log/slog"
+	"time"
+	"tournament-bot/internal/db"
+)
+
+var (
+	// UpdatesProcessed считает обработанные обновления Telegram по командам
+	UpdatesProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tournament_bot_updates_processed_total",
+		Help: "Number of Telegram updates processed, by command.",
+	}, []string{"command"})
+
+	// HandlerDuration измеряет время обработки обновлений по командам
+	HandlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tournament_bot_handler_duration_seconds",
+		Help:    "Time spent handling a Telegram update, by command.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"command"})
+
+	// NotificationFailures считает неудачные отправки уведомлений в канал по типу сообщения
+	NotificationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tournament_bot_notification_failures_total",
+		Help: "Number of channel notifications that failed to send, by message kind.",
+	}, []string{"kind"})
+
+	// ActiveTournaments показывает количество активных турниров на момент опроса
+	ActiveTournaments = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "tournament_bot_active_tournaments",
+		Help: "Number of currently active tournaments.",
+	}, countActiveTournaments)
+)
+
+func countActiveTournaments() float64 {
+	if db.DB == nil {
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	count, err := db.DB.Collection("tournaments").CountDocuments(ctx, bson.M{"is_active": true})
+	if err != nil {
+		slog.Error("Error counting active tournaments", "err", err)
+		return 0
+	}
+	return float64(count)
+}
@@ -0,0 +1,120 @@
+package migrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Имена файлов миграций: <версия>_<описание>.<up|down>.json,
+// где версия — метка времени вида 20230624170000
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.json$`)
+
+// Migration — одна версия схемы с командами для применения и отката.
+// Каждая команда — документ для runCommand (например, insert, update, delete, createIndexes).
+type Migration struct {
+	Version int64
+	Name    string
+	Up      []bson.D
+	Down    []bson.D
+	HasDown bool
+}
+
+// LoadDir читает миграции из каталога на диске
+func LoadDir(dir string) ([]Migration, error) {
+	return Load(os.DirFS(dir))
+}
+
+// Load читает миграции из файловой системы и сортирует их по версии
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := fileNamePattern.FindStringSubmatch(strings.TrimSpace(entry.Name()))
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %v", entry.Name(), err)
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		commands, err := parseCommands(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names %q and %q", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = commands
+		} else {
+			migration.Down = commands
+			migration.HasDown = true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == nil {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// parseCommands разбирает JSON-массив команд. Каждая команда читается как
+// Extended JSON в bson.D, чтобы сохранить порядок ключей: runCommand требует,
+// чтобы имя команды было первым полем документа.
+func parseCommands(data []byte) ([]bson.D, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	commands := make([]bson.D, 0, len(raw))
+	for i, item := range raw {
+		var command bson.D
+		if err := bson.UnmarshalExtJSON(item, false, &command); err != nil {
+			return nil, fmt.Errorf("command %d: %v", i, err)
+		}
+		if len(command) == 0 {
+			return nil, fmt.Errorf("command %d is empty", i)
+		}
+		commands = append(commands, command)
+	}
+
+	return commands, nil
+}
@@ -0,0 +1,189 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"log/slog"
+	"time"
+)
+
+// Runner применяет и откатывает миграции. В режиме DryRun команды не выполняются
+// и версии не записываются — только возвращается план.
+type Runner struct {
+	Store      Store
+	Migrations []Migration
+	DryRun     bool
+}
+
+// Status — состояние одной миграции
+type Status struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Status возвращает список всех известных миграций с отметкой о применении
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.Migrations))
+	for _, migration := range r.Migrations {
+		record, ok := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: record.AppliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Up применяет все непримененные миграции по возрастанию версии и возвращает список примененных
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	applied, err := r.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range r.Migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		if err := r.run(ctx, migration, "up", migration.Up); err != nil {
+			return done, err
+		}
+
+		if !r.DryRun {
+			record := Record{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}
+			if err := r.Store.MarkApplied(ctx, record); err != nil {
+				return done, fmt.Errorf("migration %d_%s applied but not recorded: %v", migration.Version, migration.Name, err)
+			}
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// DownTo откатывает примененные миграции с версией больше target в обратном порядке.
+// target = 0 откатывает все миграции.
+func (r *Runner) DownTo(ctx context.Context, target int64) ([]Migration, error) {
+	applied, err := r.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int64]bool, len(r.Migrations))
+	for _, migration := range r.Migrations {
+		known[migration.Version] = true
+	}
+	for version, record := range applied {
+		if version > target && !known[version] {
+			return nil, fmt.Errorf("applied migration %d_%s has no files, cannot roll back", version, record.Name)
+		}
+	}
+
+	// Проверяем заранее, что у всех откатываемых миграций есть down-файлы,
+	// чтобы не остановиться на середине отката
+	var plan []Migration
+	for i := len(r.Migrations) - 1; i >= 0; i-- {
+		migration := r.Migrations[i]
+		if migration.Version <= target {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if !migration.HasDown {
+			return nil, fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+		plan = append(plan, migration)
+	}
+
+	var done []Migration
+	for _, migration := range plan {
+		if err := r.run(ctx, migration, "down", migration.Down); err != nil {
+			return done, err
+		}
+
+		if !r.DryRun {
+			if err := r.Store.MarkRolledBack(ctx, migration.Version); err != nil {
+				return done, fmt.Errorf("migration %d_%s rolled back but still recorded: %v", migration.Version, migration.Name, err)
+			}
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Baseline отмечает миграции до версии target включительно как примененные, не выполняя их.
+// Нужен для баз, в которые миграции раньше вносились вручную.
+func (r *Runner) Baseline(ctx context.Context, target int64) ([]Migration, error) {
+	applied, err := r.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range r.Migrations {
+		if migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		if !r.DryRun {
+			record := Record{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}
+			if err := r.Store.MarkApplied(ctx, record); err != nil {
+				return done, err
+			}
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+func (r *Runner) run(ctx context.Context, migration Migration, direction string, commands []bson.D) error {
+	for i, command := range commands {
+		if r.DryRun {
+			slog.Info("Dry run: would run migration command",
+				"version", migration.Version, "name", migration.Name, "direction", direction,
+				"index", i, "command", command[0].Key)
+			continue
+		}
+
+		if err := r.Store.RunCommand(ctx, command); err != nil {
+			// Команды выполняются без транзакции: предыдущие команды этой миграции уже применены
+			return fmt.Errorf("migration %d_%s %s: command %d (%s) failed after %d succeeded: %v",
+				migration.Version, migration.Name, direction, i, command[0].Key, i, err)
+		}
+	}
+
+	slog.Info("Migration finished", "version", migration.Version, "name", migration.Name,
+		"direction", direction, "dry_run", r.DryRun)
+	return nil
+}
+
+func (r *Runner) appliedVersions(ctx context.Context) (map[int64]Record, error) {
+	records, err := r.Store.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]Record, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
@@ -0,0 +1,209 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// testMigrations — три миграции; у каждой команды уникальное имя коллекции,
+// по которому проверяется порядок выполнения
+func testMigrations(t *testing.T) []Migration {
+	t.Helper()
+	fsys := fstest.MapFS{
+		"3_third.up.json":    {Data: []byte(`[{"insert": "up3", "documents": [{}]}]`)},
+		"3_third.down.json":  {Data: []byte(`[{"delete": "down3", "deletes": []}]`)},
+		"1_first.up.json":    {Data: []byte(`[{"insert": "up1a", "documents": [{}]}, {"insert": "up1b", "documents": [{}]}]`)},
+		"1_first.down.json":  {Data: []byte(`[{"delete": "down1", "deletes": []}]`)},
+		"2_second.up.json":   {Data: []byte(`[{"insert": "up2", "documents": [{}]}]`)},
+		"2_second.down.json": {Data: []byte(`[{"delete": "down2", "deletes": []}]`)},
+		"README.md":          {Data: []byte("not a migration")},
+	}
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return migrations
+}
+
+func commandTargets(commands []bson.D) []string {
+	targets := []string{}
+	for _, command := range commands {
+		targets = append(targets, command[0].Value.(string))
+	}
+	return targets
+}
+
+func versions(migrations []Migration) []int64 {
+	result := []int64{}
+	for _, migration := range migrations {
+		result = append(result, migration.Version)
+	}
+	return result
+}
+
+func appliedVersions(t *testing.T, store *MemoryStore) []int64 {
+	t.Helper()
+	records, err := store.Applied(context.Background())
+	if err != nil {
+		t.Fatalf("Applied: %v", err)
+	}
+	result := []int64{}
+	for _, record := range records {
+		result = append(result, record.Version)
+	}
+	return result
+}
+
+func TestLoadSortsByVersion(t *testing.T) {
+	migrations := testMigrations(t)
+	if got := versions(migrations); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Fatalf("versions = %v, want [1 2 3]", got)
+	}
+	if migrations[0].Name != "first" || len(migrations[0].Up) != 2 || !migrations[0].HasDown {
+		t.Fatalf("first migration loaded incorrectly: %+v", migrations[0])
+	}
+}
+
+func TestRunner(t *testing.T) {
+	tests := []struct {
+		name string
+		// applied — версии, отмеченные до запуска
+		applied      []int64
+		dryRun       bool
+		run          func(r *Runner) ([]Migration, error)
+		wantDone     []int64
+		wantCommands []string
+		wantApplied  []int64
+	}{
+		{
+			name:         "up applies pending migrations in version order",
+			run:          func(r *Runner) ([]Migration, error) { return r.Up(context.Background()) },
+			wantDone:     []int64{1, 2, 3},
+			wantCommands: []string{"up1a", "up1b", "up2", "up3"},
+			wantApplied:  []int64{1, 2, 3},
+		},
+		{
+			name:         "up skips applied migrations",
+			applied:      []int64{1},
+			run:          func(r *Runner) ([]Migration, error) { return r.Up(context.Background()) },
+			wantDone:     []int64{2, 3},
+			wantCommands: []string{"up2", "up3"},
+			wantApplied:  []int64{1, 2, 3},
+		},
+		{
+			name:         "up dry run records nothing",
+			dryRun:       true,
+			run:          func(r *Runner) ([]Migration, error) { return r.Up(context.Background()) },
+			wantDone:     []int64{1, 2, 3},
+			wantCommands: []string{},
+			wantApplied:  []int64{},
+		},
+		{
+			name:         "down rolls back newer migrations in reverse order",
+			applied:      []int64{1, 2, 3},
+			run:          func(r *Runner) ([]Migration, error) { return r.DownTo(context.Background(), 1) },
+			wantDone:     []int64{3, 2},
+			wantCommands: []string{"down3", "down2"},
+			wantApplied:  []int64{1},
+		},
+		{
+			name:         "down to zero rolls back only applied migrations",
+			applied:      []int64{1, 3},
+			run:          func(r *Runner) ([]Migration, error) { return r.DownTo(context.Background(), 0) },
+			wantDone:     []int64{3, 1},
+			wantCommands: []string{"down3", "down1"},
+			wantApplied:  []int64{},
+		},
+		{
+			name:         "down dry run keeps records",
+			applied:      []int64{1, 2, 3},
+			dryRun:       true,
+			run:          func(r *Runner) ([]Migration, error) { return r.DownTo(context.Background(), 0) },
+			wantDone:     []int64{3, 2, 1},
+			wantCommands: []string{},
+			wantApplied:  []int64{1, 2, 3},
+		},
+		{
+			name:         "baseline marks without running",
+			run:          func(r *Runner) ([]Migration, error) { return r.Baseline(context.Background(), 2) },
+			wantDone:     []int64{1, 2},
+			wantCommands: []string{},
+			wantApplied:  []int64{1, 2},
+		},
+		{
+			name:         "baseline dry run records nothing",
+			dryRun:       true,
+			run:          func(r *Runner) ([]Migration, error) { return r.Baseline(context.Background(), 3) },
+			wantDone:     []int64{1, 2, 3},
+			wantCommands: []string{},
+			wantApplied:  []int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MemoryStore{}
+			for _, version := range tt.applied {
+				store.MarkApplied(context.Background(), Record{Version: version})
+			}
+			runner := &Runner{Store: store, Migrations: testMigrations(t), DryRun: tt.dryRun}
+
+			done, err := tt.run(runner)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := versions(done); !reflect.DeepEqual(got, tt.wantDone) {
+				t.Errorf("done = %v, want %v", got, tt.wantDone)
+			}
+			if got := commandTargets(store.Commands); !reflect.DeepEqual(got, tt.wantCommands) {
+				t.Errorf("commands = %v, want %v", got, tt.wantCommands)
+			}
+			if got := appliedVersions(t, store); !reflect.DeepEqual(got, tt.wantApplied) {
+				t.Errorf("applied = %v, want %v", got, tt.wantApplied)
+			}
+		})
+	}
+}
+
+func TestUpStopsAtFailedMigration(t *testing.T) {
+	store := &MemoryStore{Fail: func(command bson.D) error {
+		if command[0].Value == "up2" {
+			return errors.New("boom")
+		}
+		return nil
+	}}
+	runner := &Runner{Store: store, Migrations: testMigrations(t)}
+
+	done, err := runner.Up(context.Background())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := versions(done); !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("done = %v, want [1]", got)
+	}
+	if got := appliedVersions(t, store); !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("applied = %v, want [1]", got)
+	}
+}
+
+func TestDownRequiresDownFiles(t *testing.T) {
+	migrations := testMigrations(t)
+	migrations[2].HasDown = false
+	migrations[2].Down = nil
+	store := &MemoryStore{}
+	for _, version := range []int64{1, 2, 3} {
+		store.MarkApplied(context.Background(), Record{Version: version})
+	}
+	runner := &Runner{Store: store, Migrations: migrations}
+
+	if _, err := runner.DownTo(context.Background(), 0); err == nil {
+		t.Fatal("expected an error for a migration without a down file")
+	}
+	if len(store.Commands) != 0 {
+		t.Errorf("no command should run before the plan is validated, ran %v", commandTargets(store.Commands))
+	}
+}
@@ -0,0 +1,138 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"sync"
+	"time"
+)
+
+const migrationsCollection = "schema_migrations"
+
+// Record — отметка о примененной миграции
+type Record struct {
+	Version   int64     `bson:"version"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// Store выполняет команды миграций и хранит список примененных версий.
+// Раннер работает только через этот интерфейс, поэтому его можно проверить
+// на хранилище в памяти (MemoryStore) или на временной базе.
+type Store interface {
+	Applied(ctx context.Context) ([]Record, error)
+	RunCommand(ctx context.Context, command bson.D) error
+	MarkApplied(ctx context.Context, record Record) error
+	MarkRolledBack(ctx context.Context, version int64) error
+}
+
+// MongoStore применяет миграции к базе MongoDB и хранит версии в коллекции schema_migrations
+type MongoStore struct {
+	DB *mongo.Database
+}
+
+func (s *MongoStore) Applied(ctx context.Context) ([]Record, error) {
+	opts := options.Find().SetSort(bson.M{"version": 1})
+	cursor, err := s.DB.Collection(migrationsCollection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// RunCommand выполняет команду и проверяет ошибки записи: для insert/update/delete
+// сервер отвечает ok: 1 даже если часть документов не была записана
+func (s *MongoStore) RunCommand(ctx context.Context, command bson.D) error {
+	var result bson.M
+	err := s.DB.RunCommand(ctx, command).Decode(&result)
+	if err != nil {
+		return err
+	}
+
+	if writeErrors, ok := result["writeErrors"]; ok {
+		return fmt.Errorf("write errors: %v", writeErrors)
+	}
+	if writeConcernError, ok := result["writeConcernError"]; ok {
+		return fmt.Errorf("write concern error: %v", writeConcernError)
+	}
+	return nil
+}
+
+func (s *MongoStore) MarkApplied(ctx context.Context, record Record) error {
+	filter := bson.M{"version": record.Version}
+	update := bson.M{"$set": record}
+	opts := options.Update().SetUpsert(true)
+	_, err := s.DB.Collection(migrationsCollection).UpdateOne(ctx, filter, update, opts)
+	return err
+}
+
+func (s *MongoStore) MarkRolledBack(ctx context.Context, version int64) error {
+	_, err := s.DB.Collection(migrationsCollection).DeleteOne(ctx, bson.M{"version": version})
+	return err
+}
+
+// MemoryStore хранит версии в памяти и записывает выполненные команды по порядку.
+// Подходит для проверки раннера и для просмотра плана без базы.
+type MemoryStore struct {
+	mu       sync.Mutex
+	records  map[int64]Record
+	Commands []bson.D
+	// Fail, если задана, вызывается перед каждой командой; ошибка прерывает миграцию
+	Fail func(command bson.D) error
+}
+
+func (s *MemoryStore) Applied(ctx context.Context) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]Record, 0, len(s.records))
+	for _, record := range s.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Version < records[j].Version
+	})
+	return records, nil
+}
+
+func (s *MemoryStore) RunCommand(ctx context.Context, command bson.D) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Fail != nil {
+		if err := s.Fail(command); err != nil {
+			return err
+		}
+	}
+	s.Commands = append(s.Commands, command)
+	return nil
+}
+
+func (s *MemoryStore) MarkApplied(ctx context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.records == nil {
+		s.records = make(map[int64]Record)
+	}
+	s.records[record.Version] = record
+	return nil
+}
+
+func (s *MemoryStore) MarkRolledBack(ctx context.Context, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, version)
+	return nil
+}
@@ -0,0 +1,151 @@
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"strings"
+	"time"
+	"tournament-bot/internal/db"
+)
+
+// Пауза между парами при показе жеребьевки в канале
+var drawRevealPause = 3 * time.Second
+
+func SetDrawRevealPause(pause time.Duration) {
+	drawRevealPause = pause
+}
+
+// SendDrawCommitmentMessage публикует хэш входных данных и зерна до жеребьевки: после
+// раскрытия зерна любой может убедиться, что жеребьевку не перезапускали
+func SendDrawCommitmentMessage(tournament *db.Tournament, strategyLabel string) error {
+	message := fmt.Sprintf(`
+<b>🎲 Жеребьевка турнира %s</b>
+
+<i>Категория:</i> %s
+<i>Способ:</i> %s
+<i>Участники:</i> %s
+
+<b>Хэш жеребьевки (участники, команды и зерно):</b>
+<code>%s</code>
+
+После жеребьевки мы опубликуем зерно. Проверить результат: /verify_draw %d
+`, tournament.Name, teamCategoryLabel(tournament), strategyLabel,
+		strings.Join(tournament.Draw.Participants, ", "), tournament.Draw.Commitment, tournament.ID)
+
+	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
+	msg.ParseMode = "HTML"
+
+	err := enqueue(msg, "draw commitment message")
+	if err != nil {
+		return fmt.Errorf("failed to send draw commitment message: %v", err)
+	}
+
+	return nil
+}
+
+// SendDrawCancelledMessage объявляет, что опубликованный хэш жеребьевки больше
+// не действует и жеребьевка будет зафиксирована заново
+func SendDrawCancelledMessage(tournament *db.Tournament, record *db.DrawRecord) error {
+	message := fmt.Sprintf(`
+<b>❌ Жеребьевка турнира %s отменена</b>
+
+Хэш <code>%s</code> больше не действует. Новый хэш будет опубликован перед жеребьевкой.
+`, tournament.Name, record.Commitment)
+
+	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
+	msg.ParseMode = "HTML"
+
+	err := enqueue(msg, "draw cancelled message")
+	if err != nil {
+		return fmt.Errorf("failed to send draw cancelled message: %v", err)
+	}
+
+	return nil
+}
+
+// SendDrawCeremony показывает пары участник — команда по одной, редактируя одно сообщение,
+// и в конце раскрывает зерно. Правки ставятся в очередь по таймеру, поэтому пауза между
+// ними не задерживает другие уведомления. after ставится в очередь после последней правки,
+// чтобы, например, сообщение о старте турнира не опередило показ.
+func SendDrawCeremony(tournament *db.Tournament, after func() error) error {
+	participants := tournament.Draw.Participants
+	teams := tournament.ParticipantTeams
+	pause := drawRevealPause
+
+	start := func(api *tgbotapi.BotAPI) error {
+		msg := tgbotapi.NewMessageToChannel(ChannelID, drawCeremonyText(tournament, participants, teams, 0))
+		msg.ParseMode = "HTML"
+		sent, err := api.Send(msg)
+		if err != nil {
+			runAfterCeremony(after)
+			return err
+		}
+		scheduleDrawReveal(tournament, sent.MessageID, 1, pause, after)
+		return nil
+	}
+
+	err := enqueueFunc(start, "draw ceremony")
+	if err != nil {
+		return fmt.Errorf("failed to send draw ceremony: %v", err)
+	}
+
+	return nil
+}
+
+// scheduleDrawReveal через pause ставит в очередь правку, открывающую revealed пар,
+// и планирует следующую
+func scheduleDrawReveal(tournament *db.Tournament, messageID, revealed int, pause time.Duration, after func() error) {
+	participants := tournament.Draw.Participants
+	time.AfterFunc(pause, func() {
+		edit := tgbotapi.EditMessageTextConfig{
+			BaseEdit:  tgbotapi.BaseEdit{ChannelUsername: ChannelID, MessageID: messageID},
+			Text:      drawCeremonyText(tournament, participants, tournament.ParticipantTeams, revealed),
+			ParseMode: "HTML",
+		}
+		err := enqueue(edit, "draw ceremony")
+		if errors.Is(err, ErrOutboxClosed) {
+			return
+		}
+		if err != nil {
+			slog.Error("Failed to enqueue draw reveal", "tournament_id", tournament.ID, "err", err)
+		}
+
+		if revealed < len(participants) {
+			scheduleDrawReveal(tournament, messageID, revealed+1, pause, after)
+			return
+		}
+		runAfterCeremony(after)
+	})
+}
+
+func runAfterCeremony(after func() error) {
+	if after == nil {
+		return
+	}
+	if err := after(); err != nil {
+		slog.Error("Failed to send notification after draw ceremony", "err", err)
+	}
+}
+
+// drawCeremonyText — текст сообщения жеребьевки, в котором показаны первые revealed пар
+func drawCeremonyText(tournament *db.Tournament, participants []string, teams map[string]string, revealed int) string {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("<b>🎲 Жеребьевка турнира %s</b>\n\n", tournament.Name))
+	for i, participant := range participants {
+		if i < revealed {
+			text.WriteString(fmt.Sprintf("<b>%s</b> — %s\n", participant, teams[participant]))
+		} else {
+			text.WriteString(fmt.Sprintf("<b>%s</b> — ❓\n", participant))
+		}
+	}
+
+	if revealed < len(participants) {
+		text.WriteString("\n⏳ Тянем следующую команду...")
+		return text.String()
+	}
+	text.WriteString(fmt.Sprintf("\n<b>Зерно:</b> <code>%d</code>\n<b>Хэш:</b> <code>%s</code>\nПроверить: /verify_draw %d",
+		tournament.DrawSeed, tournament.Draw.Commitment, tournament.ID))
+	return text.String()
+}
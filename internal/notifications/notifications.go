@@ -3,15 +3,12 @@ package notifications
 import (
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"net/http"
 	"sort"
 	"strings"
-	"time"
 	"tournament-bot/internal/db"
 )
 
 const (
-	BotToken   = "7012505888:AAEtQoe-AwaoNoC5OPUQaQ6jAqNHKYAKcQk"
 	ChannelID  = "@test_bot_botsadfasd"
 	TwitchLink = "https://www.twitch.tv/prime_club1"
 )
@@ -41,39 +38,177 @@ func SendTournamentStartMessage(tournament *db.Tournament) error {
 Смотрите нашу трансляцию на Twitch: <a href="%s">%s</a> 📺
 
 <b>Да начнется битва! ⚽💪</b>
-`, tournament.Name, tournament.TeamCategory, participants, TwitchLink, TwitchLink)
+`, tournament.Name, teamCategoryLabel(tournament), participants, TwitchLink, TwitchLink)
 
 	// Создаем MessageConfig для отправки сообщения в канал
 	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
 	msg.ParseMode = "HTML"
 
-	// Создаем новый экземпляр бота с использованием токена
-	bot, err := tgbotapi.NewBotAPI(BotToken)
+	// Ставим сообщение в очередь на отправку
+	err := enqueue(msg, "tournament start message")
 	if err != nil {
-		return fmt.Errorf("failed to create bot: %v", err)
+		return fmt.Errorf("failed to send tournament start message: %v", err)
 	}
 
-	// Устанавливаем время ожидания для бота
-	bot.Debug = true
-	bot.Client = &http.Client{Timeout: 30 * time.Second}
+	return nil
+}
+
+// SendRegistrationOpenMessage объявляет в канале о наборе на турнир с кнопками записи
+func SendRegistrationOpenMessage(tournament *db.Tournament) error {
+	deadline := "до начала турнира"
+	if !tournament.RegistrationDeadline.IsZero() {
+		deadline = "до " + tournament.RegistrationDeadline.Format("02.01.2006 15:04")
+	}
+
+	message := fmt.Sprintf(`
+<b>📝 Открыта запись на турнир!</b>
+
+<i>Название:</i> %s
+<i>Мест:</i> %d (минимум %d)
+<i>Запись:</i> %s
+
+Нажмите «Участвовать», чтобы записаться. Если мест не останется, вы попадете в лист ожидания.
+Перед первой записью привяжите аккаунт командой /register в личных сообщениях с ботом.
+`, tournament.Name, tournament.MaxParticipants, tournament.MinParticipants, deadline)
 
-	// Отправляем сообщение
-	_, err = bot.Send(msg)
+	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ Участвовать", fmt.Sprintf("join_tournament_%d", tournament.ID)),
+		tgbotapi.NewInlineKeyboardButtonData("❌ Отказаться", fmt.Sprintf("withdraw_tournament_%d", tournament.ID)),
+	))
+
+	err := enqueue(msg, "registration open message")
 	if err != nil {
-		return fmt.Errorf("failed to send tournament start message: %v", err)
+		return fmt.Errorf("failed to send registration open message: %v", err)
 	}
 
 	return nil
 }
 
+// SendCheckInStartMessage просит записавшихся подтвердить участие кнопкой
+func SendCheckInStartMessage(tournament *db.Tournament) error {
+	message := fmt.Sprintf(`
+<b>🕒 Подтвердите участие!</b>
+
+Турнир <b>%s</b> скоро начнется. Нажмите «Я на месте» до %s.
+Кто не подтвердит участие, будет снят с турнира, а его место получит игрок из листа ожидания.
+`, tournament.Name, tournament.CheckInDeadline.Format("15:04"))
+
+	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
+	msg.ParseMode = "HTML"
+	msg.ReplyMarkup = CheckInKeyboard(tournament.ID)
+
+	err := enqueue(msg, "check-in start message")
+	if err != nil {
+		return fmt.Errorf("failed to send check-in start message: %v", err)
+	}
+
+	return nil
+}
+
+// CheckInKeyboard — кнопка подтверждения участия
+func CheckInKeyboard(tournamentID int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🙋 Я на месте", fmt.Sprintf("checkin_%d", tournamentID)),
+	))
+}
+
+// SendCheckInClosedMessage публикует итоговый состав после подтверждения участия
+func SendCheckInClosedMessage(tournament *db.Tournament, dropped, promoted []string) error {
+	message := fmt.Sprintf("<b>✅ Состав турнира %s подтвержден</b>\n\n<b>Участники:</b>\n%s\n",
+		tournament.Name, strings.Join(tournament.Participants, "\n"))
+	if len(dropped) > 0 {
+		message += fmt.Sprintf("\n<i>Не подтвердили участие:</i> %s\n", strings.Join(dropped, ", "))
+	}
+	if len(promoted) > 0 {
+		message += fmt.Sprintf("<i>Из листа ожидания:</i> %s\n", strings.Join(promoted, ", "))
+	}
+
+	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
+	msg.ParseMode = "HTML"
+
+	err := enqueue(msg, "check-in closed message")
+	if err != nil {
+		return fmt.Errorf("failed to send check-in closed message: %v", err)
+	}
+
+	return nil
+}
+
+// SendWithdrawalMessage сообщает о снятии участника и технических поражениях
+func SendWithdrawalMessage(tournament *db.Tournament, participant, team string, forfeits []db.Match) error {
+	message := fmt.Sprintf("<b>🚫 %s (%s) снялся с турнира</b>\n", participant, team)
+	if len(forfeits) > 0 {
+		message += "\n<b>Технические результаты:</b>\n"
+		for _, match := range forfeits {
+			message += FormatMatchResult(&match, "<b>"+match.Team1+"</b>", "<b>"+match.Team2+"</b>") + "\n"
+		}
+	}
+
+	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
+	msg.ParseMode = "HTML"
+
+	err := enqueue(msg, "withdrawal message")
+	if err != nil {
+		return fmt.Errorf("failed to send withdrawal message: %v", err)
+	}
+
+	return nil
+}
+
+// MatchStatusLabel возвращает пометку для матча с нестандартным исходом
+// (например, "тех. поражение: снялся с турнира") или пустую строку для сыгранного матча
+func MatchStatusLabel(match *db.Match) string {
+	var label string
+	switch match.StatusOrPlayed() {
+	case db.MatchForfeit:
+		label = "тех. поражение"
+	case db.MatchWalkover:
+		label = "неявка, победа " + match.Winner
+	case db.MatchAbandoned:
+		label = "матч прерван"
+	case db.MatchPostponed:
+		label = "матч перенесен"
+	default:
+		return ""
+	}
+	if match.Reason != "" {
+		label += ": " + match.Reason
+	}
+	return label
+}
+
+// FormatMatchResult форматирует результат матча для сообщений: счет, пенальти,
+// овертайм и пометку о нестандартном исходе
+func FormatMatchResult(match *db.Match, team1, team2 string) string {
+	var result string
+	switch {
+	case match.StatusOrPlayed() == db.MatchWalkover || !match.Decided():
+		result = fmt.Sprintf("%s - %s", team1, team2)
+	case match.Penalties:
+		result = fmt.Sprintf("%s %d:%d (%d:%d) %s (пен.)", team1, match.Score1, match.Score2,
+			match.PenaltyScore1, match.PenaltyScore2, team2)
+	case match.ExtraTime:
+		result = fmt.Sprintf("%s %d:%d %s (овертайм)", team1, match.Score1, match.Score2, team2)
+	default:
+		result = fmt.Sprintf("%s %d:%d %s", team1, match.Score1, match.Score2, team2)
+	}
+
+	if label := MatchStatusLabel(match); label != "" {
+		result += " [" + label + "]"
+	}
+	return result
+}
+
 func SendMatchResultMessage(tournament *db.Tournament, match *db.Match) error {
 	// Формируем текст сообщения с результатами матча
 	message := fmt.Sprintf(`
 <b>⚽ Результаты матча:</b>
-<b>%s</b> %d - %d <b>%s</b>
+%s
 
 <b>🏆 Турнирная таблица:</b>
-`, match.Team1, match.Score1, match.Score2, match.Team2)
+`, FormatMatchResult(match, "<b>"+match.Team1+"</b>", "<b>"+match.Team2+"</b>"))
 
 	// Формируем турнирную таблицу
 	standings := tournament.Standings
@@ -134,18 +269,8 @@ func SendMatchResultMessage(tournament *db.Tournament, match *db.Match) error {
 	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
 	msg.ParseMode = "HTML"
 
-	// Создаем новый экземпляр бота с использованием токена
-	bot, err := tgbotapi.NewBotAPI(BotToken)
-	if err != nil {
-		return fmt.Errorf("failed to create bot: %v", err)
-	}
-
-	// Устанавливаем время ожидания для бота
-	bot.Debug = true
-	bot.Client = &http.Client{Timeout: 30 * time.Second}
-
-	// Отправляем сообщение
-	_, err = bot.Send(msg)
+	// Ставим сообщение в очередь на отправку
+	err := enqueue(msg, "match result message")
 	if err != nil {
 		return fmt.Errorf("failed to send match result message: %v", err)
 	}
@@ -162,17 +287,21 @@ func SendPlayoffStartMessage(tournament *db.Tournament) error {
 
 	// Формируем сетку плей-офф
 	bracket := "<pre>\n"
-	bracket += "Четвертьфинал:\n"
-	for _, match := range tournament.Playoff.QuarterFinals {
-		bracket += fmt.Sprintf("%s - %s\n", match.Team1, match.Team2)
+	// При двух-трех командах в плей-офф сетка начинается с полуфинала или финала
+	if len(tournament.Playoff.QuarterFinals) > 0 {
+		bracket += "Четвертьфинал:\n"
+		for _, match := range tournament.Playoff.QuarterFinals {
+			bracket += fmt.Sprintf("%s - %s\n", match.Team1, match.Team2)
+		}
+		bracket += "\n"
 	}
-	bracket += "\nПолуфинал:\n"
 	if len(tournament.Playoff.SemiFinals) > 0 {
-		bracket += fmt.Sprintf("%s - ?\n", tournament.Playoff.SemiFinals[0].Team1)
+		bracket += "Полуфинал:\n"
+		bracket += fmt.Sprintf("%s - %s\n\n", tournament.Playoff.SemiFinals[0].Team1, teamOrUnknown(tournament.Playoff.SemiFinals[0].Team2))
 	}
-	bracket += "\nФинал:\n"
+	bracket += "Финал:\n"
 	if tournament.Playoff.Final != nil {
-		bracket += fmt.Sprintf("%s - ?\n", tournament.Playoff.Final.Team1)
+		bracket += fmt.Sprintf("%s - %s\n", tournament.Playoff.Final.Team1, teamOrUnknown(tournament.Playoff.Final.Team2))
 	} else {
 		bracket += "?\n"
 	}
@@ -184,18 +313,8 @@ func SendPlayoffStartMessage(tournament *db.Tournament) error {
 	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
 	msg.ParseMode = "HTML"
 
-	// Создаем новый экземпляр бота с использованием токена
-	bot, err := tgbotapi.NewBotAPI(BotToken)
-	if err != nil {
-		return fmt.Errorf("failed to create bot: %v", err)
-	}
-
-	// Устанавливаем время ожидания для бота
-	bot.Debug = true
-	bot.Client = &http.Client{Timeout: 30 * time.Second}
-
-	// Отправляем сообщение
-	_, err = bot.Send(msg)
+	// Ставим сообщение в очередь на отправку
+	err := enqueue(msg, "playoff start message")
 	if err != nil {
 		return fmt.Errorf("failed to send playoff start message: %v", err)
 	}
@@ -207,18 +326,7 @@ func SendPlayoffMatchResultMessage(tournament *db.Tournament, currentStage strin
 	// Формируем текст сообщения с результатами матча плей-офф
 	message := fmt.Sprintf("<b>⚽ Результаты матча %s:</b>\n", GetCurrentStageName(currentStage))
 
-	var resultString string
-	if match.Penalties {
-		resultString = fmt.Sprintf("<b>%s</b> %d:%d (%d:%d) <b>%s</b> (по пенальти)",
-			match.Team1, match.Score1, match.Score2,
-			match.PenaltyScore1, match.PenaltyScore2, match.Team2)
-	} else if match.ExtraTime {
-		resultString = fmt.Sprintf("<b>%s</b> %d:%d <b>%s</b> (после овертайма)",
-			match.Team1, match.Score1, match.Score2, match.Team2)
-	} else {
-		resultString = fmt.Sprintf("<b>%s</b> %d:%d <b>%s</b>",
-			match.Team1, match.Score1, match.Score2, match.Team2)
-	}
+	resultString := FormatMatchResult(match, "<b>"+match.Team1+"</b>", "<b>"+match.Team2+"</b>")
 
 	message += resultString + "\n\n"
 
@@ -226,72 +334,16 @@ func SendPlayoffMatchResultMessage(tournament *db.Tournament, currentStage strin
 	message += "<b>🏆 Сетка плей-офф:</b>\n"
 	bracket := "<pre>\n"
 	bracket += "Четвертьфинал:\n"
-	for _, match := range tournament.Playoff.QuarterFinals {
-		team1Participant := getParticipantByTeam(tournament.ParticipantTeams, match.Team1)
-		team2Participant := getParticipantByTeam(tournament.ParticipantTeams, match.Team2)
-		if match.Counted {
-			if match.Penalties {
-				bracket += fmt.Sprintf("%s (%s) %d:%d (%d:%d) %s (%s) (пен.)\n",
-					match.Team1, team1Participant, match.Score1, match.Score2,
-					match.PenaltyScore1, match.PenaltyScore2, match.Team2, team2Participant)
-			} else if match.ExtraTime {
-				bracket += fmt.Sprintf("%s (%s) %d:%d %s (%s) (овертайм)\n",
-					match.Team1, team1Participant, match.Score1, match.Score2, match.Team2, team2Participant)
-			} else {
-				bracket += fmt.Sprintf("%s (%s) %d:%d %s (%s)\n",
-					match.Team1, team1Participant, match.Score1, match.Score2, match.Team2, team2Participant)
-			}
-		} else {
-			bracket += fmt.Sprintf("%s (%s) - %s (%s)\n", match.Team1, team1Participant, match.Team2, team2Participant)
-		}
+	for i := range tournament.Playoff.QuarterFinals {
+		bracket += bracketLine(tournament, &tournament.Playoff.QuarterFinals[i])
 	}
 	bracket += "\nПолуфинал:\n"
-	for _, match := range tournament.Playoff.SemiFinals {
-		team1Participant := getParticipantByTeam(tournament.ParticipantTeams, match.Team1)
-		team2Participant := getParticipantByTeam(tournament.ParticipantTeams, match.Team2)
-		if match.Counted {
-			if match.Penalties {
-				bracket += fmt.Sprintf("%s (%s) %d:%d (%d:%d) %s (%s) (пен.)\n",
-					match.Team1, team1Participant, match.Score1, match.Score2,
-					match.PenaltyScore1, match.PenaltyScore2, match.Team2, team2Participant)
-			} else if match.ExtraTime {
-				bracket += fmt.Sprintf("%s (%s) %d:%d %s (%s) (овертайм)\n",
-					match.Team1, team1Participant, match.Score1, match.Score2, match.Team2, team2Participant)
-			} else {
-				bracket += fmt.Sprintf("%s (%s) %d:%d %s (%s)\n",
-					match.Team1, team1Participant, match.Score1, match.Score2, match.Team2, team2Participant)
-			}
-		} else {
-			bracket += fmt.Sprintf("%s (%s) - %s (%s)\n", match.Team1, team1Participant, match.Team2, team2Participant)
-		}
+	for i := range tournament.Playoff.SemiFinals {
+		bracket += bracketLine(tournament, &tournament.Playoff.SemiFinals[i])
 	}
 	bracket += "\nФинал:\n"
 	if tournament.Playoff.Final != nil {
-		team1Participant := getParticipantByTeam(tournament.ParticipantTeams, tournament.Playoff.Final.Team1)
-		team2Participant := getParticipantByTeam(tournament.ParticipantTeams, tournament.Playoff.Final.Team2)
-		if tournament.Playoff.Final.Counted {
-			if tournament.Playoff.Final.Penalties {
-				bracket += fmt.Sprintf("%s (%s) %d:%d (%d:%d) %s (%s) (пен.)\n",
-					tournament.Playoff.Final.Team1, team1Participant,
-					tournament.Playoff.Final.Score1, tournament.Playoff.Final.Score2,
-					tournament.Playoff.Final.PenaltyScore1, tournament.Playoff.Final.PenaltyScore2,
-					tournament.Playoff.Final.Team2, team2Participant)
-			} else if tournament.Playoff.Final.ExtraTime {
-				bracket += fmt.Sprintf("%s (%s) %d:%d %s (%s) (овертайм)\n",
-					tournament.Playoff.Final.Team1, team1Participant,
-					tournament.Playoff.Final.Score1, tournament.Playoff.Final.Score2,
-					tournament.Playoff.Final.Team2, team2Participant)
-			} else {
-				bracket += fmt.Sprintf("%s (%s) %d:%d %s (%s)\n",
-					tournament.Playoff.Final.Team1, team1Participant,
-					tournament.Playoff.Final.Score1, tournament.Playoff.Final.Score2,
-					tournament.Playoff.Final.Team2, team2Participant)
-			}
-		} else {
-			bracket += fmt.Sprintf("%s (%s) - %s (%s)\n",
-				tournament.Playoff.Final.Team1, team1Participant,
-				tournament.Playoff.Final.Team2, team2Participant)
-		}
+		bracket += bracketLine(tournament, tournament.Playoff.Final)
 	}
 	bracket += "</pre>"
 
@@ -313,18 +365,8 @@ func SendPlayoffMatchResultMessage(tournament *db.Tournament, currentStage strin
 	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
 	msg.ParseMode = "HTML"
 
-	// Создаем новый экземпляр бота с использованием токена
-	bot, err := tgbotapi.NewBotAPI(BotToken)
-	if err != nil {
-		return fmt.Errorf("failed to create bot: %v", err)
-	}
-
-	// Устанавливаем время ожидания для бота
-	bot.Debug = true
-	bot.Client = &http.Client{Timeout: 30 * time.Second}
-
-	// Отправляем сообщение
-	_, err = bot.Send(msg)
+	// Ставим сообщение в очередь на отправку
+	err := enqueue(msg, "playoff match result message")
 	if err != nil {
 		return fmt.Errorf("failed to send playoff match result message: %v", err)
 	}
@@ -341,6 +383,31 @@ func getParticipantByTeam(participantTeams map[string]string, teamName string) s
 	return "Unknown"
 }
 
+// bracketLine — строка сетки плей-офф с участниками команд
+func bracketLine(tournament *db.Tournament, match *db.Match) string {
+	team1 := fmt.Sprintf("%s (%s)", match.Team1, getParticipantByTeam(tournament.ParticipantTeams, match.Team1))
+	team2 := fmt.Sprintf("%s (%s)", match.Team2, getParticipantByTeam(tournament.ParticipantTeams, match.Team2))
+	if !match.Counted {
+		return fmt.Sprintf("%s - %s\n", team1, team2)
+	}
+	return FormatMatchResult(match, team1, team2) + "\n"
+}
+
+func teamOrUnknown(team string) string {
+	if team == "" {
+		return "?"
+	}
+	return team
+}
+
+// teamCategoryLabel — категория команд турнира или пометка о свободном выборе
+func teamCategoryLabel(tournament *db.Tournament) string {
+	if tournament.FreeChoice {
+		return "свободный выбор команд"
+	}
+	return tournament.TeamCategory
+}
+
 func GetCurrentStageName(stage string) string {
 	switch stage {
 	case "quarter":
@@ -388,21 +455,98 @@ func SendSeasonRatingMessage() error {
 	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
 	msg.ParseMode = "HTML"
 
-	// Создаем новый экземпляр бота с использованием токена
-	bot, err := tgbotapi.NewBotAPI(BotToken)
+	// Ставим сообщение в очередь на отправку
+	err = enqueue(msg, "season rating message")
+	if err != nil {
+		return fmt.Errorf("failed to send season rating message: %v", err)
+	}
+
+	return nil
+}
+
+// SendSeasonStartMessage объявляет в канале о начале сезона
+func SendSeasonStartMessage(season *db.Season) error {
+	message := fmt.Sprintf(`
+<b>🚩 Начался сезон %s!</b>
+
+<b>Очки за турнир:</b>
+%s
+Удачи всем участникам! ⚽
+`, season.Name, SeasonScoringText(season.Scoring))
+
+	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
+	msg.ParseMode = "HTML"
+
+	err := enqueue(msg, "season start message")
 	if err != nil {
-		return fmt.Errorf("failed to create bot: %v", err)
+		return fmt.Errorf("failed to send season start message: %v", err)
 	}
 
-	// Устанавливаем время ожидания для бота
-	bot.Debug = true
-	bot.Client = &http.Client{Timeout: 30 * time.Second}
+	return nil
+}
+
+// SendSeasonEndMessage объявляет чемпионов сезона и публикует итоговую таблицу
+func SendSeasonEndMessage(season *db.Season) error {
+	medals := []string{"🥇", "🥈", "🥉"}
+	message := fmt.Sprintf("<b>🏁 Сезон %s завершен!</b>\n\n", season.Name)
+	for i, standing := range season.Standings {
+		if i >= len(medals) {
+			break
+		}
+		message += fmt.Sprintf("%s <b>%s</b> — %d очков\n", medals[i], standing.Participant, standing.Points)
+	}
+
+	message += "\n<b>Итоговая таблица:</b>\n"
+	message += SeasonTableText(season.Standings)
+
+	msg := tgbotapi.NewMessageToChannel(ChannelID, message)
+	msg.ParseMode = "HTML"
 
-	// Отправляем сообщение
-	_, err = bot.Send(msg)
+	err := enqueue(msg, "season end message")
 	if err != nil {
-		return fmt.Errorf("failed to send season rating message: %v", err)
+		return fmt.Errorf("failed to send season end message: %v", err)
 	}
 
 	return nil
 }
+
+// SeasonTableText форматирует таблицу сезона для HTML-сообщения
+func SeasonTableText(standings []db.SeasonStanding) string {
+	text := "<pre>Поз. Участник               Очки  Турниры  Побед  Ничьих  Пораж.  Голы</pre>\n"
+	for _, standing := range standings {
+		text += fmt.Sprintf(
+			"<pre>%2d.  %-20s  %4d    %3d     %3d    %3d     %3d    %3d - %3d</pre>\n",
+			standing.Position, standing.Participant, standing.Points, standing.TournamentsPlayed,
+			standing.Wins, standing.Draws, standing.Losses, standing.GoalsScored, standing.GoalsConceded,
+		)
+	}
+	return text
+}
+
+// SeasonScoringText описывает правила начисления очков сезона, по строке на правило
+func SeasonScoringText(scoring db.SeasonScoring) string {
+	var text string
+	for i, points := range scoring.Places {
+		if points != 0 {
+			text += fmt.Sprintf("• %d место — %d\n", i+1, points)
+		}
+	}
+	for i, points := range scoring.GroupBonus {
+		if points != 0 {
+			text += fmt.Sprintf("• %d место в группе — +%d\n", i+1, points)
+		}
+	}
+	if scoring.Participation != 0 {
+		text += fmt.Sprintf("• участие — +%d\n", scoring.Participation)
+	}
+	if scoring.Win != 0 {
+		text += fmt.Sprintf("• каждая победа — +%d\n", scoring.Win)
+	}
+	if scoring.Draw != 0 {
+		text += fmt.Sprintf("• каждая ничья — +%d\n", scoring.Draw)
+	}
+	if text == "" {
+		text = "• очки не начисляются\n"
+	}
+	return text
+}
@@ -0,0 +1,128 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+	"tournament-bot/internal/metrics"
+)
+
+var (
+	ErrOutboxClosed = errors.New("notification outbox is closed")
+	ErrOutboxFull   = errors.New("notification outbox is full")
+)
+
+type outboxItem struct {
+	msg  tgbotapi.Chattable
+	kind string
+	// send заменяет отправку msg, когда уведомление состоит из нескольких запросов,
+	// например сообщения, которое потом редактируется
+	send func(api *tgbotapi.BotAPI) error
+}
+
+// outbox отправляет сообщения в канал в отдельной горутине, сохраняя порядок,
+// чтобы обработчики не ждали ответа Telegram и сообщения можно было дослать при остановке
+type outbox struct {
+	mu     sync.Mutex
+	api    *tgbotapi.BotAPI
+	queue  chan outboxItem
+	done   chan struct{}
+	closed bool
+}
+
+var sender *outbox
+
+// Start создает клиент Bot API для уведомлений и запускает отправку сообщений из очереди
+func Start(token string, queueSize int) error {
+	api, err := tgbotapi.NewBotAPI(token)
+	if err != nil {
+		return err
+	}
+
+	// Устанавливаем время ожидания для бота
+	api.Client = &http.Client{Timeout: 30 * time.Second}
+
+	o := &outbox{
+		api:   api,
+		queue: make(chan outboxItem, queueSize),
+		done:  make(chan struct{}),
+	}
+	go o.run()
+
+	sender = o
+	return nil
+}
+
+// Shutdown перестает принимать новые сообщения и дожидается отправки уже поставленных в очередь
+func Shutdown(ctx context.Context) error {
+	if sender == nil {
+		return nil
+	}
+
+	sender.mu.Lock()
+	if !sender.closed {
+		sender.closed = true
+		close(sender.queue)
+	}
+	sender.mu.Unlock()
+
+	select {
+	case <-sender.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func enqueue(msg tgbotapi.Chattable, kind string) error {
+	return enqueueItem(outboxItem{msg: msg, kind: kind})
+}
+
+// enqueueFunc ставит в очередь последовательность запросов. Следующие уведомления
+// отправятся только после того, как она завершится.
+func enqueueFunc(send func(api *tgbotapi.BotAPI) error, kind string) error {
+	return enqueueItem(outboxItem{send: send, kind: kind})
+}
+
+func enqueueItem(item outboxItem) error {
+	if sender == nil {
+		return ErrOutboxClosed
+	}
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	if sender.closed {
+		return ErrOutboxClosed
+	}
+
+	// Не ждем места в очереди под блокировкой: иначе при переполнении зависнут
+	// и обработчики, и Shutdown
+	select {
+	case sender.queue <- item:
+		return nil
+	default:
+		metrics.NotificationFailures.WithLabelValues(item.kind).Inc()
+		return ErrOutboxFull
+	}
+}
+
+func (o *outbox) run() {
+	defer close(o.done)
+	for item := range o.queue {
+		var err error
+		if item.send != nil {
+			err = item.send(o.api)
+		} else {
+			_, err = o.api.Send(item.msg)
+		}
+		if err != nil {
+			slog.Error("Failed to send notification", "kind", item.kind, "err", err)
+			metrics.NotificationFailures.WithLabelValues(item.kind).Inc()
+		}
+	}
+}
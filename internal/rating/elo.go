@@ -0,0 +1,42 @@
+package rating
+
+import (
+	"math"
+)
+
+// Elo — рейтинг Эло с множителем за разницу голов, как в World Football Elo Ratings
+type Elo struct {
+	K float64
+}
+
+func (e *Elo) Name() string {
+	return SystemElo
+}
+
+func (e *Elo) Initial() Rating {
+	return Rating{Value: InitialRating}
+}
+
+// Expected — ожидаемый результат первого игрока
+func Expected(a, b, handicap float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a-handicap)/400))
+}
+
+// goalDifferenceMultiplier увеличивает изменение рейтинга за крупную победу
+func goalDifferenceMultiplier(goalDifference int) float64 {
+	switch {
+	case goalDifference <= 1:
+		return 1
+	case goalDifference == 2:
+		return 1.5
+	default:
+		return (11 + float64(goalDifference)) / 8
+	}
+}
+
+func (e *Elo) Update(a, b Rating, result Result) (Rating, Rating) {
+	delta := e.K * goalDifferenceMultiplier(result.GoalDifference) * (result.Score - Expected(a.Value, b.Value, result.Handicap))
+	a.Value += delta
+	b.Value -= delta
+	return a, b
+}
@@ -0,0 +1,121 @@
+package rating
+
+import (
+	"math"
+)
+
+const (
+	// DefaultTau ограничивает изменение волатильности
+	DefaultTau = 0.5
+
+	initialDeviation  = 350
+	initialVolatility = 0.06
+	// glicko2Scale переводит рейтинг в шкалу Glicko-2
+	glicko2Scale = 173.7178
+	// convergence — точность поиска новой волатильности
+	convergence = 0.000001
+)
+
+// Glicko2 — рейтинг Glicko-2 (Glickman, 2013). Каждый матч считается отдельным
+// рейтинговым периодом.
+type Glicko2 struct {
+	Tau float64
+}
+
+func (g *Glicko2) Name() string {
+	return SystemGlicko2
+}
+
+func (g *Glicko2) Initial() Rating {
+	return Rating{Value: InitialRating, Deviation: initialDeviation, Volatility: initialVolatility}
+}
+
+// game — матч рейтингового периода с точки зрения игрока
+type game struct {
+	opponent Rating
+	score    float64
+}
+
+func (g *Glicko2) Update(a, b Rating, result Result) (Rating, Rating) {
+	a, b = g.withDefaults(a), g.withDefaults(b)
+	// Фора сдвигает соперника: для первого игрока он слабее, для второго первый сильнее
+	newA := g.update(a, []game{{Rating{Value: b.Value - result.Handicap, Deviation: b.Deviation}, result.Score}})
+	newB := g.update(b, []game{{Rating{Value: a.Value + result.Handicap, Deviation: a.Deviation}, 1 - result.Score}})
+	return newA, newB
+}
+
+// withDefaults дополняет рейтинг, посчитанный без Glicko-2, начальными отклонением и волатильностью
+func (g *Glicko2) withDefaults(r Rating) Rating {
+	if r.Deviation <= 0 {
+		r.Deviation = initialDeviation
+	}
+	if r.Volatility <= 0 {
+		r.Volatility = initialVolatility
+	}
+	return r
+}
+
+// update пересчитывает рейтинг игрока за рейтинговый период (шаги 3–8 алгоритма)
+func (g *Glicko2) update(player Rating, games []game) Rating {
+	mu := (player.Value - InitialRating) / glicko2Scale
+	phi := player.Deviation / glicko2Scale
+
+	var vInv, improvement float64
+	for _, played := range games {
+		muJ := (played.opponent.Value - InitialRating) / glicko2Scale
+		phiJ := played.opponent.Deviation / glicko2Scale
+
+		gPhi := 1 / math.Sqrt(1+3*phiJ*phiJ/(math.Pi*math.Pi))
+		expected := 1 / (1 + math.Exp(-gPhi*(mu-muJ)))
+		vInv += gPhi * gPhi * expected * (1 - expected)
+		improvement += gPhi * (played.score - expected)
+	}
+	v := 1 / vInv
+	delta := v * improvement
+
+	sigma := g.volatility(phi, player.Volatility, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*improvement
+
+	return Rating{
+		Value:      newMu*glicko2Scale + InitialRating,
+		Deviation:  newPhi * glicko2Scale,
+		Volatility: sigma,
+	}
+}
+
+// volatility находит новую волатильность методом Иллинойса (шаг 5 алгоритма)
+func (g *Glicko2) volatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		return ex*(delta*delta-phi*phi-v-ex)/(2*math.Pow(phi*phi+v+ex, 2)) - (x-a)/(g.Tau*g.Tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*g.Tau) < 0 {
+			k++
+		}
+		B = a - k*g.Tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > convergence {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	return math.Exp(A / 2)
+}
@@ -0,0 +1,67 @@
+// Package rating считает рейтинг игроков по результатам матчей: Эло с коэффициентом K
+// и множителем за разницу голов или Glicko-2. Пакет не зависит от базы данных.
+package rating
+
+import (
+	"fmt"
+)
+
+const (
+	SystemElo     = "elo"
+	SystemGlicko2 = "glicko2"
+)
+
+// Начальный рейтинг нового игрока
+const InitialRating = 1500
+
+// Rating — рейтинг игрока. Deviation и Volatility используются только в Glicko-2.
+type Rating struct {
+	Value      float64
+	Deviation  float64
+	Volatility float64
+}
+
+// Result — результат матча с точки зрения первого игрока
+type Result struct {
+	// Score — 1 за победу, 0.5 за ничью, 0 за поражение
+	Score float64
+	// GoalDifference — разница голов по модулю
+	GoalDifference int
+	// Handicap — преимущество первого игрока в очках рейтинга, например из-за более сильной команды
+	Handicap float64
+}
+
+// ResultFromScore строит результат по счету матча
+func ResultFromScore(score1, score2 int) Result {
+	result := Result{Score: 0.5, GoalDifference: score1 - score2}
+	switch {
+	case score1 > score2:
+		result.Score = 1
+	case score1 < score2:
+		result.Score = 0
+		result.GoalDifference = score2 - score1
+	}
+	return result
+}
+
+// Engine пересчитывает рейтинги двух игроков после матча
+type Engine interface {
+	Name() string
+	Initial() Rating
+	Update(a, b Rating, result Result) (Rating, Rating)
+}
+
+// New создает движок рейтинга. k — коэффициент K для Эло.
+func New(system string, k float64) (Engine, error) {
+	switch system {
+	case SystemElo, "":
+		if k <= 0 {
+			return nil, fmt.Errorf("elo K-factor must be positive, got %g", k)
+		}
+		return &Elo{K: k}, nil
+	case SystemGlicko2:
+		return &Glicko2{Tau: DefaultTau}, nil
+	default:
+		return nil, fmt.Errorf("unknown rating system: %s", system)
+	}
+}
@@ -0,0 +1,161 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func approx(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestEloUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   float64
+		result Result
+		wantA  float64
+		wantB  float64
+	}{
+		{
+			name: "equal ratings, win by one",
+			a:    1500, b: 1500,
+			result: Result{Score: 1, GoalDifference: 1},
+			wantA:  1510, wantB: 1490,
+		},
+		{
+			name: "equal ratings, draw",
+			a:    1500, b: 1500,
+			result: Result{Score: 0.5},
+			wantA:  1500, wantB: 1500,
+		},
+		{
+			name: "win by two counts one and a half times",
+			a:    1500, b: 1500,
+			result: Result{Score: 1, GoalDifference: 2},
+			wantA:  1515, wantB: 1485,
+		},
+		{
+			name: "win by four",
+			a:    1500, b: 1500,
+			result: Result{Score: 1, GoalDifference: 4},
+			wantA:  1500 + 20*15.0/8*0.5, wantB: 1500 - 20*15.0/8*0.5,
+		},
+		{
+			name: "favourite loses",
+			a:    1600, b: 1400,
+			result: Result{Score: 0, GoalDifference: 1},
+			wantA:  1600 - 20/(1+math.Pow(10, -0.5)), wantB: 1400 + 20/(1+math.Pow(10, -0.5)),
+		},
+		{
+			name: "handicap offsets the rating gap",
+			a:    1400, b: 1500,
+			result: Result{Score: 1, GoalDifference: 1, Handicap: 100},
+			wantA:  1410, wantB: 1490,
+		},
+	}
+
+	engine := &Elo{K: 20}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := engine.Update(Rating{Value: tt.a}, Rating{Value: tt.b}, tt.result)
+			if !approx(a.Value, tt.wantA, 1e-9) || !approx(b.Value, tt.wantB, 1e-9) {
+				t.Errorf("Update() = %.4f, %.4f, want %.4f, %.4f", a.Value, b.Value, tt.wantA, tt.wantB)
+			}
+		})
+	}
+}
+
+// Пример из статьи Glickman «Example of the Glicko-2 system»
+func TestGlicko2PaperExample(t *testing.T) {
+	engine := &Glicko2{Tau: 0.5}
+	player := Rating{Value: 1500, Deviation: 200, Volatility: 0.06}
+	games := []game{
+		{Rating{Value: 1400, Deviation: 30}, 1},
+		{Rating{Value: 1550, Deviation: 100}, 0},
+		{Rating{Value: 1700, Deviation: 300}, 0},
+	}
+
+	got := engine.update(player, games)
+	if !approx(got.Value, 1464.06, 0.01) {
+		t.Errorf("rating = %.4f, want 1464.06", got.Value)
+	}
+	if !approx(got.Deviation, 151.52, 0.01) {
+		t.Errorf("deviation = %.4f, want 151.52", got.Deviation)
+	}
+	if !approx(got.Volatility, 0.05999, 0.00001) {
+		t.Errorf("volatility = %.6f, want 0.05999", got.Volatility)
+	}
+}
+
+func TestGlicko2Update(t *testing.T) {
+	engine := &Glicko2{Tau: DefaultTau}
+	initial := engine.Initial()
+
+	tests := []struct {
+		name   string
+		a, b   Rating
+		result Result
+		check  func(t *testing.T, a, b Rating)
+	}{
+		{
+			name: "winner gains what the equal loser drops",
+			a:    initial, b: initial,
+			result: Result{Score: 1},
+			check: func(t *testing.T, a, b Rating) {
+				if a.Value <= InitialRating || !approx(a.Value-InitialRating, InitialRating-b.Value, 1e-9) {
+					t.Errorf("ratings = %.4f, %.4f, want a symmetric change", a.Value, b.Value)
+				}
+			},
+		},
+		{
+			name: "draw between equals keeps ratings",
+			a:    initial, b: initial,
+			result: Result{Score: 0.5},
+			check: func(t *testing.T, a, b Rating) {
+				if !approx(a.Value, InitialRating, 1e-9) || !approx(b.Value, InitialRating, 1e-9) {
+					t.Errorf("ratings = %.4f, %.4f, want %d", a.Value, b.Value, InitialRating)
+				}
+			},
+		},
+		{
+			name: "deviation shrinks after a match",
+			a:    initial, b: initial,
+			result: Result{Score: 0},
+			check: func(t *testing.T, a, b Rating) {
+				if a.Deviation >= initialDeviation || b.Deviation >= initialDeviation {
+					t.Errorf("deviations = %.4f, %.4f, want below %d", a.Deviation, b.Deviation, initialDeviation)
+				}
+			},
+		},
+		{
+			name: "elo ratings get default deviation and volatility",
+			a:    Rating{Value: 1500}, b: Rating{Value: 1500},
+			result: Result{Score: 1},
+			check: func(t *testing.T, a, b Rating) {
+				want, _ := engine.Update(initial, initial, Result{Score: 1})
+				if a != want {
+					t.Errorf("rating = %+v, want %+v", a, want)
+				}
+			},
+		},
+		{
+			name:   "handicap offsets the rating gap",
+			a:      Rating{Value: 1400, Deviation: 100, Volatility: 0.06},
+			b:      Rating{Value: 1500, Deviation: 100, Volatility: 0.06},
+			result: Result{Score: 0.5, Handicap: 100},
+			check: func(t *testing.T, a, b Rating) {
+				if !approx(a.Value, 1400, 1e-9) || !approx(b.Value, 1500, 1e-9) {
+					t.Errorf("ratings = %.4f, %.4f, want 1400, 1500", a.Value, b.Value)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := engine.Update(tt.a, tt.b, tt.result)
+			tt.check(t, a, b)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"math"
+	"tournament-bot/internal/db"
+)
+
+// Сколько последних турниров игрока учитывается при оценке его силы
+const skillTournaments = 10
+
+// playerSkills оценивает силу участников по очкам в последних турнирах. У новичков
+// истории нет, им достается средняя сила остальных участников.
+func playerSkills(participants []string) ([]float64, error) {
+	skills := make([]float64, len(participants))
+	known := make([]bool, len(participants))
+	var sum float64
+	var count int
+	for i, name := range participants {
+		participant, err := db.FindParticipant(name)
+		if err != nil {
+			return nil, err
+		}
+		if participant == nil || len(participant.Stats.TournamentStats) == 0 {
+			continue
+		}
+
+		history := participant.Stats.TournamentStats
+		if len(history) > skillTournaments {
+			history = history[len(history)-skillTournaments:]
+		}
+		var points int
+		for _, stat := range history {
+			points += stat.Points
+		}
+		skills[i] = float64(points) / float64(len(history))
+		known[i] = true
+		sum += skills[i]
+		count++
+	}
+
+	if count > 0 {
+		for i := range skills {
+			if !known[i] {
+				skills[i] = sum / float64(count)
+			}
+		}
+	}
+	return skills, nil
+}
+
+// teamStrengths возвращает рейтинги команд из справочника, 0 — команды в справочнике нет
+func teamStrengths(teams []string) ([]float64, error) {
+	strengths := make([]float64, len(teams))
+	for i, name := range teams {
+		team, err := db.FindTeam(name)
+		if err != nil {
+			return nil, err
+		}
+		if team != nil {
+			strengths[i] = float64(team.Strength())
+		}
+	}
+	return strengths, nil
+}
+
+// normalize переводит значения в отрезок [0, 1]; если все значения равны, возвращает 0.5
+func normalize(values []float64) []float64 {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	normalized := make([]float64, len(values))
+	for i, v := range values {
+		if hi > lo {
+			normalized[i] = (v - lo) / (hi - lo)
+		} else {
+			normalized[i] = 0.5
+		}
+	}
+	return normalized
+}
+
+// normalizedStrengths — сила команд в [0, 1]. Если рейтинги есть не у всех команд,
+// сила определяется порядком в категории: сильнейшие идут первыми.
+func normalizedStrengths(strengths []float64) []float64 {
+	for _, s := range strengths {
+		if s <= 0 {
+			byOrder := make([]float64, len(strengths))
+			for i := range byOrder {
+				byOrder[i] = float64(len(strengths) - i)
+			}
+			return normalize(byOrder)
+		}
+	}
+	return normalize(strengths)
+}
+
+// assignBalanced подбирает пары так, чтобы сумма силы игрока и силы команды у всех
+// была как можно ближе к одной величине: сильнейшему игроку — слабейшая команда.
+// Если команд в категории больше, чем участников, выбираются команды, с которыми
+// баланс лучше, поэтому для смешивания уровней подходит категория из справочника
+// с диапазоном звезд.
+func assignBalanced(draw *teamDraw) (map[string]string, error) {
+	skills := normalize(draw.skills)
+	strengths := normalizedStrengths(draw.strengths)
+
+	// Перемешивание делает выбор среди равноценных вариантов случайным
+	players := draw.rng.Perm(len(draw.participants))
+	teams := draw.rng.Perm(len(draw.teams))
+
+	cost := make([][]float64, len(players))
+	for i, p := range players {
+		cost[i] = make([]float64, len(teams))
+		for j, t := range teams {
+			d := skills[p] + strengths[t] - 1
+			cost[i][j] = d * d
+		}
+	}
+
+	assignment := hungarian(cost)
+	participantTeams := make(map[string]string)
+	for i, p := range players {
+		participantTeams[draw.participants[p]] = draw.teams[teams[assignment[i]]]
+	}
+	return participantTeams, nil
+}
+
+// hungarian решает задачу о назначениях для матрицы n×m (n <= m) и возвращает
+// для каждой строки номер столбца так, чтобы сумма стоимостей была минимальной
+func hungarian(cost [][]float64) []int {
+	n := len(cost)
+	if n == 0 {
+		return nil
+	}
+	m := len(cost[0])
+
+	// Потенциалы строк и столбцов; match[j] — строка (с 1), назначенная столбцу j
+	u := make([]float64, n+1)
+	v := make([]float64, m+1)
+	match := make([]int, m+1)
+	way := make([]int, m+1)
+
+	for i := 1; i <= n; i++ {
+		match[0] = i
+		j0 := 0
+		minv := make([]float64, m+1)
+		used := make([]bool, m+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+		for match[j0] != 0 {
+			used[j0] = true
+			i0, delta, j1 := match[j0], math.Inf(1), 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				if cur := cost[i0-1][j-1] - u[i0] - v[j]; cur < minv[j] {
+					minv[j], way[j] = cur, j0
+				}
+				if minv[j] < delta {
+					delta, j1 = minv[j], j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[match[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			j0 = j1
+		}
+		for j0 != 0 {
+			j1 := way[j0]
+			match[j0] = match[j1]
+			j0 = j1
+		}
+	}
+
+	assignment := make([]int, n)
+	for j := 1; j <= m; j++ {
+		if match[j] != 0 {
+			assignment[match[j]-1] = j - 1
+		}
+	}
+	return assignment
+}
+
+// BalanceScore оценивает равенство сил пар по данным жеребьевки: 100 — у всех пар
+// одинаковая сумма силы игрока и команды, 0 — сильнейший игрок с сильнейшей командой
+// против слабейшего со слабейшей
+func BalanceScore(record *db.DrawRecord, participantTeams map[string]string) (int, bool) {
+	if record == nil || len(record.Skills) != len(record.Participants) || len(record.Strengths) != len(record.Teams) {
+		return 0, false
+	}
+	skills := normalize(record.Skills)
+	strengths := normalizedStrengths(record.Strengths)
+	teamIndex := make(map[string]int)
+	for j, team := range record.Teams {
+		teamIndex[team] = j
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i, participant := range record.Participants {
+		j, ok := teamIndex[participantTeams[participant]]
+		if !ok {
+			return 0, false
+		}
+		combined := skills[i] + strengths[j]
+		lo, hi = math.Min(lo, combined), math.Max(hi, combined)
+	}
+	return int(math.Round(100 * (1 - (hi-lo)/2))), true
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"maps"
+	"math"
+	"testing"
+	"tournament-bot/internal/db"
+)
+
+// bruteForceAssignment перебирает все назначения строк на разные столбцы
+func bruteForceAssignment(cost [][]float64) float64 {
+	best := math.Inf(1)
+	used := make([]bool, len(cost[0]))
+	var walk func(row int, sum float64)
+	walk = func(row int, sum float64) {
+		if row == len(cost) {
+			best = math.Min(best, sum)
+			return
+		}
+		for j := range used {
+			if !used[j] {
+				used[j] = true
+				walk(row+1, sum+cost[row][j])
+				used[j] = false
+			}
+		}
+	}
+	walk(0, 0)
+	return best
+}
+
+func TestHungarian(t *testing.T) {
+	tests := []struct {
+		name string
+		cost [][]float64
+	}{
+		{name: "single cell", cost: [][]float64{{7}}},
+		{name: "diagonal is not optimal", cost: [][]float64{{4, 1, 3}, {2, 0, 5}, {3, 2, 2}}},
+		{name: "equal costs", cost: [][]float64{{1, 1}, {1, 1}}},
+		{name: "more columns than rows", cost: [][]float64{{9, 2, 7, 8}, {6, 4, 3, 7}}},
+		{name: "negative costs", cost: [][]float64{{-1, -5, 0}, {-3, -2, -4}, {0, -1, -6}}},
+		{
+			name: "four by four",
+			cost: [][]float64{{82, 83, 69, 92}, {77, 37, 49, 92}, {11, 69, 5, 86}, {8, 9, 98, 23}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assignment := hungarian(tt.cost)
+			if len(assignment) != len(tt.cost) {
+				t.Fatalf("assignment = %v, want one column per row", assignment)
+			}
+			used := make(map[int]bool)
+			var sum float64
+			for i, j := range assignment {
+				if j < 0 || j >= len(tt.cost[i]) || used[j] {
+					t.Fatalf("assignment = %v is not a matching", assignment)
+				}
+				used[j] = true
+				sum += tt.cost[i][j]
+			}
+			if want := bruteForceAssignment(tt.cost); math.Abs(sum-want) > 1e-9 {
+				t.Errorf("assignment %v costs %g, want %g", assignment, sum, want)
+			}
+		})
+	}
+
+	if got := hungarian(nil); got != nil {
+		t.Errorf("hungarian(nil) = %v, want nil", got)
+	}
+}
+
+func TestAssignBalanced(t *testing.T) {
+	tests := []struct {
+		name         string
+		participants []string
+		skills       []float64
+		teams        []string
+		strengths    []float64
+		want         map[string]string
+	}{
+		{
+			name:         "strongest player gets the weakest team",
+			participants: []string{"Ann", "Bob", "Cid"},
+			skills:       []float64{10, 30, 20},
+			teams:        []string{"Arsenal", "Barcelona", "Chelsea"},
+			strengths:    []float64{85, 80, 75},
+			want:         map[string]string{"Ann": "Arsenal", "Bob": "Chelsea", "Cid": "Barcelona"},
+		},
+		{
+			name:         "teams without ratings are ranked by category order",
+			participants: []string{"Ann", "Bob"},
+			skills:       []float64{5, 1},
+			teams:        []string{"Arsenal", "Barcelona"},
+			strengths:    []float64{0, 0},
+			want:         map[string]string{"Ann": "Barcelona", "Bob": "Arsenal"},
+		},
+		{
+			name:         "extra teams are picked for balance",
+			participants: []string{"Ann", "Bob"},
+			skills:       []float64{0, 10},
+			teams:        []string{"Arsenal", "Barcelona", "Chelsea", "Dortmund", "Everton"},
+			strengths:    []float64{90, 85, 80, 75, 70},
+			want:         map[string]string{"Ann": "Arsenal", "Bob": "Everton"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 5; seed++ {
+				got, err := assignBalanced(&teamDraw{
+					participants: tt.participants,
+					teams:        tt.teams,
+					skills:       tt.skills,
+					strengths:    tt.strengths,
+					rng:          drawRand(1, seed),
+				})
+				if err != nil {
+					t.Fatalf("assignBalanced: %v", err)
+				}
+				if !maps.Equal(got, tt.want) {
+					t.Fatalf("seed %d: assignment = %v, want %v", seed, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBalanceScore(t *testing.T) {
+	record := &db.DrawRecord{
+		Participants: []string{"Ann", "Bob", "Cid"},
+		Skills:       []float64{30, 20, 10},
+		Teams:        []string{"Arsenal", "Barcelona", "Chelsea"},
+		Strengths:    []float64{85, 80, 75},
+	}
+
+	tests := []struct {
+		name   string
+		record *db.DrawRecord
+		teams  map[string]string
+		want   int
+		wantOK bool
+	}{
+		{
+			name:   "perfect balance",
+			record: record,
+			teams:  map[string]string{"Ann": "Chelsea", "Bob": "Barcelona", "Cid": "Arsenal"},
+			want:   100,
+			wantOK: true,
+		},
+		{
+			name:   "strongest with strongest",
+			record: record,
+			teams:  map[string]string{"Ann": "Arsenal", "Bob": "Barcelona", "Cid": "Chelsea"},
+			want:   0,
+			wantOK: true,
+		},
+		{
+			name:   "partly balanced",
+			record: record,
+			teams:  map[string]string{"Ann": "Barcelona", "Bob": "Chelsea", "Cid": "Arsenal"},
+			want:   50,
+			wantOK: true,
+		},
+		{
+			name:   "no record",
+			teams:  map[string]string{"Ann": "Arsenal"},
+			wantOK: false,
+		},
+		{
+			name:   "record without skills",
+			record: &db.DrawRecord{Participants: record.Participants, Teams: record.Teams},
+			teams:  map[string]string{"Ann": "Chelsea", "Bob": "Barcelona", "Cid": "Arsenal"},
+			wantOK: false,
+		},
+		{
+			name:   "team outside the draw",
+			record: record,
+			teams:  map[string]string{"Ann": "Chelsea", "Bob": "Barcelona", "Cid": "Dortmund"},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := BalanceScore(tt.record, tt.teams)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("BalanceScore() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
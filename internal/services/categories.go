@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log/slog"
+	"strings"
+	"tournament-bot/internal/db"
+)
+
+const (
+	// maxCategoryNameLength ограничивает имя категории: оно передается в данных кнопок
+	maxCategoryNameLength = 32
+	// minCategoryTeams — без двух команд не сыграть ни одного матча
+	minCategoryTeams = 2
+)
+
+// validateTeamName проверяет название команды: оно передается в данных кнопок,
+// а запятая разделяет команды в аргументах команд бота
+func validateTeamName(team string) error {
+	if team == "" || len(team) > maxTeamNameLength || strings.ContainsAny(team, "_,") {
+		return fmt.Errorf("team name must be 1-%d characters long without underscores and commas", maxTeamNameLength)
+	}
+	return nil
+}
+
+func validateCategoryName(name string) error {
+	if name == "" || len(name) > maxCategoryNameLength || strings.ContainsAny(name, "_,") {
+		return fmt.Errorf("category name must be 1-%d characters long without underscores and commas", maxCategoryNameLength)
+	}
+	return nil
+}
+
+// indexOfTeam ищет команду без учета регистра
+func indexOfTeam(teams []string, team string) int {
+	for i, t := range teams {
+		if strings.EqualFold(t, team) {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkCategorySize проверяет, что в категории хватит команд на maxParticipants участников
+func checkCategorySize(name string, maxParticipants int) error {
+	category, err := db.GetTeamCategoryByName(name)
+	if err == mongo.ErrNoDocuments {
+		return fmt.Errorf("team category %s not found", name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(category.Teams) < maxParticipants {
+		return fmt.Errorf("category %s has only %d teams for %d participants",
+			category.Name, len(category.Teams), maxParticipants)
+	}
+	return nil
+}
+
+// categoryUsers возвращает незавершенные турниры и шаблоны, которые используют категорию
+func categoryUsers(name string) ([]db.Tournament, []db.TournamentTemplate, error) {
+	filter := bson.M{"team_category": name, "is_completed": false}
+	cursor, err := db.DB.Collection("tournaments").Find(context.TODO(), filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tournaments []db.Tournament
+	if err := cursor.All(context.TODO(), &tournaments); err != nil {
+		return nil, nil, err
+	}
+
+	cursor, err = db.DB.Collection("tournament_templates").Find(context.TODO(), bson.M{"settings.team_category": name})
+	if err != nil {
+		return nil, nil, err
+	}
+	var templates []db.TournamentTemplate
+	if err := cursor.All(context.TODO(), &templates); err != nil {
+		return nil, nil, err
+	}
+	return tournaments, templates, nil
+}
+
+// requiredCategoryTeams — сколько команд должно остаться в категории, чтобы всем
+// использующим ее турнирам и шаблонам хватило команд
+func requiredCategoryTeams(name string) (int, error) {
+	tournaments, templates, err := categoryUsers(name)
+	if err != nil {
+		return 0, err
+	}
+
+	required := minCategoryTeams
+	for _, tournament := range tournaments {
+		required = max(required, tournament.MaxParticipants)
+	}
+	for _, template := range templates {
+		required = max(required, template.Settings.MaxParticipants)
+	}
+	return required, nil
+}
+
+func getCategory(name string) (*db.TeamCategory, error) {
+	category, err := db.GetTeamCategoryByName(name)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("team category %s not found", name)
+	}
+	return category, err
+}
+
+// CreateTeamCategory добавляет категорию. Названия команд не должны повторяться.
+func CreateTeamCategory(name string, teams []string) error {
+	if err := validateCategoryName(name); err != nil {
+		return err
+	}
+	if len(teams) < minCategoryTeams {
+		return fmt.Errorf("a category needs at least %d teams", minCategoryTeams)
+	}
+	var unique []string
+	for _, team := range teams {
+		if err := validateTeamName(team); err != nil {
+			return err
+		}
+		if indexOfTeam(unique, team) >= 0 {
+			return fmt.Errorf("team %s is listed twice", team)
+		}
+		unique = append(unique, team)
+	}
+
+	err := db.AddTeamCategory(name, unique)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("team category %s already exists", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Team category created", "category", name, "teams", len(unique))
+	return nil
+}
+
+// RenameTeamCategory переименовывает категорию вместе со ссылками на нее в турнирах и шаблонах
+func RenameTeamCategory(oldName, newName string) error {
+	if err := validateCategoryName(newName); err != nil {
+		return err
+	}
+	if _, err := getCategory(oldName); err != nil {
+		return err
+	}
+
+	_, err := db.DB.Collection("team_categories").UpdateOne(context.TODO(),
+		bson.M{"name": oldName}, bson.M{"$set": bson.M{"name": newName}})
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("team category %s already exists", newName)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = db.DB.Collection("tournaments").UpdateMany(context.TODO(),
+		bson.M{"team_category": oldName}, bson.M{"$set": bson.M{"team_category": newName}})
+	if err != nil {
+		return err
+	}
+	_, err = db.DB.Collection("tournament_templates").UpdateMany(context.TODO(),
+		bson.M{"settings.team_category": oldName}, bson.M{"$set": bson.M{"settings.team_category": newName}})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Team category renamed", "from", oldName, "to", newName)
+	return nil
+}
+
+// categoryMember — значение, которым команда записана в категории: название
+// или, для категорий из справочника, ID команды
+func categoryMember(category *db.TeamCategory, team string) (field string, value interface{}, err error) {
+	if category.Query != nil {
+		return "", nil, fmt.Errorf("category %s is defined by a catalog query, edit the catalog instead", category.Name)
+	}
+	if len(category.TeamIDs) == 0 {
+		return "teams", team, nil
+	}
+	catalogTeam, err := db.FindTeam(team)
+	if err != nil {
+		return "", nil, err
+	}
+	if catalogTeam == nil {
+		return "", nil, fmt.Errorf("team %s is not in the catalog", team)
+	}
+	return "team_ids", catalogTeam.ID, nil
+}
+
+// AddTeamToCategory добавляет команду в конец категории
+func AddTeamToCategory(name, team string) error {
+	if err := validateTeamName(team); err != nil {
+		return err
+	}
+	category, err := getCategory(name)
+	if err != nil {
+		return err
+	}
+	if i := indexOfTeam(category.Teams, team); i >= 0 {
+		return fmt.Errorf("%s is already in category %s", category.Teams[i], name)
+	}
+	field, value, err := categoryMember(category, team)
+	if err != nil {
+		return err
+	}
+
+	// Условие на отсутствие команды защищает от одновременного добавления той же команды
+	filter := bson.M{"name": name, field: bson.M{"$ne": value}}
+	result, err := db.DB.Collection("team_categories").UpdateOne(context.TODO(), filter,
+		bson.M{"$push": bson.M{field: value}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%s is already in category %s", team, name)
+	}
+
+	slog.Info("Team added to category", "category", name, "team", team)
+	return nil
+}
+
+// RemoveTeamFromCategory убирает команду из категории, если после этого всем турнирам
+// хватит команд и команда не занята в незавершенном турнире
+func RemoveTeamFromCategory(name, team string) error {
+	category, err := getCategory(name)
+	if err != nil {
+		return err
+	}
+	i := indexOfTeam(category.Teams, team)
+	if i < 0 {
+		return fmt.Errorf("%s is not in category %s", team, name)
+	}
+	team = category.Teams[i]
+	field, value, err := categoryMember(category, team)
+	if err != nil {
+		return err
+	}
+
+	tournaments, _, err := categoryUsers(name)
+	if err != nil {
+		return err
+	}
+	for _, tournament := range tournaments {
+		if tournament.HasTeam(team) {
+			return fmt.Errorf("%s plays in tournament %s", team, tournament.Name)
+		}
+	}
+
+	required, err := requiredCategoryTeams(name)
+	if err != nil {
+		return err
+	}
+	if len(category.Teams)-1 < required {
+		return fmt.Errorf("category %s must keep at least %d teams", name, required)
+	}
+
+	// Условие на число команд не дает двум одновременным удалениям опустить его ниже минимума
+	filter := bson.M{"name": name, field + "." + fmt.Sprint(required): bson.M{"$exists": true}}
+	result, err := db.DB.Collection("team_categories").UpdateOne(context.TODO(), filter,
+		bson.M{"$pull": bson.M{field: value}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("category %s must keep at least %d teams", name, required)
+	}
+
+	slog.Info("Team removed from category", "category", name, "team", team)
+	return nil
+}
+
+// DeleteTeamCategory удаляет категорию, если ее не используют незавершенные турниры и шаблоны
+func DeleteTeamCategory(name string) error {
+	if _, err := getCategory(name); err != nil {
+		return err
+	}
+
+	tournaments, templates, err := categoryUsers(name)
+	if err != nil {
+		return err
+	}
+	if len(tournaments) > 0 {
+		return fmt.Errorf("category %s is used by tournament %s", name, tournaments[0].Name)
+	}
+	if len(templates) > 0 {
+		return fmt.Errorf("category %s is used by template %s", name, templates[0].Name)
+	}
+
+	if err := db.RemoveTeamCategory(name); err != nil {
+		return err
+	}
+
+	slog.Info("Team category deleted", "category", name)
+	return nil
+}
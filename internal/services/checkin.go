@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"time"
+	"tournament-bot/internal/db"
+)
+
+var ErrCheckInClosed = errors.New("check-in is not open")
+
+// CheckInResult — итог закрытия подтверждения участия
+type CheckInResult struct {
+	Tournament *db.Tournament
+	// Dropped — участники, не подтвердившие участие
+	Dropped []string
+	// Promoted — игроки из листа ожидания, получившие освободившиеся места
+	Promoted []string
+}
+
+// StartCheckIn открывает подтверждение участия на заданное время
+func StartCheckIn(tournamentID int, duration time.Duration) (*db.Tournament, error) {
+	filter := bson.M{
+		"id":                tournamentID,
+		"is_active":         false,
+		"setup_completed":   false,
+		"check_in_deadline": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{
+		"check_in_deadline": time.Now().Add(duration),
+		"checked_in":        []string{},
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var tournament db.Tournament
+	err := db.DB.Collection("tournaments").FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&tournament)
+	if err != nil {
+		return nil, errors.New("check-in has already been started or the tournament is running")
+	}
+
+	slog.Info("Check-in started", "tournament_id", tournamentID, "deadline", tournament.CheckInDeadline)
+	return &tournament, nil
+}
+
+// CheckIn отмечает, что участник (или игрок из листа ожидания) придет на турнир
+func CheckIn(tournamentID int, participantName string) error {
+	filter := bson.M{
+		"id":                tournamentID,
+		"is_active":         false,
+		"check_in_closed":   bson.M{"$ne": true},
+		"check_in_deadline": bson.M{"$gt": time.Now()},
+		"$or": []bson.M{
+			{"participants": participantName},
+			{"waitlist": participantName},
+		},
+	}
+	update := bson.M{"$addToSet": bson.M{"checked_in": participantName}}
+
+	result, err := db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	tournament, err := GetTournament(tournamentID)
+	if err != nil {
+		return err
+	}
+	if !tournament.HasParticipant(participantName) && !tournament.IsWaitlisted(participantName) {
+		return ErrNotRegistered
+	}
+	return ErrCheckInClosed
+}
+
+// CloseCheckIn убирает участников, не подтвердивших участие, и отдает освободившиеся места
+// подтвердившим игрокам из листа ожидания в порядке очереди. Неотметившиеся игроки
+// из листа ожидания остаются в нем после отметившихся.
+func CloseCheckIn(tournamentID int) (*CheckInResult, error) {
+	tournament, err := GetTournament(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	if !tournament.CheckInInProgress() || tournament.IsActive {
+		return nil, ErrCheckInClosed
+	}
+
+	result := &CheckInResult{}
+	participants := []string{}
+	for _, name := range tournament.Participants {
+		if tournament.IsCheckedIn(name) {
+			participants = append(participants, name)
+		} else {
+			result.Dropped = append(result.Dropped, name)
+		}
+	}
+
+	var readyWaitlist, restWaitlist []string
+	for _, name := range tournament.Waitlist {
+		if tournament.IsCheckedIn(name) {
+			readyWaitlist = append(readyWaitlist, name)
+		} else {
+			restWaitlist = append(restWaitlist, name)
+		}
+	}
+
+	for len(readyWaitlist) > 0 && len(participants) < tournament.MaxParticipants {
+		result.Promoted = append(result.Promoted, readyWaitlist[0])
+		participants = append(participants, readyWaitlist[0])
+		readyWaitlist = readyWaitlist[1:]
+	}
+	waitlist := append(readyWaitlist, restWaitlist...)
+
+	// Обновляем, только если списки не изменились с момента чтения
+	filter := bson.M{
+		"id":              tournamentID,
+		"check_in_closed": bson.M{"$ne": true},
+		"participants":    tournament.Participants,
+		"waitlist":        tournament.Waitlist,
+	}
+	update := bson.M{"$set": bson.M{
+		"participants":    participants,
+		"waitlist":        waitlist,
+		"check_in_closed": true,
+	}}
+	updated, err := db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return nil, err
+	}
+	if updated.MatchedCount == 0 {
+		return nil, errors.New("tournament changed while closing check-in, try again")
+	}
+
+	tournament.Participants = participants
+	tournament.Waitlist = waitlist
+	tournament.CheckInClosed = true
+	result.Tournament = tournament
+
+	slog.Info("Check-in closed", "tournament_id", tournamentID,
+		"dropped", result.Dropped, "promoted", result.Promoted)
+	return result, nil
+}
+
+// CloseExpiredCheckIns закрывает подтверждение участия во всех турнирах с истекшим сроком
+func CloseExpiredCheckIns() ([]*CheckInResult, error) {
+	filter := bson.M{
+		"is_active":         false,
+		"check_in_closed":   bson.M{"$ne": true},
+		"check_in_deadline": bson.M{"$lte": time.Now()},
+	}
+	cursor, err := db.DB.Collection("tournaments").Find(context.TODO(), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var tournaments []db.Tournament
+	if err := cursor.All(context.TODO(), &tournaments); err != nil {
+		return nil, err
+	}
+
+	var results []*CheckInResult
+	for _, tournament := range tournaments {
+		result, err := CloseCheckIn(tournament.ID)
+		if err != nil {
+			// Остальные турниры закрываем, этот повторим при следующем запуске
+			slog.Error("Error closing check-in", "tournament_id", tournament.ID, "err", err)
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,691 @@
+package services
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"tournament-bot/internal/db"
+)
+
+var (
+	// ErrInteractiveDraw — команды распределяются выбором игроков, а не жеребьевкой
+	ErrInteractiveDraw = errors.New("teams are picked by the players in this tournament")
+	// ErrDrawPublished — хэш жеребьевки уже опубликован для другого состава участников
+	ErrDrawPublished = errors.New("the draw commitment has already been published for a different list of participants")
+	// ErrNoDraw — жеребьевка турнира еще не зафиксирована
+	ErrNoDraw = errors.New("no draw has been committed for this tournament")
+)
+
+// Сколько последних турниров игрока учитывает жеребьевка без повторов
+var noRepeatTournaments = 3
+
+// SetNoRepeatTournaments задает, за сколько последних турниров игроку не выпадают прежние клубы
+func SetNoRepeatTournaments(n int) {
+	noRepeatTournaments = n
+}
+
+// teamDraw — данные одной жеребьевки
+type teamDraw struct {
+	participants []string
+	// teams — команды категории в порядке из справочника, сильнейшие первыми
+	teams []string
+	// ranking — участники по рейтингу сезона, сильнейшие первыми
+	ranking []string
+	// recent — клубы участников в их последних турнирах
+	recent map[string]map[string]bool
+	// skills и strengths — сила участников и рейтинги команд в порядке participants и teams
+	skills    []float64
+	strengths []float64
+	rng       *rand.Rand
+}
+
+// drawStrategy — способ распределения команд. У интерактивных способов нет assign:
+// команды выбирают сами игроки.
+// Входные данные, которые нужны способу, фиксируются в DrawRecord до жеребьевки.
+type drawStrategy struct {
+	label       string
+	assign      func(draw *teamDraw) (map[string]string, error)
+	needRanking bool
+	needRecent  bool
+	needBalance bool
+}
+
+var drawStrategies = map[string]drawStrategy{
+	db.DrawRandom:   {label: "random", assign: assignRandom},
+	db.DrawNoRepeat: {label: "no repeats", assign: assignNoRepeat, needRecent: true},
+	db.DrawPots:     {label: "pots by rating", assign: assignPots, needRanking: true},
+	db.DrawBalanced: {label: "balanced by skill", assign: assignBalanced, needBalance: true},
+	db.DrawDraft:    {label: "draft, weakest player picks first"},
+	db.DrawFreePick: {label: "free pick"},
+}
+
+// DrawStrategyLabel возвращает название способа распределения команд для сообщений
+func DrawStrategyLabel(strategy string) string {
+	if s, ok := drawStrategies[strategy]; ok {
+		return s.label
+	}
+	return strategy
+}
+
+// IsInteractiveDraw сообщает, что команды выбирают игроки
+func IsInteractiveDraw(strategy string) bool {
+	s, ok := drawStrategies[strategy]
+	return ok && s.assign == nil
+}
+
+// newDrawSeed выдает случайное зерно жеребьевки
+func newDrawSeed() (int64, error) {
+	var buf [8]byte
+	if _, err := cryptorand.Read(buf[:]); err != nil {
+		return 0, err
+	}
+	// Ноль означает "зерно не задано", поэтому используем только положительные значения
+	return int64(binary.BigEndian.Uint64(buf[:])>>1) | 1, nil
+}
+
+// drawRand создает генератор жеребьевки из зерна и ID турнира: одинаковые входные
+// данные всегда дают одинаковую последовательность
+func drawRand(tournamentID int, seed int64) *rand.Rand {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%d", tournamentID, seed)))
+	return rand.New(rand.NewPCG(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16])))
+}
+
+// drawInputs — входные данные жеребьевки в каноническом виде: поля в фиксированном
+// порядке, ключи Recent сортирует encoding/json. Их JSON хэшируется вместе с зерном.
+type drawInputs struct {
+	TournamentID int                 `json:"tournament_id"`
+	Strategy     string              `json:"strategy"`
+	Participants []string            `json:"participants"`
+	Teams        []string            `json:"teams"`
+	Ranking      []string            `json:"ranking,omitempty"`
+	Recent       map[string][]string `json:"recent,omitempty"`
+	Skills       []float64           `json:"skills,omitempty"`
+	Strengths    []float64           `json:"strengths,omitempty"`
+}
+
+// encodeDrawInputs возвращает канонический JSON входных данных из записи жеребьевки
+func encodeDrawInputs(tournamentID int, record *db.DrawRecord) (string, error) {
+	data, err := json.Marshal(drawInputs{
+		TournamentID: tournamentID,
+		Strategy:     record.Strategy,
+		Participants: record.Participants,
+		Teams:        record.Teams,
+		Ranking:      record.Ranking,
+		Recent:       record.Recent,
+		Skills:       record.Skills,
+		Strengths:    record.Strengths,
+	})
+	return string(data), err
+}
+
+// committedInputs разбирает входные данные, зафиксированные в записи жеребьевки
+func committedInputs(record *db.DrawRecord) (*drawInputs, error) {
+	if record.Inputs == "" {
+		return nil, errors.New("the draw record has no committed inputs")
+	}
+	var inputs drawInputs
+	if err := json.Unmarshal([]byte(record.Inputs), &inputs); err != nil {
+		return nil, fmt.Errorf("invalid committed draw inputs: %v", err)
+	}
+	if len(inputs.Participants) != len(record.Participants) {
+		return nil, errors.New("the participants differ from the committed draw inputs")
+	}
+	return &inputs, nil
+}
+
+// drawCommitment — хэш входных данных и зерна, который публикуется до жеребьевки.
+// Префикс отделяет его от ключа генератора в drawRand, чтобы по хэшу нельзя было
+// получить сам генератор.
+func drawCommitment(inputs string, seed int64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("tournament-draw\n%s\n%d", inputs, seed)))
+	return hex.EncodeToString(sum[:])
+}
+
+// CommitDraw фиксирует зерно и входные данные жеребьевки и возвращает турнир с
+// заполненным Draw. Пока хэш не опубликован (MarkDrawPublished), при изменении состава
+// участников или команд категории входные данные записываются заново с тем же зерном;
+// после публикации жеребьевка не фиксируется заново.
+func CommitDraw(tournamentID int) (*db.Tournament, error) {
+	tournament, err := GetTournament(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	if tournament.Draw != nil && !tournament.Draw.PublishedAt.IsZero() {
+		if slices.Equal(tournament.Draw.Participants, tournament.Participants) {
+			return tournament, nil
+		}
+		return nil, ErrDrawPublished
+	}
+
+	category, err := db.GetTeamCategoryByName(tournament.TeamCategory)
+	if err != nil {
+		return nil, err
+	}
+	if tournament.Draw != nil && slices.Equal(tournament.Draw.Participants, tournament.Participants) &&
+		slices.Equal(tournament.Draw.Teams, category.Teams) {
+		return tournament, nil
+	}
+	if tournament.IsActive {
+		return nil, errors.New("the tournament has already started")
+	}
+
+	strategy, ok := drawStrategies[tournament.Strategy()]
+	if !ok {
+		return nil, fmt.Errorf("unknown draw strategy: %s", tournament.Strategy())
+	}
+	if strategy.assign == nil {
+		return nil, ErrInteractiveDraw
+	}
+	if len(category.Teams) < len(tournament.Participants) {
+		return nil, fmt.Errorf("not enough teams for all participants")
+	}
+
+	seed := tournament.DrawSeed
+	if seed == 0 {
+		seed, err = newDrawSeed()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	record := &db.DrawRecord{
+		Strategy:     tournament.Strategy(),
+		CommittedAt:  time.Now(),
+		Participants: tournament.Participants,
+		Teams:        category.Teams,
+	}
+	if strategy.needRanking {
+		record.Ranking, err = seasonRanking(tournament.Participants)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if strategy.needBalance {
+		record.Skills, err = playerSkills(tournament.Participants)
+		if err != nil {
+			return nil, err
+		}
+		record.Strengths, err = teamStrengths(category.Teams)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if strategy.needRecent {
+		recent, err := recentTeams(tournament.Participants, noRepeatTournaments)
+		if err != nil {
+			return nil, err
+		}
+		record.Recent = make(map[string][]string)
+		for participant, teams := range recent {
+			for _, team := range category.Teams {
+				if teams[team] {
+					record.Recent[participant] = append(record.Recent[participant], team)
+				}
+			}
+		}
+	}
+
+	record.Inputs, err = encodeDrawInputs(tournamentID, record)
+	if err != nil {
+		return nil, err
+	}
+	record.Commitment = drawCommitment(record.Inputs, seed)
+
+	// Условие на прежнее зерно не дает двум одновременным вызовам зафиксировать разные зерна,
+	// а условие на публикацию — переписать уже опубликованную жеребьевку
+	filter := bson.M{"id": tournamentID, "draw_seed": tournament.DrawSeed, "draw.published_at": bson.M{"$exists": false}}
+	if tournament.DrawSeed == 0 {
+		filter["draw_seed"] = bson.M{"$exists": false}
+	}
+	update := bson.M{"$set": bson.M{"draw": record, "draw_seed": seed}}
+	result, err := db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		current, err := GetTournament(tournamentID)
+		if err != nil {
+			return nil, err
+		}
+		if current.Draw == nil || !slices.Equal(current.Draw.Participants, tournament.Participants) {
+			return nil, ErrDrawPublished
+		}
+		return current, nil
+	}
+
+	tournament.Draw = record
+	tournament.DrawSeed = seed
+	return tournament, nil
+}
+
+// MarkDrawPublished отмечает, что хэш жеребьевки опубликован: после этого CommitDraw
+// не перезапишет входные данные и зерно
+func MarkDrawPublished(tournamentID int, commitment string) error {
+	result, err := db.DB.Collection("tournaments").UpdateOne(context.TODO(),
+		bson.M{"id": tournamentID, "draw.commitment": commitment},
+		bson.M{"$set": bson.M{"draw.published_at": time.Now()}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("the draw commitment has changed")
+	}
+	return nil
+}
+
+// CancelDraw отменяет зафиксированную, но не проведенную жеребьевку: запись и зерно
+// удаляются, следующая жеребьевка фиксируется заново с новым зерном. Нужна, когда
+// после публикации хэша изменился состав участников. Возвращает отмененную запись.
+func CancelDraw(tournamentID int) (*db.DrawRecord, error) {
+	tournament, err := GetTournament(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	if tournament.IsActive || tournament.IsCompleted {
+		return nil, errors.New("the tournament has already started")
+	}
+	if tournament.Draw == nil {
+		return nil, ErrNoDraw
+	}
+
+	result, err := db.DB.Collection("tournaments").UpdateOne(context.TODO(),
+		bson.M{"id": tournamentID, "is_active": false, "draw.commitment": tournament.Draw.Commitment},
+		bson.M{"$unset": bson.M{"draw": "", "draw_seed": ""}})
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, errors.New("the draw has changed, please try again")
+	}
+	return tournament.Draw, nil
+}
+
+// runDraw повторяет жеребьевку по зафиксированным входным данным. Результат
+// возвращается по текущим именам участников: переименование меняет имена в записи
+// жеребьевки, но не их порядок и не зафиксированные данные.
+func runDraw(seed int64, record *db.DrawRecord) (map[string]string, error) {
+	inputs, err := committedInputs(record)
+	if err != nil {
+		return nil, err
+	}
+	strategy, ok := drawStrategies[inputs.Strategy]
+	if !ok || strategy.assign == nil {
+		return nil, fmt.Errorf("unknown draw strategy: %s", inputs.Strategy)
+	}
+
+	recent := make(map[string]map[string]bool)
+	for _, participant := range inputs.Participants {
+		recent[participant] = make(map[string]bool)
+		for _, team := range inputs.Recent[participant] {
+			recent[participant][team] = true
+		}
+	}
+
+	assigned, err := strategy.assign(&teamDraw{
+		participants: inputs.Participants,
+		teams:        inputs.Teams,
+		ranking:      inputs.Ranking,
+		recent:       recent,
+		skills:       inputs.Skills,
+		strengths:    inputs.Strengths,
+		rng:          drawRand(inputs.TournamentID, seed),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	participantTeams := make(map[string]string, len(assigned))
+	for i, participant := range inputs.Participants {
+		participantTeams[record.Participants[i]] = assigned[participant]
+	}
+	return participantTeams, nil
+}
+
+// PerformTeamDraw распределяет команды выбранной категории выбранным способом и создает
+// турнирную таблицу. Если жеребьевка уже зафиксирована через CommitDraw, используются
+// сохраненные зерно и входные данные. Возвращает пары в порядке списка участников.
+func PerformTeamDraw(tournamentID int) (string, error) {
+	tournament, err := CommitDraw(tournamentID)
+	if err != nil {
+		return "", err
+	}
+
+	participantTeams, err := runDraw(tournament.DrawSeed, tournament.Draw)
+	if err != nil {
+		return "", err
+	}
+
+	var drawResult strings.Builder
+	for _, participant := range tournament.Draw.Participants {
+		drawResult.WriteString(fmt.Sprintf("%s - %s\n", participant, participantTeams[participant]))
+	}
+
+	// Обновляем турнир в базе данных с новыми записями статистики команд и назначенными командами
+	filter := bson.M{"id": tournamentID}
+	update := bson.M{
+		"$set": bson.M{
+			"standings":         newStandings(participantTeams),
+			"participant_teams": participantTeams,
+		},
+	}
+	_, err = db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return "", err
+	}
+
+	return drawResult.String(), nil
+}
+
+// DrawVerification — результат проверки жеребьевки
+type DrawVerification struct {
+	Seed       int64
+	Commitment string
+	// CommitmentOK — опубликованный хэш совпадает с зерном и зафиксированными входными данными
+	CommitmentOK bool
+	// ResultOK — повтор жеребьевки дал те же пары, что записаны в турнире
+	ResultOK     bool
+	Participants []string
+	Expected     map[string]string
+}
+
+// VerifyDraw повторяет жеребьевку турнира по опубликованным данным. Зерно раскрывается
+// только после того, как команды распределены.
+func VerifyDraw(tournamentID int) (*DrawVerification, error) {
+	tournament, err := GetTournament(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	if tournament.Draw == nil {
+		return nil, errors.New("this tournament has no recorded draw")
+	}
+	if len(tournament.ParticipantTeams) == 0 {
+		return nil, errors.New("the draw has not been performed yet")
+	}
+
+	expected, err := runDraw(tournament.DrawSeed, tournament.Draw)
+	if err != nil {
+		return nil, err
+	}
+
+	resultOK := len(expected) == len(tournament.ParticipantTeams)
+	for participant, team := range expected {
+		resultOK = resultOK && tournament.ParticipantTeams[participant] == team
+	}
+
+	return &DrawVerification{
+		Seed:         tournament.DrawSeed,
+		Commitment:   tournament.Draw.Commitment,
+		CommitmentOK: drawCommitment(tournament.Draw.Inputs, tournament.DrawSeed) == tournament.Draw.Commitment,
+		ResultOK:     resultOK,
+		Participants: tournament.Draw.Participants,
+		Expected:     expected,
+	}, nil
+}
+
+// assignRandom перемешивает команды и раздает их в порядке списка участников
+func assignRandom(draw *teamDraw) (map[string]string, error) {
+	teams := make([]string, len(draw.teams))
+	copy(teams, draw.teams)
+	draw.rng.Shuffle(len(teams), func(i, j int) {
+		teams[i], teams[j] = teams[j], teams[i]
+	})
+
+	participantTeams := make(map[string]string)
+	for i, participant := range draw.participants {
+		participantTeams[participant] = teams[i]
+	}
+	return participantTeams, nil
+}
+
+// assignNoRepeat ищет случайное распределение, в котором никому не достается клуб
+// из его последних турниров. Если такого нет, повторы получают только те, кому
+// не хватило других команд.
+func assignNoRepeat(draw *teamDraw) (map[string]string, error) {
+	recent := draw.recent
+
+	participants := make([]string, len(draw.participants))
+	copy(participants, draw.participants)
+	draw.rng.Shuffle(len(participants), func(i, j int) {
+		participants[i], participants[j] = participants[j], participants[i]
+	})
+	teams := make([]string, len(draw.teams))
+	copy(teams, draw.teams)
+	draw.rng.Shuffle(len(teams), func(i, j int) {
+		teams[i], teams[j] = teams[j], teams[i]
+	})
+
+	// Паросочетание Куна: participant -> team без недавних клубов
+	owner := make(map[string]string)
+	var tryAssign func(participant string, visited map[string]bool) bool
+	tryAssign = func(participant string, visited map[string]bool) bool {
+		for _, team := range teams {
+			if visited[team] || recent[participant][team] {
+				continue
+			}
+			visited[team] = true
+			if current, taken := owner[team]; !taken || tryAssign(current, visited) {
+				owner[team] = participant
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, participant := range participants {
+		tryAssign(participant, make(map[string]bool))
+	}
+
+	participantTeams := make(map[string]string)
+	for team, participant := range owner {
+		participantTeams[participant] = team
+	}
+
+	// Оставшимся без команды раздаем свободные команды, даже если это повтор
+	var free []string
+	for _, team := range teams {
+		if _, taken := owner[team]; !taken {
+			free = append(free, team)
+		}
+	}
+	for _, participant := range participants {
+		if _, ok := participantTeams[participant]; !ok {
+			participantTeams[participant] = free[0]
+			free = free[1:]
+		}
+	}
+	return participantTeams, nil
+}
+
+// assignPots делит команды категории на корзины по порядку в справочнике (сильнейшие
+// первыми), по корзине на участника. Сильнейший по рейтингу сезона тянет команду
+// из самой слабой корзины, слабейший — из самой сильной.
+func assignPots(draw *teamDraw) (map[string]string, error) {
+	ranking := draw.ranking
+
+	n := len(ranking)
+	participantTeams := make(map[string]string)
+	for i, participant := range ranking {
+		pot := n - 1 - i
+		from, to := pot*len(draw.teams)/n, (pot+1)*len(draw.teams)/n
+		participantTeams[participant] = draw.teams[from+draw.rng.IntN(to-from)]
+	}
+	return participantTeams, nil
+}
+
+// recentTeams возвращает клубы каждого участника в его последних завершенных турнирах
+func recentTeams(participants []string, limit int) (map[string]map[string]bool, error) {
+	recent := make(map[string]map[string]bool)
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(int64(limit)).
+		SetProjection(bson.M{"participant_teams": 1})
+
+	for _, participant := range participants {
+		recent[participant] = make(map[string]bool)
+		if limit <= 0 {
+			continue
+		}
+
+		filter := bson.M{"participants": participant, "is_completed": true}
+		cursor, err := db.DB.Collection("tournaments").Find(context.TODO(), filter, opts)
+		if err != nil {
+			return nil, err
+		}
+		var tournaments []db.Tournament
+		if err := cursor.All(context.TODO(), &tournaments); err != nil {
+			return nil, err
+		}
+		for _, tournament := range tournaments {
+			if team := tournament.ParticipantTeams[participant]; team != "" {
+				recent[participant][team] = true
+			}
+		}
+	}
+	return recent, nil
+}
+
+// seasonRanking сортирует участников по рейтингу сезона, сильнейшие первыми:
+// по очкам, затем по разнице побед и поражений, затем по имени
+func seasonRanking(participants []string) ([]string, error) {
+	stats := make(map[string]db.ParticipantStats)
+	for _, name := range participants {
+		participant, err := db.FindParticipant(name)
+		if err != nil {
+			return nil, err
+		}
+		if participant != nil {
+			stats[name] = participant.Stats
+		}
+	}
+
+	ranking := make([]string, len(participants))
+	copy(ranking, participants)
+	sort.SliceStable(ranking, func(i, j int) bool {
+		a, b := stats[ranking[i]], stats[ranking[j]]
+		if a.TotalPoints != b.TotalPoints {
+			return a.TotalPoints > b.TotalPoints
+		}
+		if a.Wins-a.Losses != b.Wins-b.Losses {
+			return a.Wins-a.Losses > b.Wins-b.Losses
+		}
+		return ranking[i] < ranking[j]
+	})
+	return ranking, nil
+}
+
+// StartDraft задает очередность драфта: игроки выбирают по одной команде за один проход
+// в обратном порядке рейтинга сезона, первым — самый слабый.
+func StartDraft(tournamentID int) (*db.Tournament, error) {
+	tournament, err := GetTournament(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	if tournament.Strategy() != db.DrawDraft {
+		return nil, errors.New("this tournament does not use the draft")
+	}
+	if tournament.IsActive {
+		return nil, errors.New("the tournament has already started")
+	}
+	if tournament.TeamCategory == "" {
+		return nil, errors.New("tournament team category is not set")
+	}
+
+	ranking, err := seasonRanking(tournament.Participants)
+	if err != nil {
+		return nil, err
+	}
+	order := make([]string, 0, len(ranking))
+	for i := len(ranking) - 1; i >= 0; i-- {
+		order = append(order, ranking[i])
+	}
+
+	update := bson.M{"$set": bson.M{
+		"draft_order":       order,
+		"participant_teams": map[string]string{},
+	}}
+	_, err = db.DB.Collection("tournaments").UpdateOne(context.TODO(), bson.M{"id": tournamentID, "is_active": false}, update)
+	if err != nil {
+		return nil, err
+	}
+
+	tournament.DraftOrder = order
+	tournament.ParticipantTeams = map[string]string{}
+	return tournament, nil
+}
+
+// DraftPicker возвращает игрока, чья очередь выбирать, или пустую строку, если драфт завершен
+func DraftPicker(tournament *db.Tournament) string {
+	for _, participant := range tournament.DraftOrder {
+		if _, picked := tournament.ParticipantTeams[participant]; !picked {
+			return participant
+		}
+	}
+	return ""
+}
+
+// DraftAvailableTeams возвращает команды категории, которые еще никто не выбрал
+func DraftAvailableTeams(tournament *db.Tournament) ([]string, error) {
+	category, err := db.GetTeamCategoryByName(tournament.TeamCategory)
+	if err != nil {
+		return nil, err
+	}
+
+	var available []string
+	for _, team := range category.Teams {
+		if !tournament.HasTeam(team) {
+			available = append(available, team)
+		}
+	}
+	return available, nil
+}
+
+// DraftPick записывает выбор игрока, чья сейчас очередь. Условие на отсутствие выбора
+// у игрока не дает двум одновременным нажатиям записать две команды.
+func DraftPick(tournamentID int, participantName, team string) (*db.Tournament, error) {
+	tournament, err := GetTournament(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	if DraftPicker(tournament) != participantName {
+		return nil, errors.New("it is not this player's turn")
+	}
+
+	available, err := DraftAvailableTeams(tournament)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, a := range available {
+		found = found || a == team
+	}
+	if !found {
+		return nil, fmt.Errorf("%s is not available", team)
+	}
+
+	filter := bson.M{
+		"id":                                   tournamentID,
+		"is_active":                            false,
+		"participant_teams." + participantName: bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"participant_teams." + participantName: team}}
+	result, err := db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, errors.New("this pick has already been made")
+	}
+
+	tournament.ParticipantTeams[participantName] = team
+	return tournament, nil
+}
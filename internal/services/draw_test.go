@@ -0,0 +1,215 @@
+package services
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+	"tournament-bot/internal/db"
+)
+
+// committedRecord собирает запись жеребьевки так же, как CommitDraw
+func committedRecord(t *testing.T, tournamentID int, record db.DrawRecord, seed int64) *db.DrawRecord {
+	t.Helper()
+	inputs, err := encodeDrawInputs(tournamentID, &record)
+	if err != nil {
+		t.Fatalf("encodeDrawInputs: %v", err)
+	}
+	record.Inputs = inputs
+	record.Commitment = drawCommitment(inputs, seed)
+	return &record
+}
+
+func TestDrawCommitmentCoversInputs(t *testing.T) {
+	base := db.DrawRecord{
+		Strategy:     db.DrawNoRepeat,
+		Participants: []string{"Ann", "Bob", "Cid"},
+		Teams:        []string{"Arsenal", "Barcelona", "Chelsea", "Dortmund"},
+		Recent:       map[string][]string{"Cid": {"Chelsea"}, "Ann": {"Arsenal", "Dortmund"}},
+	}
+	const seed = 42
+	commitment := committedRecord(t, 7, base, seed).Commitment
+
+	// Одинаковые данные дают одинаковый хэш независимо от порядка заполнения карты
+	same := base
+	same.Recent = map[string][]string{"Ann": {"Arsenal", "Dortmund"}, "Cid": {"Chelsea"}}
+	if got := committedRecord(t, 7, same, seed).Commitment; got != commitment {
+		t.Fatalf("commitment of equal inputs differs: %s != %s", got, commitment)
+	}
+
+	tests := []struct {
+		name         string
+		tournamentID int
+		seed         int64
+		change       func(record *db.DrawRecord)
+	}{
+		{name: "seed", tournamentID: 7, seed: seed + 1},
+		{name: "tournament", tournamentID: 8, seed: seed},
+		{name: "participants order", tournamentID: 7, seed: seed, change: func(r *db.DrawRecord) {
+			r.Participants = []string{"Bob", "Ann", "Cid"}
+		}},
+		{name: "teams", tournamentID: 7, seed: seed, change: func(r *db.DrawRecord) {
+			r.Teams = []string{"Arsenal", "Barcelona", "Chelsea", "Everton"}
+		}},
+		{name: "strategy", tournamentID: 7, seed: seed, change: func(r *db.DrawRecord) {
+			r.Strategy = db.DrawRandom
+		}},
+		{name: "ranking", tournamentID: 7, seed: seed, change: func(r *db.DrawRecord) {
+			r.Ranking = []string{"Cid", "Bob", "Ann"}
+		}},
+		{name: "recent teams", tournamentID: 7, seed: seed, change: func(r *db.DrawRecord) {
+			r.Recent = map[string][]string{"Cid": {"Chelsea"}}
+		}},
+		{name: "team strengths", tournamentID: 7, seed: seed, change: func(r *db.DrawRecord) {
+			r.Strengths = []float64{80, 79, 78, 77}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := base
+			if tt.change != nil {
+				tt.change(&record)
+			}
+			if got := committedRecord(t, tt.tournamentID, record, tt.seed).Commitment; got == commitment {
+				t.Errorf("changing the %s did not change the commitment", tt.name)
+			}
+		})
+	}
+}
+
+func TestRunDrawAfterRename(t *testing.T) {
+	record := committedRecord(t, 3, db.DrawRecord{
+		Strategy:     db.DrawRandom,
+		Participants: []string{"Ann", "Bob", "Cid"},
+		Teams:        []string{"Arsenal", "Barcelona", "Chelsea"},
+	}, 99)
+
+	before, err := runDraw(99, record)
+	if err != nil {
+		t.Fatalf("runDraw: %v", err)
+	}
+
+	// Переименование переписывает имена в записи, но не зафиксированные данные
+	record.Participants = []string{"Ann", "Robert", "Cid"}
+	after, err := runDraw(99, record)
+	if err != nil {
+		t.Fatalf("runDraw after rename: %v", err)
+	}
+
+	want := maps.Clone(before)
+	want["Robert"] = want["Bob"]
+	delete(want, "Bob")
+	if !maps.Equal(after, want) {
+		t.Errorf("after rename = %v, want %v", after, want)
+	}
+	if drawCommitment(record.Inputs, 99) != record.Commitment {
+		t.Error("rename broke the commitment")
+	}
+}
+
+func TestDrawStrategies(t *testing.T) {
+	participants := []string{"Ann", "Bob", "Cid", "Dan"}
+	teams := []string{"Arsenal", "Barcelona", "Chelsea", "Dortmund", "Everton", "Fulham", "Genoa", "Hertha"}
+
+	tests := []struct {
+		name     string
+		strategy string
+		recent   map[string][]string
+		ranking  []string
+		// check дополнительно проверяет распределение
+		check func(t *testing.T, assigned map[string]string)
+	}{
+		{name: "random", strategy: db.DrawRandom},
+		{
+			name:     "no repeats avoids recent teams",
+			strategy: db.DrawNoRepeat,
+			recent: map[string][]string{
+				"Ann": {"Arsenal", "Barcelona", "Chelsea", "Dortmund", "Everton", "Fulham", "Genoa"},
+				"Bob": {"Hertha", "Genoa"},
+				"Cid": {"Arsenal"},
+			},
+			check: func(t *testing.T, assigned map[string]string) {
+				if assigned["Ann"] != "Hertha" {
+					t.Errorf("Ann got %s, the only team without a repeat is Hertha", assigned["Ann"])
+				}
+				for participant, team := range map[string]string{"Bob": "Genoa", "Cid": "Arsenal"} {
+					if assigned[participant] == team {
+						t.Errorf("%s got recent team %s", participant, team)
+					}
+				}
+			},
+		},
+		{
+			name:     "no repeats falls back to a repeat",
+			strategy: db.DrawNoRepeat,
+			recent: map[string][]string{
+				"Ann": teams, "Bob": teams, "Cid": teams, "Dan": teams,
+			},
+		},
+		{
+			name:     "pots give the leader the weakest pot",
+			strategy: db.DrawPots,
+			ranking:  []string{"Cid", "Ann", "Dan", "Bob"},
+			check: func(t *testing.T, assigned map[string]string) {
+				pots := map[string][]string{
+					"Cid": {"Genoa", "Hertha"}, "Ann": {"Everton", "Fulham"},
+					"Dan": {"Chelsea", "Dortmund"}, "Bob": {"Arsenal", "Barcelona"},
+				}
+				for participant, pot := range pots {
+					if !slices.Contains(pot, assigned[participant]) {
+						t.Errorf("%s got %s, want a team from %v", participant, assigned[participant], pot)
+					}
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := db.DrawRecord{
+				Strategy:     tt.strategy,
+				Participants: participants,
+				Teams:        teams,
+				Ranking:      tt.ranking,
+				Recent:       tt.recent,
+			}
+			outcomes := make(map[string]bool)
+			for seed := int64(1); seed <= 20; seed++ {
+				committed := committedRecord(t, 5, record, seed)
+				assigned, err := runDraw(seed, committed)
+				if err != nil {
+					t.Fatalf("runDraw: %v", err)
+				}
+
+				// Одинаковое зерно всегда дает одинаковый результат
+				again, err := runDraw(seed, committed)
+				if err != nil {
+					t.Fatalf("runDraw: %v", err)
+				}
+				if !maps.Equal(assigned, again) {
+					t.Fatalf("seed %d: draw is not reproducible: %v != %v", seed, assigned, again)
+				}
+
+				if len(assigned) != len(participants) {
+					t.Fatalf("seed %d: assignment = %v, want a team for every participant", seed, assigned)
+				}
+				used := make(map[string]bool)
+				for _, participant := range participants {
+					team := assigned[participant]
+					if !slices.Contains(teams, team) || used[team] {
+						t.Fatalf("seed %d: assignment = %v, want distinct teams of the category", seed, assigned)
+					}
+					used[team] = true
+				}
+				if tt.check != nil {
+					tt.check(t, assigned)
+				}
+				outcomes[fmt.Sprint(assigned)] = true
+			}
+			if len(outcomes) < 2 {
+				t.Errorf("all seeds gave the same draw %v", outcomes)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"log/slog"
+	"strings"
+	"tournament-bot/internal/db"
+)
+
+// maxTeamNameLength ограничивает название команды: оно передается в данных кнопок
+const maxTeamNameLength = 40
+
+// PickTeam закрепляет команду за участником турнира со свободным выбором.
+// Если у турнира есть категория, команду можно взять только из нее.
+// Одну команду не могут выбрать двое участников.
+func PickTeam(tournamentID int, participantName, team string) error {
+	team = strings.TrimSpace(team)
+	if team == "" || len(team) > maxTeamNameLength || strings.Contains(team, "_") {
+		return fmt.Errorf("team name must be 1-%d characters long without underscores", maxTeamNameLength)
+	}
+
+	tournament, err := GetTournament(tournamentID)
+	if err != nil {
+		return err
+	}
+	if tournament.Strategy() != db.DrawFreePick {
+		return errors.New("teams in this tournament are assigned by the draw")
+	}
+	if tournament.IsActive || tournament.SetupCompleted {
+		return errors.New("the tournament has already started")
+	}
+	if !tournament.HasParticipant(participantName) {
+		return ErrNotRegistered
+	}
+	if tournament.TeamCategory != "" {
+		category, err := db.GetTeamCategoryByName(tournament.TeamCategory)
+		if err != nil {
+			return err
+		}
+		canonical := ""
+		for _, categoryTeam := range category.Teams {
+			if strings.EqualFold(categoryTeam, team) {
+				canonical = categoryTeam
+			}
+		}
+		if canonical == "" {
+			return fmt.Errorf("%s is not in category %s", team, tournament.TeamCategory)
+		}
+		team = canonical
+	}
+	// Команды игроков, ушедших из турнира, снова свободны
+	for participant, taken := range tournament.ParticipantTeams {
+		if participant != participantName && tournament.HasParticipant(participant) && strings.EqualFold(taken, team) {
+			return fmt.Errorf("%s has already been picked by %s", taken, participant)
+		}
+	}
+
+	// Выбор остальных участников не должен измениться с момента чтения,
+	// иначе двое могли бы одновременно взять одну команду
+	filter := bson.M{"id": tournamentID, "is_active": false}
+	for _, participant := range tournament.Participants {
+		if participant == participantName {
+			continue
+		}
+		if taken, ok := tournament.ParticipantTeams[participant]; ok {
+			filter["participant_teams."+participant] = taken
+		} else {
+			filter["participant_teams."+participant] = bson.M{"$exists": false}
+		}
+	}
+	result, err := db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter,
+		bson.M{"$set": bson.M{"participant_teams." + participantName: team}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("team choices changed, try again")
+	}
+
+	slog.Info("Team picked", "tournament_id", tournamentID, "participant", participantName, "team", team)
+	return nil
+}
+
+// CompleteTeamPicks проверяет, что все участники выбрали команды, и создает турнирную таблицу.
+// Команды ушедших из турнира игроков отбрасываются. Возвращает список участников с командами.
+func CompleteTeamPicks(tournamentID int) (string, error) {
+	tournament, err := GetTournament(tournamentID)
+	if err != nil {
+		return "", err
+	}
+
+	var missing []string
+	var result strings.Builder
+	participantTeams := make(map[string]string)
+	for _, participant := range tournament.Participants {
+		team, ok := tournament.ParticipantTeams[participant]
+		if !ok {
+			missing = append(missing, participant)
+			continue
+		}
+		participantTeams[participant] = team
+		result.WriteString(fmt.Sprintf("%s - %s\n", participant, team))
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("waiting for team picks from: %s", strings.Join(missing, ", "))
+	}
+
+	update := bson.M{"$set": bson.M{
+		"standings":         newStandings(participantTeams),
+		"participant_teams": participantTeams,
+	}}
+	_, err = db.DB.Collection("tournaments").UpdateOne(context.TODO(), bson.M{"id": tournamentID}, update)
+	if err != nil {
+		return "", err
+	}
+
+	return result.String(), nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+	"tournament-bot/internal/db"
+)
+
+// Сколько последних встреч показывать в личном противостоянии
+const headToHeadRecent = 5
+
+// Meeting — матч между двумя игроками; счет и команды указаны с точки зрения первого игрока
+type Meeting struct {
+	TournamentID   int       `json:"tournament_id"`
+	TournamentName string    `json:"tournament_name"`
+	Stage          string    `json:"stage"`
+	Date           time.Time `json:"date"`
+	Team1          string    `json:"team1"`
+	Team2          string    `json:"team2"`
+	Score1         int       `json:"score1"`
+	Score2         int       `json:"score2"`
+	Status         string    `json:"status"`
+	// Winner — 1 или 2 по номеру игрока, 0 — ничья
+	Winner int `json:"winner"`
+}
+
+// HeadToHead — личные встречи двух игроков во всех турнирах
+type HeadToHead struct {
+	Player1 string `json:"player1"`
+	Player2 string `json:"player2"`
+	Matches int    `json:"matches"`
+	Wins1   int    `json:"wins1"`
+	Draws   int    `json:"draws"`
+	Wins2   int    `json:"wins2"`
+	// Голы технических результатов не учитываются, как и в статистике игроков
+	Goals1 int `json:"goals1"`
+	Goals2 int `json:"goals2"`
+	// BiggestWin1 и BiggestWin2 — крупнейшие победы каждого игрока, nil — побед нет
+	BiggestWin1 *Meeting `json:"biggest_win1,omitempty"`
+	BiggestWin2 *Meeting `json:"biggest_win2,omitempty"`
+	// Recent — последние встречи, новые первыми
+	Recent []Meeting `json:"recent"`
+}
+
+// GetHeadToHead собирает все матчи группового этапа и плей-офф, в которых игроки
+// встречались друг с другом. Команды игроков в каждом турнире берутся из
+// ParticipantTeams с учетом прежних имен.
+func GetHeadToHead(player1, player2 *db.Participant) (*HeadToHead, error) {
+	names1 := append([]string{player1.Name}, player1.Aliases...)
+	names2 := append([]string{player2.Name}, player2.Aliases...)
+	filter := bson.M{
+		"setup_completed": true,
+		"$and": []bson.M{
+			{"participants": bson.M{"$in": names1}},
+			{"participants": bson.M{"$in": names2}},
+		},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}})
+	cursor, err := db.DB.Collection("tournaments").Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var tournaments []db.Tournament
+	if err := cursor.All(context.TODO(), &tournaments); err != nil {
+		return nil, err
+	}
+
+	h2h := &HeadToHead{Player1: player1.Name, Player2: player2.Name, Recent: []Meeting{}}
+	var meetings []Meeting
+	for i := range tournaments {
+		meetings = append(meetings, tournamentMeetings(&tournaments[i], names1, names2)...)
+	}
+
+	for i := range meetings {
+		meeting := &meetings[i]
+		h2h.Matches++
+		if meeting.Status == db.MatchPlayed {
+			h2h.Goals1 += meeting.Score1
+			h2h.Goals2 += meeting.Score2
+		}
+		switch meeting.Winner {
+		case 1:
+			h2h.Wins1++
+			h2h.BiggestWin1 = biggerWin(h2h.BiggestWin1, meeting)
+		case 2:
+			h2h.Wins2++
+			h2h.BiggestWin2 = biggerWin(h2h.BiggestWin2, meeting)
+		default:
+			h2h.Draws++
+		}
+	}
+	for i := len(meetings) - 1; i >= 0 && len(h2h.Recent) < headToHeadRecent; i-- {
+		h2h.Recent = append(h2h.Recent, meetings[i])
+	}
+
+	return h2h, nil
+}
+
+type stageMatches struct {
+	name    string
+	matches []db.Match
+}
+
+// tournamentMeetings возвращает матчи игроков друг с другом в турнире в порядке игры
+func tournamentMeetings(tournament *db.Tournament, names1, names2 []string) []Meeting {
+	team1, team2 := playerTeam(tournament, names1), playerTeam(tournament, names2)
+	if team1 == "" || team2 == "" {
+		return nil
+	}
+
+	stages := []stageMatches{{"group", tournament.Matches}}
+	if playoff := tournament.Playoff; playoff != nil {
+		stages = append(stages, stageMatches{"quarter", playoff.QuarterFinals}, stageMatches{"semi", playoff.SemiFinals})
+		if playoff.Final != nil {
+			stages = append(stages, stageMatches{"final", []db.Match{*playoff.Final}})
+		}
+	}
+
+	var meetings []Meeting
+	for _, stage := range stages {
+		for _, match := range stage.matches {
+			if !match.Counted || !match.Decided() {
+				continue
+			}
+			meeting := Meeting{
+				TournamentID:   tournament.ID,
+				TournamentName: tournament.Name,
+				Stage:          stage.name,
+				Date:           match.Date,
+				Status:         match.StatusOrPlayed(),
+			}
+			// У старых матчей нет даты, для них указывается дата турнира
+			if meeting.Date.IsZero() {
+				meeting.Date = tournament.CreatedAt
+			}
+			switch {
+			case match.Team1 == team1 && match.Team2 == team2:
+				meeting.Team1, meeting.Team2 = match.Team1, match.Team2
+				meeting.Score1, meeting.Score2 = match.Score1, match.Score2
+			case match.Team1 == team2 && match.Team2 == team1:
+				meeting.Team1, meeting.Team2 = match.Team2, match.Team1
+				meeting.Score1, meeting.Score2 = match.Score2, match.Score1
+			default:
+				continue
+			}
+			switch match.WinnerTeam() {
+			case team1:
+				meeting.Winner = 1
+			case team2:
+				meeting.Winner = 2
+			}
+			meetings = append(meetings, meeting)
+		}
+	}
+	return meetings
+}
+
+// biggerWin выбирает победу с большей разницей мячей; при равной — более позднюю
+func biggerWin(current, candidate *Meeting) *Meeting {
+	if candidate.Status != db.MatchPlayed {
+		return current
+	}
+	margin := func(m *Meeting) int {
+		if m.Score1 > m.Score2 {
+			return m.Score1 - m.Score2
+		}
+		return m.Score2 - m.Score1
+	}
+	if current == nil || margin(candidate) >= margin(current) {
+		return candidate
+	}
+	return current
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"log/slog"
+	"time"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/notifications"
+)
+
+// applyMatchToStandings добавляет (sign = 1) или вычитает (sign = -1) результат матча
+// из строк турнирной таблицы, начисляя очки по правилам турнира. Несостоявшиеся матчи
+// таблицу не меняют, за неявку начисляются победа и очки без голов, техническое
+// поражение учитывается со своим счетом.
+func applyMatchToStandings(standing1, standing2 *db.Standing, match db.Match, rules db.PointsRules, sign int) {
+	if standing1 == nil || standing2 == nil || !match.Decided() {
+		return
+	}
+
+	standing1.Played += sign
+	standing2.Played += sign
+
+	if match.StatusOrPlayed() != db.MatchWalkover {
+		standing1.GoalsFor += sign * match.Score1
+		standing1.GoalsAgainst += sign * match.Score2
+		standing2.GoalsFor += sign * match.Score2
+		standing2.GoalsAgainst += sign * match.Score1
+
+		standing1.GoalsDifference = standing1.GoalsFor - standing1.GoalsAgainst
+		standing2.GoalsDifference = standing2.GoalsFor - standing2.GoalsAgainst
+	}
+
+	switch match.WinnerTeam() {
+	case match.Team1:
+		standing1.Won += sign
+		standing1.Points += rules.Win * sign
+		standing2.Lost += sign
+		standing2.Points += rules.Loss * sign
+	case match.Team2:
+		standing1.Lost += sign
+		standing1.Points += rules.Loss * sign
+		standing2.Won += sign
+		standing2.Points += rules.Win * sign
+	default:
+		standing1.Drawn += sign
+		standing1.Points += rules.Draw * sign
+		standing2.Drawn += sign
+		standing2.Points += rules.Draw * sign
+	}
+}
+
+// matchTotals — личная статистика участника по матчам турнира
+type matchTotals struct {
+	goalsScored, goalsConceded int
+	wins, losses, draws        int
+	matchesPlayed              int
+}
+
+// add учитывает матч команды участника. Засчитываются только учтенные матчи
+// с определенным исходом; голы технических результатов в личную статистику не идут.
+func (t *matchTotals) add(match db.Match, team string) {
+	if team == "" || (match.Team1 != team && match.Team2 != team) || !match.Counted || !match.Decided() {
+		return
+	}
+
+	t.matchesPlayed++
+	if match.StatusOrPlayed() == db.MatchPlayed {
+		if match.Team1 == team {
+			t.goalsScored += match.Score1
+			t.goalsConceded += match.Score2
+		} else {
+			t.goalsScored += match.Score2
+			t.goalsConceded += match.Score1
+		}
+	}
+
+	switch match.WinnerTeam() {
+	case "":
+		t.draws++
+	case team:
+		t.wins++
+	default:
+		t.losses++
+	}
+}
+
+// newStatusMatch создает матч с нестандартным исходом. При техническом поражении
+// и неявке победа присуждается team1.
+func newStatusMatch(team1, team2, status, reason string) (db.Match, error) {
+	match := db.Match{
+		Team1:  team1,
+		Team2:  team2,
+		Status: status,
+		Reason: reason,
+		Date:   time.Now(),
+	}
+
+	switch status {
+	case db.MatchForfeit:
+		match.Score1, match.Score2 = forfeitScore[0], forfeitScore[1]
+		match.Winner = team1
+	case db.MatchWalkover:
+		match.Winner = team1
+	case db.MatchAbandoned, db.MatchPostponed:
+	default:
+		return match, fmt.Errorf("unknown match status: %s", status)
+	}
+	return match, nil
+}
+
+// checkMatchPairing проверяет, что команды сейчас могут играть между собой: в плей-офф —
+// что это пара текущей стадии, в групповом этапе — что они сыграли еще не все круги
+func checkMatchPairing(tournament *db.Tournament, team1, team2 string) error {
+	if tournament.Playoff != nil {
+		teams := GetCurrentStageTeams(tournament)
+		if len(teams) < 2 || !(teams[0] == team1 && teams[1] == team2 || teams[0] == team2 && teams[1] == team1) {
+			return fmt.Errorf("%s vs %s is not the current playoff match", team1, team2)
+		}
+		return nil
+	}
+	if tournament.MatchesBetween(team1, team2) >= tournament.Rounds() {
+		return fmt.Errorf("%s and %s have already played all their group matches", team1, team2)
+	}
+	return nil
+}
+
+// RecordMatchStatus записывает матч без обычного счета: техническое поражение, неявку,
+// прерванный или перенесенный матч. В групповом этапе матч добавляется в список,
+// в плей-офф — записывается в текущую стадию. Возвращает записанный матч и стадию плей-офф
+// (пустую для группового этапа).
+func RecordMatchStatus(tournamentID int, team1, team2, status, reason string) (*db.Match, string, error) {
+	tournament, err := GetTournament(tournamentID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !tournament.IsActive {
+		return nil, "", errors.New("the tournament is not active")
+	}
+
+	err = checkMatchPairing(tournament, team1, team2)
+	if err != nil {
+		return nil, "", err
+	}
+
+	match, err := newStatusMatch(team1, team2, status, reason)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if tournament.Playoff != nil {
+		stage, err := recordPlayoffMatch(tournament, match)
+		if err != nil {
+			return nil, "", err
+		}
+		match.Counted = true
+		return &match, stage, nil
+	}
+
+	_, err = db.DB.Collection("tournaments").UpdateOne(context.TODO(),
+		bson.M{"id": tournamentID},
+		bson.M{"$push": bson.M{"matches": match}})
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = updateStandings(tournamentID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	updated, err := GetTournament(tournamentID)
+	if err == nil {
+		err = notifications.SendMatchResultMessage(updated, &match)
+	}
+	if err != nil {
+		slog.Error("Error sending match result message", "tournament_id", tournamentID, "err", err)
+	}
+
+	return &match, "", nil
+}
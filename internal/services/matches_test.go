@@ -0,0 +1,75 @@
+package services
+
+import (
+	"testing"
+	"tournament-bot/internal/db"
+)
+
+func TestCheckMatchPairing(t *testing.T) {
+	played := db.Match{Team1: "Arsenal", Team2: "Barcelona", Score1: 1, Score2: 0}
+	postponed := db.Match{Team1: "Arsenal", Team2: "Barcelona", Status: db.MatchPostponed}
+	semi := &db.Playoff{
+		CurrentStage: "semi",
+		SemiFinals:   []db.Match{{Team1: "Chelsea", Team2: "Arsenal"}},
+	}
+
+	tests := []struct {
+		name         string
+		tournament   db.Tournament
+		team1, team2 string
+		wantErr      bool
+	}{
+		{
+			name:  "group pair not played yet",
+			team1: "Arsenal", team2: "Barcelona",
+		},
+		{
+			name:       "group pair already played",
+			tournament: db.Tournament{Matches: []db.Match{played}},
+			team1:      "Barcelona", team2: "Arsenal",
+			wantErr: true,
+		},
+		{
+			name:       "postponed match can still be recorded",
+			tournament: db.Tournament{Matches: []db.Match{postponed}},
+			team1:      "Arsenal", team2: "Barcelona",
+		},
+		{
+			name:       "second leg of a double round robin",
+			tournament: db.Tournament{Format: db.FormatDoubleRoundRobin, Matches: []db.Match{played}},
+			team1:      "Barcelona", team2: "Arsenal",
+		},
+		{
+			name:       "both legs of a double round robin played",
+			tournament: db.Tournament{Format: db.FormatDoubleRoundRobin, Matches: []db.Match{played, played}},
+			team1:      "Arsenal", team2: "Barcelona",
+			wantErr: true,
+		},
+		{
+			name:       "current playoff pair in any order",
+			tournament: db.Tournament{Playoff: semi},
+			team1:      "Arsenal", team2: "Chelsea",
+		},
+		{
+			name:       "playoff pair from another stage",
+			tournament: db.Tournament{Playoff: semi},
+			team1:      "Arsenal", team2: "Barcelona",
+			wantErr: true,
+		},
+		{
+			name:       "playoff without a current match",
+			tournament: db.Tournament{Playoff: &db.Playoff{CurrentStage: "final"}},
+			team1:      "Arsenal", team2: "Chelsea",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMatchPairing(&tt.tournament, tt.team1, tt.team2)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkMatchPairing() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"tournament-bot/internal/db"
+)
+
+// RenameParticipant меняет имя участника и переписывает его во всех турнирах.
+// Старое имя сохраняется в aliases, чтобы участника можно было найти по нему.
+func RenameParticipant(id, newName string) error {
+	participant, err := db.GetParticipantByID(id)
+	if err != nil {
+		return err
+	}
+	if participant == nil {
+		return errors.New("participant not found")
+	}
+	if participant.Name == newName {
+		return nil
+	}
+
+	existing, err := db.FindParticipant(newName)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != id {
+		return fmt.Errorf("name %s is already used by another participant", newName)
+	}
+
+	// Сначала турниры: при сбое повторное переименование допишет оставшиеся ссылки
+	if err := rewriteParticipantReferences(participant.Name, newName); err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$set":      bson.M{"name": newName},
+		"$addToSet": bson.M{"aliases": participant.Name},
+	}
+	_, err = db.DB.Collection("participants").UpdateOne(context.TODO(), bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	_, err = db.DB.Collection("participants").UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$pull": bson.M{"aliases": newName}})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Participant renamed", "participant_id", id, "old_name", participant.Name, "new_name", newName)
+	return nil
+}
+
+// MergeParticipants переносит историю дубликата duplicateID в участника keepID:
+// ссылки в турнирах, статистику, имена и привязку к Telegram. Дубликат удаляется,
+// рейтинги игроков пересчитываются.
+func MergeParticipants(keepID, duplicateID string) error {
+	if keepID == duplicateID {
+		return errors.New("cannot merge a participant with itself")
+	}
+
+	keep, err := db.GetParticipantByID(keepID)
+	if err != nil {
+		return err
+	}
+	duplicate, err := db.GetParticipantByID(duplicateID)
+	if err != nil {
+		return err
+	}
+	if keep == nil || duplicate == nil {
+		return errors.New("participant not found")
+	}
+
+	if keep.TelegramID != 0 && duplicate.TelegramID != 0 && keep.TelegramID != duplicate.TelegramID {
+		return errors.New("participants are linked to different Telegram accounts")
+	}
+
+	// Если оба участвовали в одном турнире, после слияния в нем будет один участник
+	// с двумя командами — такие записи нужно разбирать вручную
+	shared, err := db.DB.Collection("tournaments").CountDocuments(context.TODO(), bson.M{"$and": []bson.M{
+		tournamentMemberFilter(keep.Name), tournamentMemberFilter(duplicate.Name),
+	}})
+	if err != nil {
+		return err
+	}
+	if shared > 0 {
+		return fmt.Errorf("%s and %s played in the same tournament", keep.Name, duplicate.Name)
+	}
+
+	if err := rewriteParticipantReferences(duplicate.Name, keep.Name); err != nil {
+		return err
+	}
+
+	stats := duplicate.Stats
+	aliases := append([]string{duplicate.Name}, duplicate.Aliases...)
+	update := bson.M{
+		"$inc": bson.M{
+			"stats.total_points":       stats.TotalPoints,
+			"stats.goals_scored":       stats.GoalsScored,
+			"stats.goals_conceded":     stats.GoalsConceded,
+			"stats.wins":               stats.Wins,
+			"stats.losses":             stats.Losses,
+			"stats.draws":              stats.Draws,
+			"stats.matches_played":     stats.MatchesPlayed,
+			"stats.tournaments_played": stats.TournamentsPlayed,
+		},
+		"$addToSet": bson.M{"aliases": bson.M{"$each": aliases}},
+	}
+	if len(stats.TournamentStats) > 0 {
+		// Турниры идут по порядку проведения: ID турниров выдаются по возрастанию
+		update["$push"] = bson.M{"stats.tournament_stats": bson.M{
+			"$each": stats.TournamentStats,
+			"$sort": bson.M{"tournament_id": 1},
+		}}
+	}
+
+	// Дубликат удаляем до переноса привязки: уникальный индекс telegram_id не допускает двух владельцев
+	_, err = db.DB.Collection("participants").DeleteOne(context.TODO(), bson.M{"_id": duplicateID})
+	if err != nil {
+		return err
+	}
+
+	if keep.TelegramID == 0 && duplicate.TelegramID != 0 {
+		update["$set"] = bson.M{"telegram_id": duplicate.TelegramID, "username": duplicate.Username}
+	}
+
+	_, err = db.DB.Collection("participants").UpdateOne(context.TODO(), bson.M{"_id": keepID}, update)
+	if err != nil {
+		// Дубликат уже удален: логируем его данные, чтобы их можно было восстановить
+		slog.Error("Error merging participant stats", "keep_id", keepID, "duplicate", duplicate, "err", err)
+		return err
+	}
+
+	slog.Info("Participants merged", "keep_id", keepID, "duplicate_id", duplicateID, "duplicate_name", duplicate.Name)
+
+	// Матчи дубликата теперь записаны на оставшегося игрока, поэтому его рейтинг
+	// считается заново вместе с историей
+	if _, err := RecalculateRatings(); err != nil {
+		return fmt.Errorf("%w: %v", ErrRatingsOutdated, err)
+	}
+	return nil
+}
+
+// Списки имен участников в турнирах: при переименовании они переписываются целиком
+var participantNameArrays = []string{
+	"participants", "waitlist", "checked_in", "withdrawn", "draft_order", "draw.participants", "draw.ranking",
+}
+
+// tournamentMemberFilter находит турниры, в которых участник заявлен, стоит в листе
+// ожидания или снялся
+func tournamentMemberFilter(name string) bson.M {
+	return bson.M{"$or": []bson.M{{"participants": name}, {"waitlist": name}, {"withdrawn": name}}}
+}
+
+// rewriteParticipantReferences заменяет имя участника везде, где на него ссылаются
+// по имени: в списках турниров, ключах распределения команд и клубов жеребьевки,
+// истории рейтинга и итоговых таблицах сезонов
+func rewriteParticipantReferences(oldName, newName string) error {
+	tournaments := db.DB.Collection("tournaments")
+
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"p": oldName}},
+	})
+	for _, field := range participantNameArrays {
+		_, err := tournaments.UpdateMany(context.TODO(),
+			bson.M{field: oldName},
+			bson.M{"$set": bson.M{field + ".$[p]": newName}},
+			opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, field := range []string{"participant_teams.", "draw.recent."} {
+		_, err := tournaments.UpdateMany(context.TODO(),
+			bson.M{field + oldName: bson.M{"$exists": true}},
+			bson.M{"$rename": bson.M{field + oldName: field + newName}})
+		if err != nil {
+			return err
+		}
+	}
+
+	// История рейтинга ссылается на игроков по имени
+	for _, field := range []string{"participant", "opponent"} {
+		_, err := db.DB.Collection("rating_history").UpdateMany(context.TODO(),
+			bson.M{field: oldName}, bson.M{"$set": bson.M{field: newName}})
+		if err != nil {
+			return err
+		}
+	}
+
+	standingsOpts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"s.participant": oldName}},
+	})
+	_, err := db.DB.Collection("seasons").UpdateMany(context.TODO(),
+		bson.M{"standings.participant": oldName},
+		bson.M{"$set": bson.M{"standings.$[s].participant": newName}},
+		standingsOpts)
+	return err
+}
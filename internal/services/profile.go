@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"slices"
+	"time"
+	"tournament-bot/internal/db"
+)
+
+const (
+	// Сколько последних матчей показывает текущую форму
+	profileFormMatches = 5
+	// За сколько последних матчей считается динамика рейтинга
+	profileRatingTrendMatches = 10
+)
+
+// Места по этапу выбывания для записей, сохраненных до подсчета полных мест
+var labelPositions = map[string]int{"first": 1, "second": 2, "third": 3}
+
+// ProfileTournament — турнир в карьере игрока
+type ProfileTournament struct {
+	Stat         db.TournamentStat
+	Name         string
+	Team         string
+	Date         time.Time
+	Participants int
+}
+
+// Position возвращает итоговое место или 0, если оно неизвестно
+func (t *ProfileTournament) Position() int {
+	if t.Stat.Position > 0 {
+		return t.Stat.Position
+	}
+	return labelPositions[t.Stat.Place]
+}
+
+// TeamRecord — выступления игрока за команду
+type TeamRecord struct {
+	Team        string
+	Tournaments int
+	Wins        int
+}
+
+// Profile — карьерная статистика игрока по всем завершенным турнирам, в том числе
+// прошлых сезонов
+type Profile struct {
+	Participant   *db.Participant
+	Tournaments   int
+	Matches       int
+	Wins          int
+	Draws         int
+	Losses        int
+	GoalsScored   int
+	GoalsConceded int
+	Titles        int
+	Podiums       int
+	// Best и Worst — лучшее и худшее известное место, nil — мест еще нет
+	Best  *ProfileTournament
+	Worst *ProfileTournament
+	// FavouriteTeam — команда, за которую игрок выступал чаще всего;
+	// BestTeam — команда, с которой у него больше всего побед
+	FavouriteTeam *TeamRecord
+	BestTeam      *TeamRecord
+	// Form — результаты последних матчей, новые первыми: "W", "D" или "L"
+	Form []string
+	// RatingTrend — изменение рейтинга за последние RatingTrendMatches рейтинговых матчей
+	RatingTrend        float64
+	RatingTrendMatches int
+	// History — завершенные турниры, новые первыми
+	History []ProfileTournament
+}
+
+// WinRate — доля побед в процентах
+func (p *Profile) WinRate() float64 {
+	if p.Matches == 0 {
+		return 0
+	}
+	return 100 * float64(p.Wins) / float64(p.Matches)
+}
+
+// GoalsPerGame — среднее число забитых голов за матч
+func (p *Profile) GoalsPerGame() float64 {
+	if p.Matches == 0 {
+		return 0
+	}
+	return float64(p.GoalsScored) / float64(p.Matches)
+}
+
+// GetProfile собирает карьерную статистику игрока
+func GetProfile(participant *db.Participant) (*Profile, error) {
+	names := append([]string{participant.Name}, participant.Aliases...)
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "id", Value: -1}})
+	cursor, err := db.DB.Collection("tournaments").Find(context.TODO(),
+		bson.M{"participants": bson.M{"$in": names}, "setup_completed": true}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var tournaments []db.Tournament
+	if err := cursor.All(context.TODO(), &tournaments); err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{Participant: participant}
+	tournamentsByID := make(map[int]*db.Tournament, len(tournaments))
+	for i := range tournaments {
+		tournament := &tournaments[i]
+		tournamentsByID[tournament.ID] = tournament
+		if len(profile.Form) < profileFormMatches {
+			profile.Form = append(profile.Form, recentResults(tournament, playerTeam(tournament, names), profileFormMatches-len(profile.Form))...)
+		}
+	}
+
+	teams := make(map[string]*TeamRecord)
+	stats := participant.Stats.TournamentStats
+	// Best и Worst указывают на элементы History, поэтому массив не должен перевыделяться
+	profile.History = make([]ProfileTournament, 0, len(stats))
+	for i := len(stats) - 1; i >= 0; i-- {
+		entry := ProfileTournament{Stat: stats[i]}
+		if tournament, ok := tournamentsByID[stats[i].TournamentID]; ok {
+			entry.Name = tournament.Name
+			entry.Team = playerTeam(tournament, names)
+			entry.Date = tournament.CreatedAt
+			entry.Participants = len(tournament.Participants)
+		}
+		profile.History = append(profile.History, entry)
+		profile.addTournament(&profile.History[len(profile.History)-1])
+
+		if entry.Team != "" {
+			record := teams[entry.Team]
+			if record == nil {
+				record = &TeamRecord{Team: entry.Team}
+				teams[entry.Team] = record
+			}
+			record.Tournaments++
+			record.Wins += entry.Stat.Wins
+		}
+	}
+
+	for _, record := range teams {
+		if profile.FavouriteTeam == nil || record.Tournaments > profile.FavouriteTeam.Tournaments ||
+			record.Tournaments == profile.FavouriteTeam.Tournaments && record.Team < profile.FavouriteTeam.Team {
+			profile.FavouriteTeam = record
+		}
+		if profile.BestTeam == nil || record.Wins > profile.BestTeam.Wins ||
+			record.Wins == profile.BestTeam.Wins && record.Team < profile.BestTeam.Team {
+			profile.BestTeam = record
+		}
+	}
+
+	changes, err := GetRatingHistory(participant.Name, profileRatingTrendMatches)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		profile.RatingTrend += change.Delta()
+	}
+	profile.RatingTrendMatches = len(changes)
+
+	return profile, nil
+}
+
+func (p *Profile) addTournament(entry *ProfileTournament) {
+	stat := entry.Stat
+	p.Tournaments++
+	p.Matches += stat.MatchesPlayed
+	p.Wins += stat.Wins
+	p.Draws += stat.Draws
+	p.Losses += stat.Losses
+	p.GoalsScored += stat.GoalsScored
+	p.GoalsConceded += stat.GoalsConceded
+
+	position := entry.Position()
+	if position == 0 {
+		return
+	}
+	if position == 1 {
+		p.Titles++
+	}
+	if position <= 3 {
+		p.Podiums++
+	}
+	if p.Best == nil || position < p.Best.Position() {
+		p.Best = entry
+	}
+	if p.Worst == nil || position > p.Worst.Position() {
+		p.Worst = entry
+	}
+}
+
+// playerTeam возвращает команду игрока в турнире по его имени или прежним именам
+func playerTeam(tournament *db.Tournament, names []string) string {
+	for participant, team := range tournament.ParticipantTeams {
+		if slices.Contains(names, participant) {
+			return team
+		}
+	}
+	return ""
+}
+
+// recentResults возвращает до limit последних результатов команды в турнире, новые первыми
+func recentResults(tournament *db.Tournament, team string, limit int) []string {
+	if team == "" {
+		return nil
+	}
+	matches := tournamentMatches(tournament)
+	var results []string
+	for i := len(matches) - 1; i >= 0 && len(results) < limit; i-- {
+		match := matches[i]
+		if (match.Team1 != team && match.Team2 != team) || !match.Counted || !match.Decided() {
+			continue
+		}
+		switch match.WinnerTeam() {
+		case "":
+			results = append(results, "D")
+		case team:
+			results = append(results, "W")
+		default:
+			results = append(results, "L")
+		}
+	}
+	return results
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"time"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/rating"
+)
+
+// Сколько очков рейтинга дает разница в одну звезду между командами соперников
+const starRatingPoints = 100
+
+// ErrRatingsOutdated — изменение сохранено (например, матч удален или игроки объединены),
+// но рейтинги игроков обновить не удалось
+var ErrRatingsOutdated = errors.New("the change was saved, but player ratings could not be updated")
+
+var (
+	ratingEngine rating.Engine = &rating.Elo{K: 32}
+	// ratingTeamAdjust — учитывать ли звезды команд из справочника как фору
+	ratingTeamAdjust bool
+)
+
+// SetRatingSystem выбирает систему рейтинга игроков (elo или glicko2), коэффициент K
+// для Эло и учет силы команд
+func SetRatingSystem(system string, k float64, teamAdjust bool) error {
+	engine, err := rating.New(system, k)
+	if err != nil {
+		return err
+	}
+	ratingEngine = engine
+	ratingTeamAdjust = teamAdjust
+	return nil
+}
+
+// RatingSystem возвращает название используемой системы рейтинга
+func RatingSystem() string {
+	return ratingEngine.Name()
+}
+
+func currentRating(participant *db.Participant) rating.Rating {
+	if participant.Rating == nil || participant.Rating.System != ratingEngine.Name() {
+		return ratingEngine.Initial()
+	}
+	return rating.Rating{
+		Value:      participant.Rating.Value,
+		Deviation:  participant.Rating.Deviation,
+		Volatility: participant.Rating.Volatility,
+	}
+}
+
+// teamHandicap — фора первого игрока за более сильную по звездам команду
+func teamHandicap(team1, team2 string) (float64, error) {
+	if !ratingTeamAdjust {
+		return 0, nil
+	}
+	t1, err := db.FindTeam(team1)
+	if err != nil {
+		return 0, err
+	}
+	t2, err := db.FindTeam(team2)
+	if err != nil {
+		return 0, err
+	}
+	if t1 == nil || t2 == nil {
+		return 0, nil
+	}
+	return (t1.Stars - t2.Stars) * starRatingPoints, nil
+}
+
+// applyMatchRating пересчитывает рейтинги игроков после сыгранного матча и записывает
+// изменения в историю. Технические результаты и несостоявшиеся матчи рейтинг не меняют.
+func applyMatchRating(tournament *db.Tournament, match db.Match, date time.Time) error {
+	if match.StatusOrPlayed() != db.MatchPlayed {
+		return nil
+	}
+
+	name1 := getParticipantByTeam(tournament.ParticipantTeams, match.Team1)
+	name2 := getParticipantByTeam(tournament.ParticipantTeams, match.Team2)
+	player1, err := db.FindParticipant(name1)
+	if err != nil {
+		return err
+	}
+	player2, err := db.FindParticipant(name2)
+	if err != nil {
+		return err
+	}
+	if player1 == nil || player2 == nil {
+		slog.Warn("Rating not updated: participant not found", "tournament_id", tournament.ID,
+			"team1", match.Team1, "team2", match.Team2)
+		return nil
+	}
+
+	handicap, err := teamHandicap(match.Team1, match.Team2)
+	if err != nil {
+		return err
+	}
+	result := rating.ResultFromScore(match.Score1, match.Score2)
+	result.Handicap = handicap
+
+	before1, before2 := currentRating(player1), currentRating(player2)
+	after1, after2 := ratingEngine.Update(before1, before2, result)
+
+	changes := []db.RatingChange{
+		ratingChange(tournament.ID, player1, player2.Name, match.Team1, match.Team2, match.Score1, match.Score2, before1, after1, date),
+		ratingChange(tournament.ID, player2, player1.Name, match.Team2, match.Team1, match.Score2, match.Score1, before2, after2, date),
+	}
+	var documents []interface{}
+	for _, change := range changes {
+		_, err := db.DB.Collection("participants").UpdateOne(context.TODO(),
+			bson.M{"name": change.Participant}, bson.M{"$set": bson.M{"rating": change.After}})
+		if err != nil {
+			return err
+		}
+		documents = append(documents, change)
+	}
+	_, err = db.DB.Collection("rating_history").InsertMany(context.TODO(), documents)
+	return err
+}
+
+func ratingChange(tournamentID int, player *db.Participant, opponent, team, opponentTeam string,
+	score, opponentScore int, before, after rating.Rating, date time.Time) db.RatingChange {
+	matches := 0
+	if player.Rating != nil && player.Rating.System == ratingEngine.Name() {
+		matches = player.Rating.Matches
+	}
+	return db.RatingChange{
+		Participant:   player.Name,
+		TournamentID:  tournamentID,
+		Opponent:      opponent,
+		Team:          team,
+		OpponentTeam:  opponentTeam,
+		Score:         score,
+		OpponentScore: opponentScore,
+		Before:        playerRating(before, matches, date),
+		After:         playerRating(after, matches+1, date),
+		Date:          date,
+	}
+}
+
+func playerRating(r rating.Rating, matches int, date time.Time) db.PlayerRating {
+	return db.PlayerRating{
+		System:     ratingEngine.Name(),
+		Value:      r.Value,
+		Deviation:  r.Deviation,
+		Volatility: r.Volatility,
+		Matches:    matches,
+		UpdatedAt:  date,
+	}
+}
+
+// recordMatchRating обновляет рейтинг после матча; ошибка рейтинга не отменяет запись матча
+func recordMatchRating(tournamentID int, match db.Match) {
+	tournament, err := GetTournament(tournamentID)
+	if err == nil {
+		err = applyMatchRating(tournament, match, time.Now())
+	}
+	if err != nil {
+		slog.Error("Error updating player ratings", "tournament_id", tournamentID, "err", err)
+	}
+}
+
+// revertMatchRating отменяет изменение рейтинга за удаленный матч. Откатить можно
+// только последний матч обоих игроков, иначе нужен полный пересчет.
+func revertMatchRating(tournament *db.Tournament, match *db.Match) error {
+	if match.StatusOrPlayed() != db.MatchPlayed {
+		return nil
+	}
+	name1 := getParticipantByTeam(tournament.ParticipantTeams, match.Team1)
+	name2 := getParticipantByTeam(tournament.ParticipantTeams, match.Team2)
+
+	history := db.DB.Collection("rating_history")
+	latest := options.FindOne().SetSort(bson.M{"date": -1})
+	var changes []db.RatingChange
+	for _, pair := range [][2]string{{name1, name2}, {name2, name1}} {
+		var last db.RatingChange
+		err := history.FindOne(context.TODO(), bson.M{"participant": pair[0]}, latest).Decode(&last)
+		if err != nil {
+			return err
+		}
+		if last.TournamentID != tournament.ID || last.Opponent != pair[1] {
+			return errors.New("the match is not the latest rated match of both players, recalculate ratings")
+		}
+		changes = append(changes, last)
+	}
+
+	for _, change := range changes {
+		_, err := db.DB.Collection("participants").UpdateOne(context.TODO(),
+			bson.M{"name": change.Participant}, bson.M{"$set": bson.M{"rating": change.Before}})
+		if err != nil {
+			return err
+		}
+		_, err = history.DeleteOne(context.TODO(), bson.M{
+			"participant": change.Participant, "tournament_id": change.TournamentID, "date": change.Date,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tournamentMatches возвращает матчи турнира в порядке игры: групповой этап, затем плей-офф
+func tournamentMatches(tournament *db.Tournament) []db.Match {
+	matches := append([]db.Match{}, tournament.Matches...)
+	if tournament.Playoff != nil {
+		matches = append(matches, tournament.Playoff.QuarterFinals...)
+		matches = append(matches, tournament.Playoff.SemiFinals...)
+		if tournament.Playoff.Final != nil {
+			matches = append(matches, *tournament.Playoff.Final)
+		}
+	}
+	return matches
+}
+
+// RecalculateRatings пересчитывает рейтинги всех игроков заново по всем турнирам,
+// например после смены системы рейтинга. Возвращает число учтенных матчей.
+func RecalculateRatings() (int, error) {
+	_, err := db.DB.Collection("rating_history").DeleteMany(context.TODO(), bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	_, err = db.DB.Collection("participants").UpdateMany(context.TODO(), bson.M{}, bson.M{"$unset": bson.M{"rating": ""}})
+	if err != nil {
+		return 0, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}})
+	cursor, err := db.DB.Collection("tournaments").Find(context.TODO(), bson.M{"setup_completed": true}, opts)
+	if err != nil {
+		return 0, err
+	}
+	var tournaments []db.Tournament
+	if err := cursor.All(context.TODO(), &tournaments); err != nil {
+		return 0, err
+	}
+
+	rated := 0
+	for i := range tournaments {
+		tournament := &tournaments[i]
+		for j, match := range tournamentMatches(tournament) {
+			// У старых матчей нет даты: сохраняем порядок внутри турнира
+			date := match.Date
+			if date.IsZero() {
+				date = tournament.CreatedAt.Add(time.Duration(j) * time.Second)
+			}
+			// Матчи плей-офф, которые еще не сыграны, хранятся без отметки об учете
+			if match.StatusOrPlayed() != db.MatchPlayed || !match.Counted {
+				continue
+			}
+			if err := applyMatchRating(tournament, match, date); err != nil {
+				return rated, err
+			}
+			rated++
+		}
+	}
+
+	slog.Info("Player ratings recalculated", "system", ratingEngine.Name(), "matches", rated)
+	return rated, nil
+}
+
+// GetRatingLeaderboard возвращает игроков с рейтингом текущей системы, лучшие первыми
+func GetRatingLeaderboard(limit int) ([]db.Participant, error) {
+	opts := options.Find().SetSort(bson.M{"rating.value": -1}).SetLimit(int64(limit))
+	cursor, err := db.DB.Collection("participants").Find(context.TODO(),
+		bson.M{"rating.system": ratingEngine.Name()}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var participants []db.Participant
+	if err := cursor.All(context.TODO(), &participants); err != nil {
+		return nil, err
+	}
+	return participants, nil
+}
+
+// GetRatingHistory возвращает последние изменения рейтинга игрока, новые первыми
+func GetRatingHistory(participantName string, limit int) ([]db.RatingChange, error) {
+	opts := options.Find().SetSort(bson.M{"date": -1}).SetLimit(int64(limit))
+	cursor, err := db.DB.Collection("rating_history").Find(context.TODO(),
+		bson.M{"participant": participantName}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var changes []db.RatingChange
+	if err := cursor.All(context.TODO(), &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
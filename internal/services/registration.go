@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"time"
+	"tournament-bot/internal/db"
+)
+
+var (
+	ErrRegistrationClosed = errors.New("registration for this tournament is closed")
+	ErrAlreadyRegistered  = errors.New("participant is already registered")
+	ErrNotRegistered      = errors.New("participant is not registered for this tournament")
+	// ErrLinkPending — участник с таким именем уже есть, привязка к нему ждет подтверждения админа
+	ErrLinkPending   = errors.New("link request is waiting for admin approval")
+	ErrNoLinkRequest = errors.New("there is no pending link request for this participant")
+)
+
+// JoinResult — результат записи на турнир
+type JoinResult struct {
+	Waitlisted bool
+	// Position — место в листе ожидания, начиная с 1
+	Position int
+}
+
+// GetPendingTournament возвращает последний созданный турнир, который еще не начался
+func GetPendingTournament() (*db.Tournament, error) {
+	filter := bson.M{"is_active": false, "setup_completed": false, "is_completed": false}
+	opts := options.FindOne().SetSort(bson.M{"created_at": -1})
+
+	var tournament db.Tournament
+	err := db.DB.Collection("tournaments").FindOne(context.TODO(), filter, opts).Decode(&tournament)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tournament, nil
+}
+
+// GetOpenTournament возвращает последний созданный турнир, на который открыта запись
+func GetOpenTournament() (*db.Tournament, error) {
+	tournament, err := GetPendingTournament()
+	if err != nil || tournament == nil {
+		return nil, err
+	}
+	if !tournament.RegistrationOpen(time.Now()) {
+		return nil, nil
+	}
+	return tournament, nil
+}
+
+// SetRegistrationDeadline устанавливает время окончания записи
+func SetRegistrationDeadline(tournamentID int, deadline time.Time) error {
+	filter := bson.M{"id": tournamentID}
+	update := bson.M{"$set": bson.M{"registration_deadline": deadline}}
+	result, err := db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("tournament not found")
+	}
+	return nil
+}
+
+// registrationOpenFilter отбирает турнир, на который сейчас открыта запись
+// и в котором участника еще нет ни в списке, ни в листе ожидания
+func registrationOpenFilter(tournamentID int, participantName string) bson.M {
+	return bson.M{
+		"id":              tournamentID,
+		"is_active":       false,
+		"setup_completed": false,
+		"check_in_closed": bson.M{"$ne": true},
+		"participants":    bson.M{"$ne": participantName},
+		"waitlist":        bson.M{"$ne": participantName},
+		"$or": []bson.M{
+			{"registration_deadline": bson.M{"$exists": false}},
+			{"registration_deadline": bson.M{"$gt": time.Now()}},
+		},
+	}
+}
+
+// JoinTournament записывает участника на турнир или, если мест нет, в лист ожидания.
+// Проверка свободных мест и запись выполняются одним обновлением, поэтому
+// одновременные нажатия не превысят MaxParticipants.
+func JoinTournament(tournamentID int, participantName string) (*JoinResult, error) {
+	tournaments := db.DB.Collection("tournaments")
+
+	filter := registrationOpenFilter(tournamentID, participantName)
+	filter["$expr"] = bson.M{"$lt": bson.A{bson.M{"$size": "$participants"}, "$max_participants"}}
+	result, err := tournaments.UpdateOne(context.TODO(), filter, bson.M{"$push": bson.M{"participants": participantName}})
+	if err != nil {
+		return nil, err
+	}
+	if result.ModifiedCount > 0 {
+		slog.Info("Participant joined tournament", "tournament_id", tournamentID, "participant", participantName)
+		return &JoinResult{}, nil
+	}
+
+	// Мест нет: ставим в конец листа ожидания
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var tournament db.Tournament
+	err = tournaments.FindOneAndUpdate(context.TODO(),
+		registrationOpenFilter(tournamentID, participantName),
+		bson.M{"$push": bson.M{"waitlist": participantName}},
+		opts).Decode(&tournament)
+	if err == nil {
+		slog.Info("Participant added to waitlist", "tournament_id", tournamentID, "participant", participantName)
+		return &JoinResult{Waitlisted: true, Position: len(tournament.Waitlist)}, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// Ни одно обновление не прошло: выясняем причину
+	current, err := GetTournament(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	if current.HasParticipant(participantName) || current.IsWaitlisted(participantName) {
+		return nil, ErrAlreadyRegistered
+	}
+	return nil, ErrRegistrationClosed
+}
+
+// WithdrawFromTournament снимает участника с турнира до его начала.
+// Освободившееся место занимает первый из листа ожидания; его имя возвращается.
+func WithdrawFromTournament(tournamentID int, participantName string) (string, error) {
+	tournaments := db.DB.Collection("tournaments")
+	filter := bson.M{"id": tournamentID, "is_active": false, "setup_completed": false}
+
+	result, err := tournaments.UpdateOne(context.TODO(),
+		bson.M{"id": tournamentID, "waitlist": participantName},
+		bson.M{"$pull": bson.M{"waitlist": participantName}})
+	if err != nil {
+		return "", err
+	}
+	if result.ModifiedCount > 0 {
+		return "", nil
+	}
+
+	filter["participants"] = participantName
+	result, err = tournaments.UpdateOne(context.TODO(), filter, bson.M{"$pull": bson.M{"participants": participantName}})
+	if err != nil {
+		return "", err
+	}
+	if result.ModifiedCount == 0 {
+		current, err := GetTournament(tournamentID)
+		if err != nil {
+			return "", err
+		}
+		if current.HasParticipant(participantName) {
+			return "", errors.New("the tournament has already started")
+		}
+		return "", ErrNotRegistered
+	}
+
+	slog.Info("Participant withdrew from tournament", "tournament_id", tournamentID, "participant", participantName)
+	return promoteFromWaitlist(tournamentID)
+}
+
+// promoteFromWaitlist переносит первого из листа ожидания в участники, если есть свободное место.
+// Перенос выполняется одним обновлением с конвейером, чтобы два освободившихся места
+// не достались одному и тому же игроку.
+func promoteFromWaitlist(tournamentID int) (string, error) {
+	filter := bson.M{
+		"id":              tournamentID,
+		"is_active":       false,
+		"setup_completed": false,
+		"waitlist.0":      bson.M{"$exists": true},
+		"$expr":           bson.M{"$lt": bson.A{bson.M{"$size": "$participants"}, "$max_participants"}},
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"participants": bson.M{"$concatArrays": bson.A{"$participants", bson.M{"$slice": bson.A{"$waitlist", 1}}}},
+			"waitlist":     bson.M{"$slice": bson.A{"$waitlist", 1, bson.M{"$size": "$waitlist"}}},
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var before db.Tournament
+	err := db.DB.Collection("tournaments").FindOneAndUpdate(context.TODO(), filter, pipeline, opts).Decode(&before)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	promoted := before.Waitlist[0]
+	slog.Info("Participant promoted from waitlist", "tournament_id", tournamentID, "participant", promoted)
+	return promoted, nil
+}
+
+// RegisterTelegramUser привязывает аккаунт Telegram к новому участнику с указанным именем.
+// Если участник с таким именем уже есть, привязка только запрашивается: участник
+// возвращается вместе с ErrLinkPending, а подтверждает ее админ через ApproveLinkRequest.
+func RegisterTelegramUser(telegramID int64, username, name string) (*db.Participant, error) {
+	linked, err := db.GetParticipantByTelegramID(telegramID)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		return nil, fmt.Errorf("your account is already linked to %s", linked.Name)
+	}
+
+	participant, err := db.FindParticipant(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if participant == nil {
+		participant = &db.Participant{ID: db.NewParticipantID(), Name: name, TelegramID: telegramID, Username: username}
+		_, err = db.DB.Collection("participants").InsertOne(context.TODO(), bson.M{
+			"_id":         participant.ID,
+			"name":        participant.Name,
+			"telegram_id": telegramID,
+			"username":    username,
+		})
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("Participant registered", "participant_id", participant.ID, "telegram_id", telegramID)
+		return participant, nil
+	}
+
+	if participant.TelegramID != 0 {
+		return nil, fmt.Errorf("%s is already linked to another Telegram account", participant.Name)
+	}
+
+	// Запрос одного пользователя можно повторить, но чужой запрос не перезаписывается
+	request := &db.LinkRequest{TelegramID: telegramID, Username: username, RequestedAt: time.Now()}
+	filter := bson.M{
+		"_id":         participant.ID,
+		"telegram_id": bson.M{"$in": bson.A{nil, 0}},
+		"$or": []bson.M{
+			{"link_request": bson.M{"$exists": false}},
+			{"link_request.telegram_id": telegramID},
+		},
+	}
+	result, err := db.DB.Collection("participants").UpdateOne(context.TODO(), filter,
+		bson.M{"$set": bson.M{"link_request": request}})
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("another account has already asked to be linked to %s", participant.Name)
+	}
+	participant.LinkRequest = request
+
+	slog.Info("Participant link requested", "participant_id", participant.ID, "telegram_id", telegramID)
+	return participant, ErrLinkPending
+}
+
+// ApproveLinkRequest привязывает к участнику аккаунт Telegram из его запроса на привязку
+func ApproveLinkRequest(participantID string) (*db.Participant, error) {
+	participant, err := db.GetParticipantByID(participantID)
+	if err != nil {
+		return nil, err
+	}
+	if participant == nil {
+		return nil, errors.New("participant not found")
+	}
+	request := participant.LinkRequest
+	if request == nil {
+		return nil, ErrNoLinkRequest
+	}
+
+	linked, err := db.GetParticipantByTelegramID(request.TelegramID)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		return nil, fmt.Errorf("this Telegram account is already linked to %s", linked.Name)
+	}
+
+	filter := bson.M{
+		"_id":                      participant.ID,
+		"telegram_id":              bson.M{"$in": bson.A{nil, 0}},
+		"link_request.telegram_id": request.TelegramID,
+	}
+	update := bson.M{
+		"$set":   bson.M{"telegram_id": request.TelegramID, "username": request.Username},
+		"$unset": bson.M{"link_request": ""},
+	}
+	result, err := db.DB.Collection("participants").UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, errors.New("the link request has changed, please try again")
+	}
+	participant.TelegramID = request.TelegramID
+	participant.Username = request.Username
+	participant.LinkRequest = nil
+
+	slog.Info("Participant linked to Telegram", "participant_id", participant.ID, "telegram_id", request.TelegramID)
+	return participant, nil
+}
+
+// RejectLinkRequest отклоняет запрос на привязку и возвращает его, чтобы можно было
+// уведомить пользователя
+func RejectLinkRequest(participantID string) (*db.LinkRequest, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	var participant db.Participant
+	err := db.DB.Collection("participants").FindOneAndUpdate(context.TODO(),
+		bson.M{"_id": participantID, "link_request": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"link_request": ""}},
+		opts).Decode(&participant)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNoLinkRequest
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Participant link request rejected", "participant_id", participantID, "telegram_id", participant.LinkRequest.TelegramID)
+	return participant.LinkRequest, nil
+}
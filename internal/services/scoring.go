@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"log/slog"
+	"strconv"
+	"strings"
+	"tournament-bot/internal/db"
+)
+
+// Сколько мест можно задать в правилах начисления очков
+const maxScoringPlaces = 32
+
+// TournamentResult — итог турнира для игрока, по которому начисляются очки сезона
+type TournamentResult struct {
+	// Place — место по итогам плей-офф, 0 — место не определено
+	Place int
+	// GroupPosition — место в групповом этапе (с 1), 0 — команды нет в таблице
+	GroupPosition int
+	Wins          int
+	Draws         int
+}
+
+// ScoreTournament считает очки сезона за турнир по правилам сезона
+func ScoreTournament(scoring db.SeasonScoring, result TournamentResult) int {
+	points := scoring.Participation + result.Wins*scoring.Win + result.Draws*scoring.Draw
+	if result.Place > 0 && result.Place <= len(scoring.Places) {
+		points += scoring.Places[result.Place-1]
+	}
+	if result.GroupPosition > 0 && result.GroupPosition <= len(scoring.GroupBonus) {
+		points += scoring.GroupBonus[result.GroupPosition-1]
+	}
+	return points
+}
+
+// ParseSeasonScoring меняет правила по аргументам вида key=value:
+// places=8,4,2 group=2,2,2 participation=1 win=1 draw=0. Не указанные правила
+// остаются как в base; пустое значение списка убирает очки за места.
+func ParseSeasonScoring(base db.SeasonScoring, args []string) (db.SeasonScoring, error) {
+	scoring := base
+	if len(args) == 0 {
+		return scoring, errors.New("no rules given")
+	}
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return scoring, fmt.Errorf("expected key=value, got %q", arg)
+		}
+		var err error
+		switch strings.ToLower(key) {
+		case "places":
+			scoring.Places, err = parsePointsList(value)
+		case "group":
+			scoring.GroupBonus, err = parsePointsList(value)
+		case "participation":
+			scoring.Participation, err = parsePoints(value)
+		case "win":
+			scoring.Win, err = parsePoints(value)
+		case "draw":
+			scoring.Draw, err = parsePoints(value)
+		default:
+			return scoring, fmt.Errorf("unknown rule %q, use places, group, participation, win or draw", key)
+		}
+		if err != nil {
+			return scoring, fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	return scoring, nil
+}
+
+func parsePointsList(value string) ([]int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parts := strings.Split(value, ",")
+	if len(parts) > maxScoringPlaces {
+		return nil, fmt.Errorf("at most %d places", maxScoringPlaces)
+	}
+	points := make([]int, len(parts))
+	for i, part := range parts {
+		p, err := parsePoints(part)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = p
+	}
+	return points, nil
+}
+
+func parsePoints(value string) (int, error) {
+	points, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || points < 0 {
+		return 0, fmt.Errorf("%q is not a non-negative number", value)
+	}
+	return points, nil
+}
+
+// SetSeasonScoring меняет правила начисления очков текущего сезона. Уже начисленные
+// очки не меняются, их пересчитывает RecalculateSeasonPoints.
+func SetSeasonScoring(scoring db.SeasonScoring) (*db.Season, error) {
+	season, err := GetActiveSeason()
+	if err != nil {
+		return nil, err
+	}
+	if season == nil {
+		return nil, ErrNoActiveSeason
+	}
+	_, err = db.DB.Collection("seasons").UpdateOne(context.TODO(),
+		bson.M{"id": season.ID}, bson.M{"$set": bson.M{"scoring": scoring}})
+	if err != nil {
+		return nil, err
+	}
+	season.Scoring = scoring
+	slog.Info("Season scoring changed", "season_id", season.ID)
+	return season, nil
+}
+
+// RecalculateSeasonPoints заново начисляет очки за все завершенные турниры текущего
+// сезона по его правилам и пересчитывает итог сезона у каждого игрока. Возвращает
+// число игроков, у которых изменились очки.
+func RecalculateSeasonPoints() (int, error) {
+	season, err := GetActiveSeason()
+	if err != nil {
+		return 0, err
+	}
+	if season == nil {
+		return 0, ErrNoActiveSeason
+	}
+
+	cursor, err := db.DB.Collection("tournaments").Find(context.TODO(),
+		bson.M{"season_id": season.ID, "is_completed": true})
+	if err != nil {
+		return 0, err
+	}
+	var tournaments []db.Tournament
+	if err := cursor.All(context.TODO(), &tournaments); err != nil {
+		return 0, err
+	}
+
+	// Очки по ID игрока и ID турнира
+	points := make(map[string]map[int]int)
+	for i := range tournaments {
+		tournament := &tournaments[i]
+		stats := tournamentStats(tournament, season.Scoring)
+		for _, participant := range tournament.Participants {
+			record, err := db.FindParticipant(participant)
+			if err != nil {
+				return 0, err
+			}
+			if record == nil {
+				continue
+			}
+			if points[record.ID] == nil {
+				points[record.ID] = make(map[int]int)
+			}
+			points[record.ID][tournament.ID] = stats[participant].Points
+		}
+	}
+
+	participants, err := db.GetAllParticipantsWithStats()
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, participant := range participants {
+		stats := participant.Stats.TournamentStats
+		total, modified := 0, false
+		for i := range stats {
+			if stats[i].SeasonID != season.ID {
+				continue
+			}
+			if p, ok := points[participant.ID][stats[i].TournamentID]; ok && p != stats[i].Points {
+				stats[i].Points = p
+				modified = true
+			}
+			total += stats[i].Points
+		}
+		if !modified && total == participant.Stats.TotalPoints {
+			continue
+		}
+
+		_, err := db.DB.Collection("participants").UpdateOne(context.TODO(), bson.M{"_id": participant.ID},
+			bson.M{"$set": bson.M{"stats.tournament_stats": stats, "stats.total_points": total}})
+		if err != nil {
+			return changed, err
+		}
+		changed++
+	}
+
+	slog.Info("Season points recalculated", "season_id", season.ID, "tournaments", len(tournaments), "participants", changed)
+	return changed, nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"tournament-bot/internal/db"
+)
+
+func TestScoreTournament(t *testing.T) {
+	scoring := db.SeasonScoring{
+		Places:        []int{10, 6, 4},
+		GroupBonus:    []int{3, 1},
+		Participation: 2,
+		Win:           1,
+		Draw:          0,
+	}
+
+	tests := []struct {
+		name    string
+		scoring db.SeasonScoring
+		result  TournamentResult
+		want    int
+	}{
+		{name: "no rules", result: TournamentResult{Place: 1, GroupPosition: 1, Wins: 5}, want: 0},
+		{name: "participation only", scoring: scoring, want: 2},
+		{name: "champion who topped the group", scoring: scoring,
+			result: TournamentResult{Place: 1, GroupPosition: 1, Wins: 4, Draws: 1}, want: 2 + 4 + 10 + 3},
+		{name: "place outside the table", scoring: scoring,
+			result: TournamentResult{Place: 4, GroupPosition: 2, Wins: 1}, want: 2 + 1 + 1},
+		{name: "undetermined place and no team in the table", scoring: scoring,
+			result: TournamentResult{Wins: 2}, want: 2 + 2},
+		{name: "draws score when configured", scoring: db.SeasonScoring{Win: 3, Draw: 1},
+			result: TournamentResult{Wins: 2, Draws: 3}, want: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScoreTournament(tt.scoring, tt.result); got != tt.want {
+				t.Errorf("ScoreTournament() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSeasonScoring(t *testing.T) {
+	base := db.SeasonScoring{Places: []int{5, 3, 1}, GroupBonus: []int{1}, Participation: 1, Win: 1}
+	tooMany := strings.TrimSuffix(strings.Repeat("1,", maxScoringPlaces+1), ",")
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    db.SeasonScoring
+		wantErr bool
+	}{
+		{
+			name: "all rules",
+			args: []string{"places=8,4,2", "group=2,2", "participation=0", "win=3", "draw=1"},
+			want: db.SeasonScoring{Places: []int{8, 4, 2}, GroupBonus: []int{2, 2}, Win: 3, Draw: 1},
+		},
+		{
+			name: "unset rules keep their values",
+			args: []string{"WIN=2"},
+			want: db.SeasonScoring{Places: []int{5, 3, 1}, GroupBonus: []int{1}, Participation: 1, Win: 2},
+		},
+		{
+			name: "empty list removes place points",
+			args: []string{"places="},
+			want: db.SeasonScoring{GroupBonus: []int{1}, Participation: 1, Win: 1},
+		},
+		{
+			name: "spaces around numbers",
+			args: []string{"places= 3, 2 ,1"},
+			want: db.SeasonScoring{Places: []int{3, 2, 1}, GroupBonus: []int{1}, Participation: 1, Win: 1},
+		},
+		{name: "no arguments", wantErr: true},
+		{name: "missing equals sign", args: []string{"win"}, wantErr: true},
+		{name: "unknown rule", args: []string{"loss=1"}, wantErr: true},
+		{name: "negative points", args: []string{"draw=-1"}, wantErr: true},
+		{name: "not a number", args: []string{"places=1,x"}, wantErr: true},
+		{name: "too many places", args: []string{"places=" + tooMany}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSeasonScoring(base, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSeasonScoring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSeasonScoring() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Разбор не меняет списки исходных правил
+	if !reflect.DeepEqual(base.Places, []int{5, 3, 1}) {
+		t.Errorf("base places changed: %v", base.Places)
+	}
+}
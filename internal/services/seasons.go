@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"sort"
+	"strconv"
+	"time"
+	"tournament-bot/internal/db"
+)
+
+const seasonIDSequence = "season_id"
+
+// ErrNoActiveSeason — сезон не начат
+var ErrNoActiveSeason = errors.New("no season is in progress")
+
+// GetActiveSeason возвращает текущий сезон или nil, если сезон не начат
+func GetActiveSeason() (*db.Season, error) {
+	return findSeason(bson.M{"is_active": true})
+}
+
+func findSeason(filter bson.M, opts ...*options.FindOneOptions) (*db.Season, error) {
+	var season db.Season
+	err := db.DB.Collection("seasons").FindOne(context.TODO(), filter, opts...).Decode(&season)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+// FindSeason ищет сезон по ID или названию. Возвращает nil, если сезона нет.
+func FindSeason(idOrName string) (*db.Season, error) {
+	if id, err := strconv.Atoi(idOrName); err == nil {
+		return findSeason(bson.M{"id": id})
+	}
+	return findSeason(bson.M{"name": idOrName})
+}
+
+func GetSeasons() ([]db.Season, error) {
+	opts := options.Find().SetSort(bson.M{"id": -1}).SetProjection(bson.M{"standings": 0})
+	cursor, err := db.DB.Collection("seasons").Find(context.TODO(), bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var seasons []db.Season
+	if err := cursor.All(context.TODO(), &seasons); err != nil {
+		return nil, err
+	}
+	return seasons, nil
+}
+
+// seasonScoring возвращает правила начисления очков сезона турнира
+func seasonScoring(seasonID int) (db.SeasonScoring, error) {
+	if seasonID == 0 {
+		return db.DefaultSeasonScoring, nil
+	}
+	season, err := findSeason(bson.M{"id": seasonID})
+	if err != nil {
+		return db.SeasonScoring{}, err
+	}
+	// У сезонов без правил (созданных до настраиваемых правил) действуют прежние очки
+	if season == nil || season.Scoring.IsZero() {
+		return db.DefaultSeasonScoring, nil
+	}
+	return season.Scoring, nil
+}
+
+// StartSeason начинает новый сезон. Статистика, накопленная вне сезона (до появления
+// сезонов), сначала сохраняется в архивный сезон, чтобы новый сезон начался с нуля.
+func StartSeason(name string) (*db.Season, error) {
+	if name == "" {
+		return nil, errors.New("season name is empty")
+	}
+	active, err := GetActiveSeason()
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		return nil, fmt.Errorf("season %s is still in progress", active.Name)
+	}
+	running, err := tournamentRunning()
+	if err != nil {
+		return nil, err
+	}
+	if running {
+		return nil, errors.New("finish the running tournament before starting a season")
+	}
+
+	// Новый сезон наследует правила начисления очков предыдущего
+	scoring := db.DefaultSeasonScoring
+	previous, err := findSeason(bson.M{}, options.FindOne().SetSort(bson.M{"id": -1}))
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil && !previous.Scoring.IsZero() {
+		scoring = previous.Scoring
+	}
+
+	table, err := currentSeasonTable()
+	if err != nil {
+		return nil, err
+	}
+	if len(table) > 0 {
+		archive := &db.Season{Name: "До сезона " + name, Scoring: db.DefaultSeasonScoring}
+		if err := archiveSeason(archive, table); err != nil {
+			return nil, err
+		}
+	}
+
+	id, err := nextSequence(seasonIDSequence)
+	if err != nil {
+		return nil, err
+	}
+	season := &db.Season{
+		ID:        id,
+		Name:      name,
+		StartedAt: time.Now(),
+		IsActive:  true,
+		Scoring:   scoring,
+	}
+	_, err = db.DB.Collection("seasons").InsertOne(context.TODO(), season)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Season started", "season_id", season.ID, "name", name)
+	return season, nil
+}
+
+// EndSeason замораживает итоговую таблицу текущего сезона и обнуляет статистику игроков.
+// Сезон нельзя завершить, пока идет турнир.
+func EndSeason() (*db.Season, error) {
+	season, err := GetActiveSeason()
+	if err != nil {
+		return nil, err
+	}
+	if season == nil {
+		return nil, ErrNoActiveSeason
+	}
+
+	running, err := tournamentRunning()
+	if err != nil {
+		return nil, err
+	}
+	if running {
+		return nil, errors.New("finish the running tournament before ending the season")
+	}
+
+	table, err := currentSeasonTable()
+	if err != nil {
+		return nil, err
+	}
+	if err := archiveSeason(season, table); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Season ended", "season_id", season.ID, "name", season.Name, "participants", len(table))
+	return season, nil
+}
+
+// tournamentRunning сообщает, что идет турнир. Сезон нельзя начать или завершить
+// посреди турнира: его статистика попала бы в итоги другого сезона.
+func tournamentRunning() (bool, error) {
+	running, err := db.DB.Collection("tournaments").CountDocuments(context.TODO(),
+		bson.M{"is_active": true, "is_completed": false})
+	if err != nil {
+		return false, err
+	}
+	return running > 0, nil
+}
+
+// archiveSeason сохраняет таблицу в сезон, закрывает его и обнуляет статистику игроков.
+// История турниров игроков (tournament_stats) сохраняется.
+func archiveSeason(season *db.Season, table []db.SeasonStanding) error {
+	season.Standings = table
+	season.EndedAt = time.Now()
+	season.IsActive = false
+
+	if season.ID == 0 {
+		id, err := nextSequence(seasonIDSequence)
+		if err != nil {
+			return err
+		}
+		season.ID = id
+		season.StartedAt = time.Time{}
+		_, err = db.DB.Collection("seasons").InsertOne(context.TODO(), season)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Условие на активность не дает двум одновременным вызовам закрыть сезон дважды
+		result, err := db.DB.Collection("seasons").UpdateOne(context.TODO(),
+			bson.M{"id": season.ID, "is_active": true},
+			bson.M{"$set": bson.M{"standings": table, "ended_at": season.EndedAt, "is_active": false}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return errors.New("the season has already ended")
+		}
+	}
+
+	_, err := db.DB.Collection("participants").UpdateMany(context.TODO(), bson.M{}, bson.M{"$set": bson.M{
+		"stats.total_points":       0,
+		"stats.goals_scored":       0,
+		"stats.goals_conceded":     0,
+		"stats.wins":               0,
+		"stats.losses":             0,
+		"stats.draws":              0,
+		"stats.matches_played":     0,
+		"stats.tournaments_played": 0,
+	}})
+	return err
+}
+
+// currentSeasonTable строит таблицу текущего сезона по статистике игроков: по очкам,
+// затем по разнице побед и поражений, как в рейтинге сезона в канале
+func currentSeasonTable() ([]db.SeasonStanding, error) {
+	participants, err := db.GetAllParticipantsWithStats()
+	if err != nil {
+		return nil, err
+	}
+
+	var table []db.SeasonStanding
+	for _, participant := range participants {
+		stats := participant.Stats
+		if stats.TournamentsPlayed == 0 {
+			continue
+		}
+		table = append(table, db.SeasonStanding{
+			Participant:       participant.Name,
+			Points:            stats.TotalPoints,
+			TournamentsPlayed: stats.TournamentsPlayed,
+			Wins:              stats.Wins,
+			Draws:             stats.Draws,
+			Losses:            stats.Losses,
+			GoalsScored:       stats.GoalsScored,
+			GoalsConceded:     stats.GoalsConceded,
+		})
+	}
+
+	sort.SliceStable(table, func(i, j int) bool {
+		if table[i].Points != table[j].Points {
+			return table[i].Points > table[j].Points
+		}
+		if table[i].Wins-table[i].Losses != table[j].Wins-table[j].Losses {
+			return table[i].Wins-table[i].Losses > table[j].Wins-table[j].Losses
+		}
+		return table[i].Participant < table[j].Participant
+	})
+	for i := range table {
+		table[i].Position = i + 1
+	}
+	return table, nil
+}
+
+// SeasonTable возвращает таблицу сезона: замороженную для завершенного
+// и текущую для идущего
+func SeasonTable(season *db.Season) ([]db.SeasonStanding, error) {
+	if season.IsActive {
+		return currentSeasonTable()
+	}
+	return season.Standings, nil
+}
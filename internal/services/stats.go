@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"time"
+	"tournament-bot/internal/db"
+)
+
+// StatsDiff — расхождения сохраненной статистики игрока с пересчитанной по турнирам
+type StatsDiff struct {
+	Participant string
+	Changes     []string
+}
+
+// RebuildParticipantStats пересчитывает статистику всех игроков по завершенным турнирам:
+// историю турниров целиком и итоги текущего периода — идущего сезона или, если сезон
+// не начат, турниров после завершения последнего сезона. Замороженные таблицы прошлых
+// сезонов не меняются. При dryRun только возвращает расхождения.
+func RebuildParticipantStats(dryRun bool) ([]StatsDiff, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "id", Value: 1}})
+	cursor, err := db.DB.Collection("tournaments").Find(context.TODO(), bson.M{"is_completed": true}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var tournaments []db.Tournament
+	if err := cursor.All(context.TODO(), &tournaments); err != nil {
+		return nil, err
+	}
+
+	inCurrentPeriod, err := currentPeriodFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	rebuilt := make(map[string]*db.ParticipantStats)
+	scorings := make(map[int]db.SeasonScoring)
+	for i := range tournaments {
+		tournament := &tournaments[i]
+		scoring, ok := scorings[tournament.SeasonID]
+		if !ok {
+			scoring, err = seasonScoring(tournament.SeasonID)
+			if err != nil {
+				return nil, err
+			}
+			scorings[tournament.SeasonID] = scoring
+		}
+
+		results := tournamentStats(tournament, scoring)
+		for _, participant := range tournament.Participants {
+			record, err := db.FindParticipant(participant)
+			if err != nil {
+				return nil, err
+			}
+			if record == nil {
+				slog.Warn("Participant not found, stats not rebuilt", "tournament_id", tournament.ID, "participant", participant)
+				continue
+			}
+
+			stats := rebuilt[record.ID]
+			if stats == nil {
+				stats = &db.ParticipantStats{}
+				rebuilt[record.ID] = stats
+			}
+			stat := results[participant]
+			stats.TournamentStats = append(stats.TournamentStats, stat)
+			if inCurrentPeriod(tournament) {
+				stats.TotalPoints += stat.Points
+				stats.GoalsScored += stat.GoalsScored
+				stats.GoalsConceded += stat.GoalsConceded
+				stats.Wins += stat.Wins
+				stats.Losses += stat.Losses
+				stats.Draws += stat.Draws
+				stats.MatchesPlayed += stat.MatchesPlayed
+				stats.TournamentsPlayed++
+			}
+		}
+	}
+
+	participants, err := db.GetAllParticipantsWithStats()
+	if err != nil {
+		return nil, err
+	}
+	var diffs []StatsDiff
+	for _, participant := range participants {
+		stats := rebuilt[participant.ID]
+		if stats == nil {
+			stats = &db.ParticipantStats{}
+		}
+		changes := statsChanges(&participant.Stats, stats)
+		if len(changes) == 0 {
+			continue
+		}
+		diffs = append(diffs, StatsDiff{Participant: participant.Name, Changes: changes})
+		if dryRun {
+			continue
+		}
+
+		_, err := db.DB.Collection("participants").UpdateOne(context.TODO(),
+			bson.M{"_id": participant.ID}, bson.M{"$set": bson.M{"stats": stats}})
+		if err != nil {
+			return diffs, err
+		}
+	}
+
+	if !dryRun {
+		slog.Info("Participant stats rebuilt", "tournaments", len(tournaments), "participants", len(diffs))
+	}
+	return diffs, nil
+}
+
+// currentPeriodFilter возвращает проверку, входит ли турнир в итоги, которые сейчас
+// копятся в статистике игроков
+func currentPeriodFilter() (func(*db.Tournament) bool, error) {
+	season, err := GetActiveSeason()
+	if err != nil {
+		return nil, err
+	}
+	if season != nil {
+		return func(t *db.Tournament) bool { return t.SeasonID == season.ID }, nil
+	}
+
+	last, err := findSeason(bson.M{}, options.FindOne().SetSort(bson.M{"ended_at": -1}))
+	if err != nil {
+		return nil, err
+	}
+	var since time.Time
+	if last != nil {
+		since = last.EndedAt
+	}
+	return func(t *db.Tournament) bool { return t.SeasonID == 0 && t.CreatedAt.After(since) }, nil
+}
+
+// statsChanges описывает отличия пересчитанной статистики от сохраненной
+func statsChanges(stored, rebuilt *db.ParticipantStats) []string {
+	changes := intChanges("", []string{
+		"total_points", "goals_scored", "goals_conceded", "wins", "losses", "draws", "matches_played", "tournaments_played",
+	}, []int{
+		stored.TotalPoints, stored.GoalsScored, stored.GoalsConceded, stored.Wins, stored.Losses, stored.Draws,
+		stored.MatchesPlayed, stored.TournamentsPlayed,
+	}, []int{
+		rebuilt.TotalPoints, rebuilt.GoalsScored, rebuilt.GoalsConceded, rebuilt.Wins, rebuilt.Losses, rebuilt.Draws,
+		rebuilt.MatchesPlayed, rebuilt.TournamentsPlayed,
+	})
+
+	storedByID := make(map[int]db.TournamentStat)
+	for _, stat := range stored.TournamentStats {
+		storedByID[stat.TournamentID] = stat
+	}
+	for _, stat := range rebuilt.TournamentStats {
+		old, ok := storedByID[stat.TournamentID]
+		delete(storedByID, stat.TournamentID)
+		prefix := fmt.Sprintf("tournament #%d ", stat.TournamentID)
+		if !ok {
+			changes = append(changes, prefix+"missing, added")
+			continue
+		}
+		if old.Place != stat.Place {
+			changes = append(changes, fmt.Sprintf("%splace: %s -> %s", prefix, old.Place, stat.Place))
+		}
+		changes = append(changes, intChanges(prefix, []string{
+			"season", "position", "points", "goals_scored", "goals_conceded", "wins", "losses", "draws", "matches_played",
+		}, []int{
+			old.SeasonID, old.Position, old.Points, old.GoalsScored, old.GoalsConceded, old.Wins, old.Losses, old.Draws, old.MatchesPlayed,
+		}, []int{
+			stat.SeasonID, stat.Position, stat.Points, stat.GoalsScored, stat.GoalsConceded, stat.Wins, stat.Losses, stat.Draws, stat.MatchesPlayed,
+		})...)
+	}
+	for _, stat := range stored.TournamentStats {
+		if _, ok := storedByID[stat.TournamentID]; ok {
+			changes = append(changes, fmt.Sprintf("tournament #%d not found or not completed, removed", stat.TournamentID))
+		}
+	}
+	return changes
+}
+
+func intChanges(prefix string, names []string, before, after []int) []string {
+	var changes []string
+	for i, name := range names {
+		if before[i] != after[i] {
+			changes = append(changes, fmt.Sprintf("%s%s: %d -> %d", prefix, name, before[i], after[i]))
+		}
+	}
+	return changes
+}
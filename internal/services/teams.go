@@ -0,0 +1,404 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"tournament-bot/internal/db"
+)
+
+const teamIDSequence = "team_id"
+
+// Колонки CSV с рейтингами команд. Поддерживаются выгрузки sofifa (team_id, team_name,
+// league_name, nationality_name, overall, attack, midfield, defence) и короткие названия.
+var teamCSVColumns = map[string][]string{
+	"id":       {"team_id", "id"},
+	"name":     {"team_name", "name", "team"},
+	"code":     {"short_name", "short_code", "code"},
+	"league":   {"league_name", "league"},
+	"country":  {"nationality_name", "country", "nationality"},
+	"stars":    {"stars", "star_rating"},
+	"overall":  {"overall", "ovr"},
+	"attack":   {"attack", "att"},
+	"midfield": {"midfield", "mid"},
+	"defence":  {"defence", "defense", "def"},
+	"crest":    {"crest", "crest_url", "logo"},
+}
+
+// starsFromOverall переводит общий рейтинг команды в звезды, как в игре (приближенно):
+// 5 звезд с 82, дальше ползвезды на каждые 3 пункта
+func starsFromOverall(overall int) float64 {
+	if overall <= 0 {
+		return 0
+	}
+	stars := 5 - float64((82-overall+2)/3)*0.5
+	if overall >= 82 {
+		stars = 5
+	}
+	return max(stars, 0.5)
+}
+
+// TeamImport — итог импорта справочника команд
+type TeamImport struct {
+	Created int
+	Updated int
+	Skipped []string
+}
+
+// ImportTeamsCSV загружает команды из CSV с рейтингами. Существующие команды (по ID из
+// файла или по названию) обновляются, другие названия команд сохраняются. ID из файла
+// хранится отдельно от ID справочника. Если указан crestDir, эмблемой становится файл
+// <ID из файла>.png из этого каталога.
+func ImportTeamsCSV(r io.Reader, crestDir string, dryRun bool) (*TeamImport, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	columns := make(map[string]int)
+	for field, names := range teamCSVColumns {
+		for i, column := range header {
+			column = strings.ToLower(strings.TrimSpace(column))
+			if _, found := columns[field]; !found && slices.Contains(names, column) {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("CSV has no team name column")
+	}
+
+	// В выгрузках одна команда встречается по разу на каждую версию игры: берем первую
+	seen := make(map[string]bool)
+	result := &TeamImport{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		team, err := parseTeamRecord(record, columns)
+		if err != nil {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+		if seen[strings.ToLower(team.Name)] {
+			continue
+		}
+		seen[strings.ToLower(team.Name)] = true
+
+		created, err := upsertTeam(team, crestDir, dryRun)
+		if errors.Is(err, errSourceIDConflict) {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("line %d: %v", line, err)
+		}
+		if created {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	slog.Info("Teams imported", "created", result.Created, "updated", result.Updated,
+		"skipped", len(result.Skipped), "dry_run", dryRun)
+	return result, nil
+}
+
+func parseTeamRecord(record []string, columns map[string]int) (*db.Team, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	number := func(name string) (int, error) {
+		value := field(name)
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s: %s", name, value)
+		}
+		return n, nil
+	}
+
+	team := &db.Team{
+		Name:      field("name"),
+		ShortCode: field("code"),
+		League:    field("league"),
+		Country:   field("country"),
+		Crest:     field("crest"),
+	}
+	if err := validateTeamName(team.Name); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if team.SourceID, err = number("id"); err != nil {
+		return nil, err
+	}
+	if team.Overall, err = number("overall"); err != nil {
+		return nil, err
+	}
+	if team.Attack, err = number("attack"); err != nil {
+		return nil, err
+	}
+	if team.Midfield, err = number("midfield"); err != nil {
+		return nil, err
+	}
+	if team.Defence, err = number("defence"); err != nil {
+		return nil, err
+	}
+
+	if stars := field("stars"); stars != "" {
+		team.Stars, err = strconv.ParseFloat(strings.Replace(stars, ",", ".", 1), 64)
+		if err != nil || team.Stars < 0 || team.Stars > 5 {
+			return nil, fmt.Errorf("invalid stars: %s", stars)
+		}
+	} else {
+		team.Stars = starsFromOverall(team.Strength())
+	}
+	return team, nil
+}
+
+// errSourceIDConflict — команда с таким названием уже загружена из выгрузки под другим ID
+var errSourceIDConflict = errors.New("team is already imported under another source ID")
+
+// upsertTeam сохраняет команду и сообщает, была ли она создана. Команда ищется по ID
+// из выгрузки, а если его нет в справочнике — по названию: так команда, добавленная
+// вручную или из файла без ID, получает ID из выгрузки. Новым командам ID выдает счетчик.
+func upsertTeam(team *db.Team, crestDir string, dryRun bool) (bool, error) {
+	var existing *db.Team
+	var err error
+	if team.SourceID > 0 {
+		existing, err = getTeamBySourceID(team.SourceID)
+		if err != nil {
+			return false, err
+		}
+	}
+	if existing == nil {
+		existing, err = db.FindTeam(team.Name)
+		if err != nil {
+			return false, err
+		}
+		if existing != nil && existing.SourceID > 0 && team.SourceID > 0 {
+			return false, fmt.Errorf("%s: %w %d", existing.Name, errSourceIDConflict, existing.SourceID)
+		}
+	}
+	if dryRun {
+		return existing == nil, nil
+	}
+
+	if crestDir != "" && team.SourceID > 0 {
+		crest := filepath.Join(crestDir, fmt.Sprintf("%d.png", team.SourceID))
+		if _, err := os.Stat(crest); err == nil {
+			team.Crest = crest
+		}
+	}
+
+	fields := bson.M{
+		"name":       team.Name,
+		"stars":      team.Stars,
+		"short_code": team.ShortCode,
+		"league":     team.League,
+		"country":    team.Country,
+		"overall":    team.Overall,
+		"attack":     team.Attack,
+		"midfield":   team.Midfield,
+		"defence":    team.Defence,
+		"updated_at": time.Now(),
+	}
+	if team.SourceID > 0 {
+		// Найденная по названию команда сохраняет свой ID справочника и получает ID из выгрузки
+		fields["source_id"] = team.SourceID
+	}
+	if team.Crest != "" {
+		fields["crest"] = team.Crest
+	}
+
+	if existing != nil {
+		team.ID = existing.ID
+		_, err = db.DB.Collection("teams").UpdateOne(context.TODO(), bson.M{"id": team.ID}, bson.M{"$set": fields})
+	} else if team.ID, err = nextSequence(teamIDSequence); err == nil {
+		fields["id"] = team.ID
+		_, err = db.DB.Collection("teams").InsertOne(context.TODO(), fields)
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return false, fmt.Errorf("another team is already named %s", team.Name)
+	}
+	if err != nil {
+		return false, err
+	}
+	return existing == nil, nil
+}
+
+func getTeamBySourceID(sourceID int) (*db.Team, error) {
+	var team db.Team
+	err := db.DB.Collection("teams").FindOne(context.TODO(), bson.M{"source_id": sourceID}).Decode(&team)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// AddTeamAlias добавляет команде справочника другое название, чтобы старые категории
+// со списком названий находили ее рейтинги
+func AddTeamAlias(teamName, alias string) error {
+	if err := validateTeamName(alias); err != nil {
+		return err
+	}
+	team, err := db.FindTeam(teamName)
+	if err != nil {
+		return err
+	}
+	if team == nil {
+		return fmt.Errorf("team %s is not in the catalog", teamName)
+	}
+	other, err := db.FindTeam(alias)
+	if err != nil {
+		return err
+	}
+	if other != nil && other.ID != team.ID {
+		return fmt.Errorf("%s already refers to %s", alias, other.Name)
+	}
+
+	_, err = db.DB.Collection("teams").UpdateOne(context.TODO(), bson.M{"id": team.ID},
+		bson.M{"$addToSet": bson.M{"aliases": alias}})
+	return err
+}
+
+// ParseTeamQuery разбирает условия вида "stars 4.5", "stars 4-5", "league Premier League",
+// "country England"
+func ParseTeamQuery(conditions []string) (*db.TeamQuery, error) {
+	query := &db.TeamQuery{}
+	for _, condition := range conditions {
+		key, value, _ := strings.Cut(strings.TrimSpace(condition), " ")
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, fmt.Errorf("condition %q has no value", condition)
+		}
+
+		switch strings.ToLower(key) {
+		case "stars":
+			from, to, isRange := strings.Cut(value, "-")
+			minStars, err1 := strconv.ParseFloat(strings.TrimSpace(from), 64)
+			maxStars, err2 := minStars, error(nil)
+			if isRange {
+				maxStars, err2 = strconv.ParseFloat(strings.TrimSpace(to), 64)
+			}
+			if err1 != nil || err2 != nil || minStars <= 0 || maxStars < minStars || maxStars > 5 {
+				return nil, fmt.Errorf("invalid stars: %s", value)
+			}
+			query.MinStars, query.MaxStars = minStars, maxStars
+		case "league":
+			query.League = value
+		case "country":
+			query.Country = value
+		default:
+			return nil, fmt.Errorf("unknown condition %q, use stars, league or country", key)
+		}
+	}
+	return query, nil
+}
+
+// IsTeamQueryCondition сообщает, что аргумент — условие запроса, а не название команды
+func IsTeamQueryCondition(arg string) bool {
+	key, _, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	switch strings.ToLower(key) {
+	case "stars", "league", "country":
+		return true
+	}
+	return false
+}
+
+// CreateCatalogCategory создает категорию из справочника: по сохраненному запросу
+// (query не nil) или по списку команд справочника
+func CreateCatalogCategory(name string, query *db.TeamQuery, teamNames []string) error {
+	if err := validateCategoryName(name); err != nil {
+		return err
+	}
+
+	category := db.TeamCategory{Name: name, Query: query}
+	if query == nil {
+		for _, teamName := range teamNames {
+			team, err := db.FindTeam(teamName)
+			if err != nil {
+				return err
+			}
+			if team == nil {
+				return fmt.Errorf("team %s is not in the catalog", teamName)
+			}
+			if slices.Contains(category.TeamIDs, team.ID) {
+				return fmt.Errorf("team %s is listed twice", team.Name)
+			}
+			category.TeamIDs = append(category.TeamIDs, team.ID)
+		}
+	}
+
+	// Проверяем, сколько команд попадет в категорию
+	if err := db.ResolveCategoryTeams(&category); err != nil {
+		return err
+	}
+	if len(category.Teams) < minCategoryTeams {
+		return fmt.Errorf("the category would have %d teams, at least %d are needed", len(category.Teams), minCategoryTeams)
+	}
+	stored := category
+	stored.Teams = nil
+
+	_, err := db.DB.Collection("team_categories").InsertOne(context.TODO(), stored)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("team category %s already exists", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Catalog team category created", "category", name, "teams", len(category.Teams))
+	return nil
+}
+
+// DescribeTeamQuery описывает запрос категории для списка категорий
+func DescribeTeamQuery(query *db.TeamQuery) string {
+	var parts []string
+	if query.MinStars > 0 || query.MaxStars > 0 {
+		if query.MinStars == query.MaxStars {
+			parts = append(parts, fmt.Sprintf("%g★", query.MinStars))
+		} else {
+			parts = append(parts, fmt.Sprintf("%g-%g★", query.MinStars, query.MaxStars))
+		}
+	}
+	if query.League != "" {
+		parts = append(parts, query.League)
+	}
+	if query.Country != "" {
+		parts = append(parts, query.Country)
+	}
+	if len(parts) == 0 {
+		return "all catalog teams"
+	}
+	return strings.Join(parts, ", ")
+}
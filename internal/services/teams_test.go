@@ -0,0 +1,32 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestStarsFromOverall(t *testing.T) {
+	tests := []struct {
+		overall int
+		want    float64
+	}{
+		{overall: 0, want: 0},
+		{overall: -5, want: 0},
+		{overall: 99, want: 5},
+		{overall: 82, want: 5},
+		{overall: 81, want: 4.5},
+		{overall: 79, want: 4.5},
+		{overall: 78, want: 4},
+		{overall: 76, want: 4},
+		{overall: 75, want: 3.5},
+		{overall: 60, want: 1},
+		{overall: 58, want: 1},
+		{overall: 57, want: 0.5},
+		{overall: 30, want: 0.5},
+	}
+
+	for _, tt := range tests {
+		if got := starsFromOverall(tt.overall); got != tt.want {
+			t.Errorf("starsFromOverall(%d) = %g, want %g", tt.overall, got, tt.want)
+		}
+	}
+}
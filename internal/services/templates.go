@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log/slog"
+	"time"
+	"tournament-bot/internal/db"
+)
+
+const (
+	minPlayoffQualifiers = 2
+	maxPlayoffQualifiers = 4
+	// maxTemplateNameLength ограничивает имя шаблона: оно передается в данных кнопки
+	maxTemplateNameLength = 32
+)
+
+// DefaultTournamentSettings — настройки еженедельного турнира, которые раньше были зашиты в код
+func DefaultTournamentSettings() db.TournamentSettings {
+	return db.TournamentSettings{
+		Name:              "Tournament",
+		MinParticipants:   5,
+		MaxParticipants:   6,
+		Format:            db.FormatRoundRobin,
+		PlayoffQualifiers: 4,
+		PointsRules:       db.DefaultPointsRules,
+		DrawStrategy:      db.DrawRandom,
+	}
+}
+
+// ValidateTournamentSettings проверяет, что с такими настройками турнир можно провести
+func ValidateTournamentSettings(settings db.TournamentSettings) error {
+	if settings.Name == "" {
+		return errors.New("tournament name is empty")
+	}
+	if settings.MinParticipants < minPlayoffQualifiers {
+		return fmt.Errorf("a tournament needs at least %d participants", minPlayoffQualifiers)
+	}
+	if settings.MinParticipants > settings.MaxParticipants {
+		return errors.New("minimum participants exceeds maximum")
+	}
+	if settings.Format != db.FormatRoundRobin && settings.Format != db.FormatDoubleRoundRobin {
+		return fmt.Errorf("unknown format: %s", settings.Format)
+	}
+	if settings.PlayoffQualifiers < minPlayoffQualifiers || settings.PlayoffQualifiers > maxPlayoffQualifiers {
+		return fmt.Errorf("playoff qualifiers must be between %d and %d", minPlayoffQualifiers, maxPlayoffQualifiers)
+	}
+	if settings.PlayoffQualifiers > settings.MinParticipants {
+		return errors.New("more playoff qualifiers than minimum participants")
+	}
+	rules := settings.PointsRules
+	if rules.Win <= rules.Draw || rules.Draw < rules.Loss || rules.Loss < 0 {
+		return errors.New("points must satisfy win > draw >= loss >= 0")
+	}
+
+	if _, ok := drawStrategies[settings.DrawStrategy]; settings.DrawStrategy != "" && !ok {
+		return fmt.Errorf("unknown draw strategy: %s", settings.DrawStrategy)
+	}
+
+	if settings.FreeChoice {
+		return nil
+	}
+	if settings.TeamCategory == "" {
+		return errors.New("choose a team category or free choice")
+	}
+	return checkCategorySize(settings.TeamCategory, settings.MaxParticipants)
+}
+
+// SaveTournamentTemplate сохраняет настройки под именем шаблона, заменяя прежние
+func SaveTournamentTemplate(name string, settings db.TournamentSettings) error {
+	if name == "" || len(name) > maxTemplateNameLength {
+		return fmt.Errorf("template name must be 1-%d characters long", maxTemplateNameLength)
+	}
+	if err := ValidateTournamentSettings(settings); err != nil {
+		return err
+	}
+
+	template := db.TournamentTemplate{Name: name, Settings: settings, UpdatedAt: time.Now()}
+	opts := options.Replace().SetUpsert(true)
+	_, err := db.DB.Collection("tournament_templates").ReplaceOne(context.TODO(), bson.M{"name": name}, template, opts)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Tournament template saved", "template", name)
+	return nil
+}
+
+func GetTournamentTemplates() ([]db.TournamentTemplate, error) {
+	opts := options.Find().SetSort(bson.M{"name": 1})
+	cursor, err := db.DB.Collection("tournament_templates").Find(context.TODO(), bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []db.TournamentTemplate
+	if err := cursor.All(context.TODO(), &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// GetTournamentTemplate возвращает шаблон по имени или nil, если его нет
+func GetTournamentTemplate(name string) (*db.TournamentTemplate, error) {
+	var template db.TournamentTemplate
+	err := db.DB.Collection("tournament_templates").FindOne(context.TODO(), bson.M{"name": name}).Decode(&template)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func DeleteTournamentTemplate(name string) error {
+	result, err := db.DB.Collection("tournament_templates").DeleteOne(context.TODO(), bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("template not found")
+	}
+	return nil
+}
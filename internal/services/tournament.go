@@ -7,10 +7,8 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"log"
-	"math/rand/v2"
+	"log/slog"
 	"sort"
-	"strings"
 	"time"
 	"tournament-bot/internal/db"
 	"tournament-bot/internal/notifications"
@@ -28,26 +26,44 @@ func GetActiveTournament() (*db.Tournament, error) {
 	return &tournament, nil
 }
 
-func CreateTournament() (*db.Tournament, error) {
+// CreateTournament создает турнир с настройками из мастера или шаблона
+func CreateTournament(settings db.TournamentSettings) (*db.Tournament, error) {
+	err := ValidateTournamentSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
 	today := time.Now().Format("2006-01-02")
-	tournamentName := fmt.Sprintf("%s Tournament #%d", today, getNextTournamentNumber(today))
+	tournamentName := fmt.Sprintf("%s %s #%d", today, settings.Name, getNextTournamentNumber(today))
+
+	tournamentID, err := getNextTournamentID()
+	if err != nil {
+		return nil, err
+	}
 
 	tournament := &db.Tournament{
-		ID:               getNextTournamentID(),
-		Name:             tournamentName,
-		Participants:     []string{},
-		MinParticipants:  5,
-		MaxParticipants:  6,
-		ParticipantTeams: make(map[string]string),
-		Matches:          []db.Match{},
-		Standings:        []db.Standing{},
-		IsActive:         false,
-		SetupCompleted:   false,
-		CreatedAt:        time.Now(),
-		IsCompleted:      false,
-	}
-
-	_, err := db.DB.Collection("tournaments").InsertOne(context.TODO(), tournament)
+		ID:                tournamentID,
+		Name:              tournamentName,
+		Participants:      []string{},
+		MinParticipants:   settings.MinParticipants,
+		MaxParticipants:   settings.MaxParticipants,
+		TeamCategory:      settings.TeamCategory,
+		ParticipantTeams:  make(map[string]string),
+		Matches:           []db.Match{},
+		Standings:         []db.Standing{},
+		IsActive:          false,
+		SetupCompleted:    false,
+		CreatedAt:         time.Now(),
+		IsCompleted:       false,
+		Format:            settings.Format,
+		PlayoffQualifiers: settings.PlayoffQualifiers,
+		PointsRules:       settings.PointsRules,
+		FreeChoice:        settings.FreeChoice,
+		DrawStrategy:      settings.DrawStrategy,
+		DrawCeremony:      settings.DrawCeremony,
+	}
+
+	_, err = db.DB.Collection("tournaments").InsertOne(context.TODO(), tournament)
 	if err != nil {
 		return nil, err
 	}
@@ -80,26 +96,38 @@ func DeleteTournament(tournamentID int) error {
 	return nil
 }
 
-func ToggleParticipant(tournamentID int, participantName string) error {
+// ToggleParticipant добавляет участника в турнир или убирает его. При удалении место
+// занимает первый из листа ожидания; его имя возвращается.
+func ToggleParticipant(tournamentID int, participantName string) (string, error) {
 	filter := bson.M{"id": tournamentID}
 	var tournament db.Tournament
 	err := db.DB.Collection("tournaments").FindOne(context.TODO(), filter).Decode(&tournament)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	update := bson.M{}
-	if tournament.HasParticipant(participantName) {
+	removed := tournament.HasParticipant(participantName)
+	if removed {
 		update["$pull"] = bson.M{"participants": participantName}
 	} else {
 		if len(tournament.Participants) >= tournament.MaxParticipants {
-			return fmt.Errorf("tournament has reached the maximum number of participants (%d)", tournament.MaxParticipants)
+			return "", fmt.Errorf("tournament has reached the maximum number of participants (%d)", tournament.MaxParticipants)
 		}
 		update["$addToSet"] = bson.M{"participants": participantName}
+		// Админ может добавить игрока из листа ожидания в обход очереди
+		update["$pull"] = bson.M{"waitlist": participantName}
 	}
 
 	_, err = db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter, update)
-	return err
+	if err != nil {
+		return "", err
+	}
+
+	if !removed {
+		return "", nil
+	}
+	return promoteFromWaitlist(tournamentID)
 }
 
 func StartTournament(tournamentID int) (*db.Tournament, error) {
@@ -118,7 +146,7 @@ func StartTournament(tournamentID int) (*db.Tournament, error) {
 		return nil, fmt.Errorf("tournament exceeds the maximum limit of %d participants", tournament.MaxParticipants)
 	}
 
-	if tournament.TeamCategory == "" {
+	if tournament.TeamCategory == "" && !tournament.FreeChoice {
 		return nil, fmt.Errorf("tournament team category is not set")
 	}
 
@@ -126,17 +154,28 @@ func StartTournament(tournamentID int) (*db.Tournament, error) {
 		return nil, fmt.Errorf("tournament is already active")
 	}
 
+	if tournament.CheckInInProgress() {
+		return nil, fmt.Errorf("check-in is still open, close it with /close_checkin first")
+	}
+
 	// Проверяем условия настройки турнира
 	setupCompleted := len(tournament.Participants) >= tournament.MinParticipants &&
 		len(tournament.Participants) <= tournament.MaxParticipants &&
-		tournament.TeamCategory != ""
+		(tournament.TeamCategory != "" || tournament.FreeChoice)
 
-	update := bson.M{
-		"$set": bson.M{
-			"is_active":       true,
-			"setup_completed": setupCompleted,
-		},
+	// Турнир относится к сезону, в котором он начался
+	season, err := GetActiveSeason()
+	if err != nil {
+		return nil, err
 	}
+	set := bson.M{
+		"is_active":       true,
+		"setup_completed": setupCompleted,
+	}
+	if season != nil {
+		set["season_id"] = season.ID
+	}
+	update := bson.M{"$set": set}
 	_, err = db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		return nil, err
@@ -155,14 +194,138 @@ func StartTournament(tournamentID int) (*db.Tournament, error) {
 	return &tournament, nil
 }
 
-func getNextTournamentID() int {
+const tournamentIDSequence = "tournament_id"
+
+// getNextTournamentID выдает следующий ID турнира из последовательности в коллекции counters.
+// Перед увеличением счетчик подтягивается к максимальному существующему ID, чтобы
+// последовательность продолжила нумерацию турниров, созданных до ее появления.
+func getNextTournamentID() (int, error) {
 	var lastTournament db.Tournament
-	opts := options.FindOne().SetSort(bson.D{{"id", -1}})
-	err := db.DB.Collection("tournaments").FindOne(context.TODO(), bson.D{}, opts).Decode(&lastTournament)
+	opts := options.FindOne().SetSort(bson.M{"id": -1}).SetProjection(bson.M{"id": 1})
+	err := db.DB.Collection("tournaments").FindOne(context.TODO(), bson.M{}, opts).Decode(&lastTournament)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return 0, err
+	}
+
+	// $max не уменьшает значение, поэтому безопасен при одновременных вызовах
+	_, err = db.DB.Collection("counters").UpdateOne(context.TODO(),
+		bson.M{"_id": tournamentIDSequence},
+		bson.M{"$max": bson.M{"seq": lastTournament.ID}},
+		options.Update().SetUpsert(true))
 	if err != nil {
-		return 1
+		return 0, err
+	}
+
+	return nextSequence(tournamentIDSequence)
+}
+
+// nextSequence атомарно увеличивает счетчик и возвращает новое значение
+func nextSequence(name string) (int, error) {
+	filter := bson.M{"_id": name}
+	update := bson.M{"$inc": bson.M{"seq": 1}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var result struct {
+		Seq int `bson:"seq"`
+	}
+
+	err := db.DB.Collection("counters").FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&result)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.Seq, nil
+}
+
+// TournamentIDChange — турнир, получивший новый ID при исправлении дубликатов
+type TournamentIDChange struct {
+	Name  string
+	OldID int
+	NewID int
+}
+
+// RepairDuplicateTournamentIDs находит турниры с одинаковым ID и перенумеровывает все,
+// кроме самого раннего по дате создания. Новые ID берутся из той же последовательности,
+// что и при создании турниров. В режиме dryRun изменения не записываются, а новые ID
+// в отчете не заполняются.
+func RepairDuplicateTournamentIDs(dryRun bool) ([]TournamentIDChange, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$id", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := db.DB.Collection("tournaments").Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []struct {
+		ID int `bson:"_id"`
+	}
+	if err := cursor.All(context.TODO(), &groups); err != nil {
+		return nil, err
+	}
+
+	var changes []TournamentIDChange
+	for _, group := range groups {
+		opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+		cursor, err := db.DB.Collection("tournaments").Find(context.TODO(), bson.M{"id": group.ID}, opts)
+		if err != nil {
+			return changes, err
+		}
+
+		var duplicates []struct {
+			ObjectID interface{} `bson:"_id"`
+			Name     string      `bson:"name"`
+		}
+		if err := cursor.All(context.TODO(), &duplicates); err != nil {
+			return changes, err
+		}
+
+		// Первый турнир сохраняет свой ID
+		for _, duplicate := range duplicates[1:] {
+			change := TournamentIDChange{Name: duplicate.Name, OldID: group.ID}
+
+			if !dryRun {
+				change.NewID, err = getNextTournamentID()
+				if err != nil {
+					return changes, err
+				}
+
+				_, err = db.DB.Collection("tournaments").UpdateOne(context.TODO(),
+					bson.M{"_id": duplicate.ObjectID},
+					bson.M{"$set": bson.M{"id": change.NewID}})
+				if err != nil {
+					return changes, err
+				}
+				slog.Info("Tournament renumbered", "name", change.Name, "old_id", change.OldID, "new_id", change.NewID)
+			}
+
+			changes = append(changes, change)
+		}
 	}
-	return lastTournament.ID + 1
+
+	return changes, nil
+}
+
+// Сколько хранится турнир, который так и не начался
+const abandonedTournamentAge = 24 * time.Hour
+
+// Abandoned сообщает, что турнир брошен и его можно удалить: он не завершен, не идет,
+// создан больше суток назад, запись на него закрыта и сроки записи и подтверждения
+// участия уже прошли
+func Abandoned(tournament *db.Tournament, now time.Time) bool {
+	if tournament.IsCompleted || (tournament.SetupCompleted && tournament.IsActive) {
+		return false
+	}
+	if now.Sub(tournament.CreatedAt) <= abandonedTournamentAge {
+		return false
+	}
+	if tournament.RegistrationOpen(now) {
+		return false
+	}
+	return !now.Before(tournament.RegistrationDeadline) && !now.Before(tournament.CheckInDeadline)
 }
 
 func GetInactiveTournaments() ([]*db.Tournament, error) {
@@ -199,75 +362,48 @@ func GetTournament(tournamentID int) (*db.Tournament, error) {
 	return &tournament, nil
 }
 
+// SetTournamentTeamCategory меняет категорию команд турнира, если в ней хватит команд
+// на всех участников. После публикации хэша жеребьевки категорию не изменить: команды
+// категории входят в хэш.
 func SetTournamentTeamCategory(tournamentID int, categoryName string) error {
-	filter := bson.M{"id": tournamentID}
-	update := bson.M{"$set": bson.M{"team_category": categoryName}}
-
-	_, err := db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter, update)
-	return err
-}
-
-func PerformTeamDraw(tournamentID int) (string, error) {
 	tournament, err := GetTournament(tournamentID)
 	if err != nil {
-		return "", err
+		return err
 	}
-
-	category, err := db.GetTeamCategoryByName(tournament.TeamCategory)
+	err = checkCategorySize(categoryName, tournament.MaxParticipants)
 	if err != nil {
-		return "", err
-	}
-
-	// Перемешиваем список команд
-	teams := make([]string, len(category.Teams))
-	copy(teams, category.Teams)
-	rand.Shuffle(len(teams), func(i, j int) {
-		teams[i], teams[j] = teams[j], teams[i]
-	})
-
-	// Назначаем команды участникам турнира
-	var drawResult strings.Builder
-	participantTeams := make(map[string]string)
-	for i, participant := range tournament.Participants {
-		if i >= len(teams) {
-			return "", fmt.Errorf("not enough teams for all participants")
-		}
-		team := teams[i]
-		participantTeams[participant] = team
-		drawResult.WriteString(fmt.Sprintf("%s - %s\n", participant, team))
+		return err
 	}
 
-	// Создаем записи статистики только для команд участников
-	standings := make([]db.Standing, 0)
-	for _, team := range participantTeams {
-		standing := db.Standing{
-			Team:            team,
-			Played:          0,
-			Won:             0,
-			Drawn:           0,
-			Lost:            0,
-			GoalsFor:        0,
-			GoalsAgainst:    0,
-			GoalsDifference: 0,
-			Points:          0,
-		}
-		standings = append(standings, standing)
+	filter := bson.M{"id": tournamentID, "$or": []bson.M{
+		{"draw.published_at": bson.M{"$exists": false}},
+		{"team_category": categoryName},
+	}}
+	update := bson.M{"$set": bson.M{"team_category": categoryName}}
+	if tournament.TeamCategory != categoryName {
+		// Неопубликованная жеребьевка зафиксирована с командами прежней категории
+		filter = bson.M{"id": tournamentID, "team_category": tournament.TeamCategory,
+			"draw.published_at": bson.M{"$exists": false}}
+		update["$unset"] = bson.M{"draw": "", "draw_seed": ""}
 	}
 
-	// Обновляем турнир в базе данных с новыми записями статистики команд и назначенными командами
-	filter := bson.M{"id": tournamentID}
-	update := bson.M{
-		"$set": bson.M{
-			"standings":         standings,
-			"participant_teams": participantTeams,
-		},
-	}
-	_, err = db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter, update)
+	result, err := db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter, update)
 	if err != nil {
-		return "", err
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("the draw has already been published, the category cannot be changed")
 	}
+	return nil
+}
 
-	return drawResult.String(), nil
+// newStandings создает пустую турнирную таблицу для команд участников
+func newStandings(participantTeams map[string]string) []db.Standing {
+	standings := make([]db.Standing, 0, len(participantTeams))
+	for _, team := range participantTeams {
+		standings = append(standings, db.Standing{Team: team})
+	}
+	return standings
 }
 
 func GetActiveTournaments() ([]*db.Tournament, error) {
@@ -302,7 +438,7 @@ func getNextTournamentNumber(date string) int {
 
 	err := db.DB.Collection("tournament_counters").FindOneAndUpdate(context.TODO(), filter, update, opts).Decode(&result)
 	if err != nil {
-		log.Printf("Error getting next tournament number: %v", err)
+		slog.Error("Error getting next tournament number", "date", date, "err", err)
 		return 1
 	}
 
@@ -331,11 +467,13 @@ func AddMatchResult(tournamentID int, team1, team2 string, score1, score2 int) e
 		return err
 	}
 
+	recordMatchRating(tournamentID, match)
+
 	tournament, err := GetActiveTournament()
 
 	err = notifications.SendMatchResultMessage(tournament, &match)
 	if err != nil {
-		log.Printf("Error sending match result message: %v", err)
+		slog.Error("Error sending match result message", "tournament_id", tournamentID, "err", err)
 	}
 
 	return nil
@@ -360,34 +498,7 @@ func updateStandings(tournamentID int) error {
 		}
 
 		// Обновляем статистику для каждой команды матча
-		standing1 := standingsMap[match.Team1]
-		standing2 := standingsMap[match.Team2]
-
-		standing1.Played++
-		standing2.Played++
-
-		standing1.GoalsFor += match.Score1
-		standing1.GoalsAgainst += match.Score2
-		standing2.GoalsFor += match.Score2
-		standing2.GoalsAgainst += match.Score1
-
-		standing1.GoalsDifference = standing1.GoalsFor - standing1.GoalsAgainst
-		standing2.GoalsDifference = standing2.GoalsFor - standing2.GoalsAgainst
-
-		if match.Score1 > match.Score2 {
-			standing1.Won++
-			standing1.Points += 3
-			standing2.Lost++
-		} else if match.Score1 < match.Score2 {
-			standing1.Lost++
-			standing2.Won++
-			standing2.Points += 3
-		} else {
-			standing1.Drawn++
-			standing1.Points++
-			standing2.Drawn++
-			standing2.Points++
-		}
+		applyMatchToStandings(standingsMap[match.Team1], standingsMap[match.Team2], match, tournament.Points(), 1)
 
 		// Помечаем матч как учтенный
 
@@ -503,6 +614,15 @@ func DeleteLastMatch(tournamentID int, stageType string) error {
 		return err
 	}
 
+	// Матч уже удален, поэтому ошибка рейтинга не отменяет удаление: если откатить
+	// изменение рейтинга не удалось, рейтинги пересчитываются по всем матчам
+	if err := revertMatchRating(&tournament, deletedMatch); err != nil {
+		slog.Warn("Error reverting player ratings, recalculating", "tournament_id", tournamentID, "err", err)
+		if _, err := RecalculateRatings(); err != nil {
+			return fmt.Errorf("%w: %v", ErrRatingsOutdated, err)
+		}
+	}
+
 	return nil
 }
 
@@ -521,34 +641,7 @@ func recalculateStandingsAfterDeletion(tournamentID int, deletedMatch *db.Match)
 	}
 
 	// Обновление статистики для команд удаленного матча
-	standing1 := standingsMap[deletedMatch.Team1]
-	standing2 := standingsMap[deletedMatch.Team2]
-
-	standing1.Played--
-	standing2.Played--
-
-	standing1.GoalsFor -= deletedMatch.Score1
-	standing1.GoalsAgainst -= deletedMatch.Score2
-	standing2.GoalsFor -= deletedMatch.Score2
-	standing2.GoalsAgainst -= deletedMatch.Score1
-
-	standing1.GoalsDifference = standing1.GoalsFor - standing1.GoalsAgainst
-	standing2.GoalsDifference = standing2.GoalsFor - standing2.GoalsAgainst
-
-	if deletedMatch.Score1 > deletedMatch.Score2 {
-		standing1.Won--
-		standing1.Points -= 3
-		standing2.Lost--
-	} else if deletedMatch.Score1 < deletedMatch.Score2 {
-		standing1.Lost--
-		standing2.Won--
-		standing2.Points -= 3
-	} else {
-		standing1.Drawn--
-		standing1.Points--
-		standing2.Drawn--
-		standing2.Points--
-	}
+	applyMatchToStandings(standingsMap[deletedMatch.Team1], standingsMap[deletedMatch.Team2], *deletedMatch, tournament.Points(), -1)
 
 	// Сохранение обновленных standings в базе данных
 	filter := bson.M{"id": tournamentID}
@@ -565,7 +658,7 @@ func GetTournamentStandings(tournamentID int) []db.Standing {
 	var tournament db.Tournament
 	err := db.DB.Collection("tournaments").FindOne(context.TODO(), bson.M{"id": tournamentID}).Decode(&tournament)
 	if err != nil {
-		log.Printf("Error getting tournament standings: %v", err)
+		slog.Error("Error getting tournament standings", "tournament_id", tournamentID, "err", err)
 		return nil
 	}
 	return tournament.Standings
@@ -575,7 +668,7 @@ func GetTournamentMatches(tournamentID int) []db.Match {
 	var tournament db.Tournament
 	err := db.DB.Collection("tournaments").FindOne(context.TODO(), bson.M{"id": tournamentID}).Decode(&tournament)
 	if err != nil {
-		log.Printf("Error getting tournament matches: %v", err)
+		slog.Error("Error getting tournament matches", "tournament_id", tournamentID, "err", err)
 		return nil
 	}
 	return tournament.Matches
@@ -633,38 +726,17 @@ func StartPlayoff(tournamentID int) error {
 		return standings[i].Team < standings[j].Team
 	})
 
-	// Получаем команды, занявшие соответствующие места
+	// Получаем команды, занявшие соответствующие места; снявшиеся в плей-офф не выходят
 	var teams []string
-	for i := 0; i < 4 && i < len(standings); i++ {
+	for i := 0; len(teams) < tournament.PlayoffTeams() && i < len(standings); i++ {
+		if tournament.IsTeamWithdrawn(standings[i].Team) {
+			continue
+		}
 		teams = append(teams, standings[i].Team)
 	}
 
 	// Создаем структуру плей-офф
-
-	playoff := &db.Playoff{
-		CurrentStage: "quarter",
-	}
-
-	// Команда, занявшая первое место, выходит в финал
-	if len(teams) >= 1 {
-		playoff.Final = &db.Match{
-			Team1: teams[0],
-		}
-	}
-
-	// Команда, занявшая второе место, выходит в полуфинал
-	if len(teams) >= 2 {
-		playoff.SemiFinals = []db.Match{
-			{Team1: teams[1]},
-		}
-	}
-
-	// Команды, занявшие третье и четвертое места, играют в четвертьфинале
-	if len(teams) >= 4 {
-		playoff.QuarterFinals = []db.Match{
-			{Team1: teams[2], Team2: teams[3]},
-		}
-	}
+	playoff := newPlayoff(teams)
 
 	// Сохраняем структуру плей-офф в турнире
 	tournament.Playoff = playoff
@@ -677,29 +749,55 @@ func StartPlayoff(tournamentID int) error {
 
 	err = notifications.SendPlayoffStartMessage(tournament)
 	if err != nil {
-		log.Printf("Error sending playoff start message: %v", err)
+		slog.Error("Error sending playoff start message", "tournament_id", tournamentID, "err", err)
 	}
 
 	return nil
 }
 
+// newPlayoff строит лесенку плей-офф: первое место ждет в финале, второе — в полуфинале,
+// третье и четвертое играют четвертьфинал. При трех командах турнир начинается
+// с полуфинала второго и третьего мест, при двух — сразу с финала.
+func newPlayoff(teams []string) *db.Playoff {
+	playoff := &db.Playoff{}
+
+	switch {
+	case len(teams) >= 4:
+		playoff.CurrentStage = "quarter"
+		playoff.QuarterFinals = []db.Match{{Team1: teams[2], Team2: teams[3]}}
+		playoff.SemiFinals = []db.Match{{Team1: teams[1]}}
+		playoff.Final = &db.Match{Team1: teams[0]}
+	case len(teams) == 3:
+		playoff.CurrentStage = "semi"
+		playoff.SemiFinals = []db.Match{{Team1: teams[1], Team2: teams[2]}}
+		playoff.Final = &db.Match{Team1: teams[0]}
+	case len(teams) == 2:
+		playoff.CurrentStage = "final"
+		playoff.Final = &db.Match{Team1: teams[0], Team2: teams[1]}
+	default:
+		playoff.CurrentStage = "quarter"
+		if len(teams) == 1 {
+			playoff.Final = &db.Match{Team1: teams[0]}
+		}
+	}
+
+	return playoff
+}
+
 func getHeadToHeadResult(team1, team2 string, matches []db.Match) int {
 	team1Wins := 0
 	team2Wins := 0
 
 	for _, match := range matches {
-		if match.Team1 == team1 && match.Team2 == team2 {
-			if match.Score1 > match.Score2 {
-				team1Wins++
-			} else if match.Score1 < match.Score2 {
-				team2Wins++
-			}
-		} else if match.Team1 == team2 && match.Team2 == team1 {
-			if match.Score1 > match.Score2 {
-				team2Wins++
-			} else if match.Score1 < match.Score2 {
-				team1Wins++
-			}
+		isPair := (match.Team1 == team1 && match.Team2 == team2) || (match.Team1 == team2 && match.Team2 == team1)
+		if !isPair {
+			continue
+		}
+		switch match.WinnerTeam() {
+		case team1:
+			team1Wins++
+		case team2:
+			team2Wins++
 		}
 	}
 
@@ -719,7 +817,31 @@ func AddPlayoffMatch(tournamentID int, team1, team2 string, score1, score2, pena
 		return "", err
 	}
 
-	currentStage := tournament.Playoff.CurrentStage
+	match := db.Match{
+		Team1:         team1,
+		Team2:         team2,
+		Score1:        score1,
+		Score2:        score2,
+		ExtraTime:     extraTime,
+		Penalties:     penalties,
+		PenaltyScore1: penaltyScore1,
+		PenaltyScore2: penaltyScore2,
+		Date:          time.Now(),
+	}
+
+	stage, err := recordPlayoffMatch(tournament, match)
+	if err != nil {
+		return "", err
+	}
+	recordMatchRating(tournamentID, match)
+	return stage, nil
+}
+
+// recordPlayoffMatch записывает матч текущей стадии плей-офф и выводит победителя дальше.
+// Если матч не состоялся (прерван или перенесен), он сохраняется, но стадия не меняется
+// и матч нужно сыграть заново.
+func recordPlayoffMatch(tournament *db.Tournament, match db.Match) (string, error) {
+	tournamentID := tournament.ID
 
 	// Проверяем, что турнир не завершен
 	if tournament.IsCompleted {
@@ -731,17 +853,34 @@ func AddPlayoffMatch(tournamentID int, team1, team2 string, score1, score2, pena
 		return "", errors.New("playoff has not started")
 	}
 
-	match := db.Match{
-		Team1:         team1,
-		Team2:         team2,
-		Score1:        score1,
-		Score2:        score2,
-		ExtraTime:     extraTime,
-		Penalties:     penalties,
-		PenaltyScore1: penaltyScore1,
-		PenaltyScore2: penaltyScore2,
-		Counted:       true,
-		Date:          time.Now(),
+	currentStage := tournament.Playoff.CurrentStage
+	match.Counted = true
+
+	// Победитель: при ничьей без пенальти проходит вторая команда
+	winner := match.WinnerTeam()
+	if winner == "" && match.Decided() {
+		winner = match.Team2
+	}
+
+	if !match.Decided() {
+		switch currentStage {
+		case "quarter":
+			if len(tournament.Playoff.QuarterFinals) > 0 {
+				tournament.Playoff.QuarterFinals[0] = match
+			}
+		case "semi":
+			if len(tournament.Playoff.SemiFinals) > 0 {
+				tournament.Playoff.SemiFinals[0] = match
+			}
+		case "final":
+			*tournament.Playoff.Final = match
+		}
+
+		err := UpdateTournament(tournament)
+		if err != nil {
+			return "", err
+		}
+		return currentStage, nil
 	}
 
 	// Обновляем текущую стадию плей-офф
@@ -753,13 +892,6 @@ func AddPlayoffMatch(tournamentID int, team1, team2 string, score1, score2, pena
 
 			// Переходим к полуфиналам
 			tournament.Playoff.CurrentStage = "semi"
-			// Определяем команду-победителя четвертьфинала
-			var winner string
-			if (score1 > score2) || (score1 == score2 && penaltyScore1 > penaltyScore2) {
-				winner = team1
-			} else {
-				winner = team2
-			}
 			// Добавляем победителя четвертьфинала в полуфинал
 			tournament.Playoff.SemiFinals = []db.Match{{Team1: tournament.Playoff.SemiFinals[0].Team1, Team2: winner}}
 		}
@@ -770,13 +902,6 @@ func AddPlayoffMatch(tournamentID int, team1, team2 string, score1, score2, pena
 
 			// Переходим к финалу
 			tournament.Playoff.CurrentStage = "final"
-			// Определяем команду-победителя полуфинала
-			var winner string
-			if (score1 > score2) || (score1 == score2 && penaltyScore1 > penaltyScore2) {
-				winner = team1
-			} else {
-				winner = team2
-			}
 			// Добавляем победителя полуфинала в финал
 			tournament.Playoff.Final = &db.Match{Team1: tournament.Playoff.Final.Team1, Team2: winner}
 		}
@@ -785,16 +910,12 @@ func AddPlayoffMatch(tournamentID int, team1, team2 string, score1, score2, pena
 		*tournament.Playoff.Final = match
 
 		// Определяем победителя турнира
-		if (score1 > score2) || (score1 == score2 && penaltyScore1 > penaltyScore2) {
-			tournament.Playoff.Winner = team1
-		} else {
-			tournament.Playoff.Winner = team2
-		}
+		tournament.Playoff.Winner = winner
 
 		tournament.IsCompleted = true
 		tournament.IsActive = false
 
-		err = UpdateParticipantStats(tournamentID, tournament)
+		err := UpdateParticipantStats(tournamentID, tournament)
 		if err != nil {
 			// Откатываем изменения турнира в случае ошибки
 			tournament.IsCompleted = false
@@ -802,7 +923,7 @@ func AddPlayoffMatch(tournamentID int, team1, team2 string, score1, score2, pena
 			tournament.Playoff.Winner = ""
 			updateErr := UpdateTournament(tournament)
 			if updateErr != nil {
-				log.Printf("failed to rollback tournament update: %v", updateErr)
+				slog.Error("Failed to rollback tournament update", "tournament_id", tournamentID, "err", updateErr)
 			}
 			return "", fmt.Errorf("failed to update participant stats: %v", err)
 		}
@@ -810,7 +931,7 @@ func AddPlayoffMatch(tournamentID int, team1, team2 string, score1, score2, pena
 	}
 
 	// Обновляем турнир в базе данных
-	err = UpdateTournament(tournament)
+	err := UpdateTournament(tournament)
 	if err != nil {
 		return "", err
 	}
@@ -877,11 +998,10 @@ func GetCurrentStageTeams(tournament *db.Tournament) []string {
 }
 
 func getWinner(match db.Match) string {
-	if match.Score1 > match.Score2 {
-		return match.Team1
-	} else {
-		return match.Team2
+	if winner := match.WinnerTeam(); winner != "" {
+		return winner
 	}
+	return match.Team2
 }
 
 func getQuarterFinalTeams(tournament *db.Tournament) []string {
@@ -906,190 +1026,55 @@ func getFinalTeams(tournament *db.Tournament) []string {
 }
 
 func UpdateParticipantStats(tournamentID int, tournament *db.Tournament) error {
-	for _, participant := range tournament.Participants {
-		var place string
-		var points int
-		var goalsScored, goalsConceded, wins, losses, draws, matchesPlayed int
-
-		// Получаем статистику участника в групповом этапе турнира
-		for _, match := range tournament.Matches {
-			if match.Team1 == tournament.ParticipantTeams[participant] || match.Team2 == tournament.ParticipantTeams[participant] {
-				matchesPlayed++
-				if match.Team1 == tournament.ParticipantTeams[participant] {
-					goalsScored += match.Score1
-					goalsConceded += match.Score2
-					if match.Score1 > match.Score2 {
-						wins++
-					} else if match.Score1 < match.Score2 {
-						losses++
-					} else {
-						draws++
-					}
-				} else {
-					goalsScored += match.Score2
-					goalsConceded += match.Score1
-					if match.Score2 > match.Score1 {
-						wins++
-					} else if match.Score2 < match.Score1 {
-						losses++
-					} else {
-						draws++
-					}
-				}
-			}
-		}
-
-		// Получаем статистику участника в матчах плей-офф
-		for _, match := range tournament.Playoff.QuarterFinals {
-			if match.Team1 == tournament.ParticipantTeams[participant] || match.Team2 == tournament.ParticipantTeams[participant] {
-				matchesPlayed++
-				if match.Team1 == tournament.ParticipantTeams[participant] {
-					goalsScored += match.Score1
-					goalsConceded += match.Score2
-					if match.Score1 > match.Score2 {
-						wins++
-					} else {
-						losses++
-					}
-				} else {
-					goalsScored += match.Score2
-					goalsConceded += match.Score1
-					if match.Score2 > match.Score1 {
-						wins++
-					} else {
-						losses++
-					}
-				}
-			}
-		}
-
-		for _, match := range tournament.Playoff.SemiFinals {
-			if match.Team1 == tournament.ParticipantTeams[participant] || match.Team2 == tournament.ParticipantTeams[participant] {
-				matchesPlayed++
-				if match.Team1 == tournament.ParticipantTeams[participant] {
-					goalsScored += match.Score1
-					goalsConceded += match.Score2
-					if match.Score1 > match.Score2 {
-						wins++
-					} else {
-						losses++
-					}
-				} else {
-					goalsScored += match.Score2
-					goalsConceded += match.Score1
-					if match.Score2 > match.Score1 {
-						wins++
-					} else {
-						losses++
-					}
-				}
-			}
-		}
-
-		if tournament.Playoff.Final != nil {
-			match := *tournament.Playoff.Final
-			if match.Team1 == tournament.ParticipantTeams[participant] || match.Team2 == tournament.ParticipantTeams[participant] {
-				matchesPlayed++
-				if match.Team1 == tournament.ParticipantTeams[participant] {
-					goalsScored += match.Score1
-					goalsConceded += match.Score2
-					if match.Score1 > match.Score2 {
-						wins++
-					} else {
-						losses++
-					}
-				} else {
-					goalsScored += match.Score2
-					goalsConceded += match.Score1
-					if match.Score2 > match.Score1 {
-						wins++
-					} else {
-						losses++
-					}
-				}
-			}
-		}
-
-		// Определяем место участника в плей-офф и начисляем очки
-		if tournament.ParticipantTeams[participant] == tournament.Playoff.Winner {
-			place = "first"
-			points = 8
-		} else if participant == getSecondPlace(tournament) {
-			place = "second"
-			points = 4
-		} else if participant == getThirdPlace(tournament) {
-			place = "third"
-			points = 2
-		} else {
-			place = "group"
-		}
-
-		// Начисляем дополнительные очки за место в групповом этапе
-		groupStage := make([]db.Standing, len(tournament.Standings))
-		copy(groupStage, tournament.Standings)
-		sort.Slice(groupStage, func(i, j int) bool {
-			// Сравнение по количеству очков
-			if groupStage[i].Points != groupStage[j].Points {
-				return groupStage[i].Points > groupStage[j].Points
-			}
-			// Сравнение по разнице забитых и пропущенных мячей
-			if groupStage[i].GoalsDifference != groupStage[j].GoalsDifference {
-				return groupStage[i].GoalsDifference > groupStage[j].GoalsDifference
-			}
-			// Сравнение по количеству забитых мячей
-			if groupStage[i].GoalsFor != groupStage[j].GoalsFor {
-				return groupStage[i].GoalsFor > groupStage[j].GoalsFor
-			}
-			// Сравнение по количеству сыгранных матчей
-			if groupStage[i].Played != groupStage[j].Played {
-				return groupStage[i].Played > groupStage[j].Played
-			}
-			// Сравнение по результатам личных встреч
-			headToHeadResult := getHeadToHeadResult(groupStage[i].Team, groupStage[j].Team, tournament.Matches)
-			if headToHeadResult != 0 {
-				return headToHeadResult > 0
-			}
-			// Сравнение по алфавиту
-			return groupStage[i].Team < groupStage[j].Team
-		})
+	scoring, err := seasonScoring(tournament.SeasonID)
+	if err != nil {
+		return err
+	}
 
-		for i, standing := range groupStage {
-			if standing.Team == tournament.ParticipantTeams[participant] {
-				if i < 3 {
-					points += 2
-				}
-				break
-			}
-		}
+	stats := tournamentStats(tournament, scoring)
+	for _, participant := range tournament.Participants {
+		stat := stats[participant]
 
 		// Обновляем статистику участника в базе данных
 		update := bson.M{
 			"$inc": bson.M{
-				"stats.total_points":       points,
-				"stats.goals_scored":       goalsScored,
-				"stats.goals_conceded":     goalsConceded,
-				"stats.wins":               wins,
-				"stats.losses":             losses,
-				"stats.draws":              draws,
-				"stats.matches_played":     matchesPlayed,
+				"stats.total_points":       stat.Points,
+				"stats.goals_scored":       stat.GoalsScored,
+				"stats.goals_conceded":     stat.GoalsConceded,
+				"stats.wins":               stat.Wins,
+				"stats.losses":             stat.Losses,
+				"stats.draws":              stat.Draws,
+				"stats.matches_played":     stat.MatchesPlayed,
 				"stats.tournaments_played": 1,
 			},
 			"$push": bson.M{
 				"stats.tournament_stats": bson.M{
 					"tournament_id":  tournamentID,
-					"place":          place,
-					"points":         points,
-					"goals_scored":   goalsScored,
-					"goals_conceded": goalsConceded,
-					"wins":           wins,
-					"losses":         losses,
-					"draws":          draws,
-					"matches_played": matchesPlayed,
+					"season_id":      stat.SeasonID,
+					"place":          stat.Place,
+					"position":       stat.Position,
+					"points":         stat.Points,
+					"goals_scored":   stat.GoalsScored,
+					"goals_conceded": stat.GoalsConceded,
+					"wins":           stat.Wins,
+					"losses":         stat.Losses,
+					"draws":          stat.Draws,
+					"matches_played": stat.MatchesPlayed,
 				},
 			},
 		}
 
-		_, err := db.DB.Collection("participants").UpdateOne(context.TODO(), bson.M{"name": participant}, update)
+		// Ищем по имени и прежним именам, а обновляем по постоянному ID
+		record, err := db.FindParticipant(participant)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			slog.Warn("Participant not found, stats not updated", "tournament_id", tournamentID, "participant", participant)
+			continue
+		}
+
+		_, err = db.DB.Collection("participants").UpdateOne(context.TODO(), bson.M{"_id": record.ID}, update)
 		if err != nil {
 			return err
 		}
@@ -1098,27 +1083,150 @@ func UpdateParticipantStats(tournamentID int, tournament *db.Tournament) error {
 	return nil
 }
 
-func getSecondPlace(tournament *db.Tournament) string {
+// tournamentStats подводит итоги турнира для каждого участника: итоговое место,
+// очки сезона по правилам scoring и статистику матчей
+func tournamentStats(tournament *db.Tournament, scoring db.SeasonScoring) map[string]db.TournamentStat {
+	groupStage := groupStageOrder(tournament)
+	placings := finalPlacings(tournament, groupStage)
+
+	stats := make(map[string]db.TournamentStat, len(tournament.Participants))
+	for _, participant := range tournament.Participants {
+		team := tournament.ParticipantTeams[participant]
+		totals := participantTotals(tournament, team)
+		placing := placings[participant]
+		stats[participant] = db.TournamentStat{
+			TournamentID: tournament.ID,
+			SeasonID:     tournament.SeasonID,
+			Place:        placing.label,
+			Position:     placing.position,
+			Points: ScoreTournament(scoring, TournamentResult{
+				Place:         placing.position,
+				GroupPosition: groupPosition(groupStage, team),
+				Wins:          totals.wins,
+				Draws:         totals.draws,
+			}),
+			GoalsScored:   totals.goalsScored,
+			GoalsConceded: totals.goalsConceded,
+			Wins:          totals.wins,
+			Losses:        totals.losses,
+			Draws:         totals.draws,
+			MatchesPlayed: totals.matchesPlayed,
+		}
+	}
+	return stats
+}
+
+// participantTotals собирает статистику команды в групповом этапе и в матчах плей-офф
+func participantTotals(tournament *db.Tournament, team string) matchTotals {
+	var totals matchTotals
+	for _, match := range tournament.Matches {
+		totals.add(match, team)
+	}
+	for _, match := range tournament.Playoff.QuarterFinals {
+		totals.add(match, team)
+	}
+	for _, match := range tournament.Playoff.SemiFinals {
+		totals.add(match, team)
+	}
 	if tournament.Playoff.Final != nil {
-		if tournament.Playoff.Final.Team1 != tournament.Playoff.Winner {
-			return getParticipantByTeam(tournament.ParticipantTeams, tournament.Playoff.Final.Team1)
-		} else {
-			return getParticipantByTeam(tournament.ParticipantTeams, tournament.Playoff.Final.Team2)
+		totals.add(*tournament.Playoff.Final, team)
+	}
+	return totals
+}
+
+// placing — итоговое место участника и этап, на котором он выбыл:
+// first, second, third (полуфинал), quarter_final или group
+type placing struct {
+	position int
+	label    string
+}
+
+// finalPlacings распределяет места 1..N: сначала по этапу плей-офф, до которого дошел
+// участник (чем дальше, тем выше), затем по месту в групповом этапе. Участники без
+// команды в таблице получают последние места.
+func finalPlacings(tournament *db.Tournament, groupStage []db.Standing) map[string]placing {
+	placings := make(map[string]placing, len(tournament.Participants))
+	place := func(participant, label string) {
+		if participant == "" {
+			return
+		}
+		if _, ok := placings[participant]; !ok {
+			placings[participant] = placing{position: len(placings) + 1, label: label}
 		}
 	}
-	return ""
+	// Все участники матчей этапа получают его место; победители к этому моменту уже выше,
+	// а выбывшие на одном этапе упорядочиваются по месту в групповом этапе
+	placeMatches := func(matches []db.Match, label string) {
+		var teams []string
+		for _, match := range matches {
+			teams = append(teams, match.Team1, match.Team2)
+		}
+		sort.SliceStable(teams, func(i, j int) bool {
+			pi, pj := groupPosition(groupStage, teams[i]), groupPosition(groupStage, teams[j])
+			return pi != 0 && (pj == 0 || pi < pj)
+		})
+		for _, team := range teams {
+			place(getParticipantByTeam(tournament.ParticipantTeams, team), label)
+		}
+	}
+
+	if playoff := tournament.Playoff; playoff != nil && playoff.Winner != "" {
+		place(getParticipantByTeam(tournament.ParticipantTeams, playoff.Winner), "first")
+		if playoff.Final != nil {
+			placeMatches([]db.Match{*playoff.Final}, "second")
+		}
+		placeMatches(playoff.SemiFinals, "third")
+		placeMatches(playoff.QuarterFinals, "quarter_final")
+	}
+	for _, standing := range groupStage {
+		place(getParticipantByTeam(tournament.ParticipantTeams, standing.Team), "group")
+	}
+	for _, participant := range tournament.Participants {
+		place(participant, "group")
+	}
+	return placings
 }
 
-func getThirdPlace(tournament *db.Tournament) string {
-	if len(tournament.Playoff.SemiFinals) > 0 {
-		semifinal := tournament.Playoff.SemiFinals[0]
-		if semifinal.Team1 != tournament.Playoff.Winner && semifinal.Team1 != getSecondPlace(tournament) {
-			return getParticipantByTeam(tournament.ParticipantTeams, semifinal.Team1)
-		} else if semifinal.Team2 != tournament.Playoff.Winner && semifinal.Team2 != getSecondPlace(tournament) {
-			return getParticipantByTeam(tournament.ParticipantTeams, semifinal.Team2)
+// groupStageOrder возвращает таблицу группового этапа в порядке мест
+func groupStageOrder(tournament *db.Tournament) []db.Standing {
+	groupStage := make([]db.Standing, len(tournament.Standings))
+	copy(groupStage, tournament.Standings)
+	sort.Slice(groupStage, func(i, j int) bool {
+		// Сравнение по количеству очков
+		if groupStage[i].Points != groupStage[j].Points {
+			return groupStage[i].Points > groupStage[j].Points
+		}
+		// Сравнение по разнице забитых и пропущенных мячей
+		if groupStage[i].GoalsDifference != groupStage[j].GoalsDifference {
+			return groupStage[i].GoalsDifference > groupStage[j].GoalsDifference
+		}
+		// Сравнение по количеству забитых мячей
+		if groupStage[i].GoalsFor != groupStage[j].GoalsFor {
+			return groupStage[i].GoalsFor > groupStage[j].GoalsFor
+		}
+		// Сравнение по количеству сыгранных матчей
+		if groupStage[i].Played != groupStage[j].Played {
+			return groupStage[i].Played > groupStage[j].Played
+		}
+		// Сравнение по результатам личных встреч
+		headToHeadResult := getHeadToHeadResult(groupStage[i].Team, groupStage[j].Team, tournament.Matches)
+		if headToHeadResult != 0 {
+			return headToHeadResult > 0
+		}
+		// Сравнение по алфавиту
+		return groupStage[i].Team < groupStage[j].Team
+	})
+	return groupStage
+}
+
+// groupPosition возвращает место команды в групповом этапе (с 1) или 0, если ее нет в таблице
+func groupPosition(groupStage []db.Standing, team string) int {
+	for i, standing := range groupStage {
+		if standing.Team == team {
+			return i + 1
 		}
 	}
-	return ""
+	return 0
 }
 
 func getParticipantByTeam(participantTeams map[string]string, teamName string) string {
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"tournament-bot/internal/db"
+)
+
+func TestAbandoned(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-48 * time.Hour)
+
+	tests := []struct {
+		name       string
+		tournament db.Tournament
+		want       bool
+	}{
+		{
+			name:       "fresh setup is kept",
+			tournament: db.Tournament{CreatedAt: now.Add(-time.Hour), RegistrationDeadline: now.Add(-time.Minute)},
+			want:       false,
+		},
+		{
+			name:       "registration without a deadline stays open",
+			tournament: db.Tournament{CreatedAt: old},
+			want:       false,
+		},
+		{
+			name:       "registration deadline in the future",
+			tournament: db.Tournament{CreatedAt: old, RegistrationDeadline: now.Add(time.Hour)},
+			want:       false,
+		},
+		{
+			name:       "registration closed long ago",
+			tournament: db.Tournament{CreatedAt: old, RegistrationDeadline: now.Add(-time.Hour)},
+			want:       true,
+		},
+		{
+			name: "check-in still running",
+			tournament: db.Tournament{CreatedAt: old, RegistrationDeadline: now.Add(-time.Hour),
+				CheckInDeadline: now.Add(time.Hour)},
+			want: false,
+		},
+		{
+			name: "check-in closed but never started",
+			tournament: db.Tournament{CreatedAt: old, CheckInDeadline: now.Add(-time.Hour),
+				CheckInClosed: true},
+			want: true,
+		},
+		{
+			name:       "teams drawn but not started",
+			tournament: db.Tournament{CreatedAt: old, SetupCompleted: true},
+			want:       true,
+		},
+		{
+			name:       "running tournament",
+			tournament: db.Tournament{CreatedAt: old, SetupCompleted: true, IsActive: true},
+			want:       false,
+		},
+		{
+			name:       "completed tournament",
+			tournament: db.Tournament{CreatedAt: old, SetupCompleted: true, IsCompleted: true},
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Abandoned(&tt.tournament, now); got != tt.want {
+				t.Errorf("Abandoned() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFinalPlacings(t *testing.T) {
+	teams := map[string]string{
+		"Ann": "Arsenal", "Bob": "Barcelona", "Cid": "Chelsea", "Dan": "Dortmund",
+		"Eve": "Everton", "Fay": "Fulham", "Gus": "Genoa",
+	}
+	participants := []string{"Ann", "Bob", "Cid", "Dan", "Eve", "Fay", "Gus"}
+	// Таблица группового этапа в порядке мест; у Gus нет команды в таблице
+	groupStage := []db.Standing{
+		{Team: "Arsenal"}, {Team: "Barcelona"}, {Team: "Chelsea"}, {Team: "Dortmund"},
+		{Team: "Everton"}, {Team: "Fulham"},
+	}
+
+	tests := []struct {
+		name    string
+		playoff *db.Playoff
+		want    map[string]placing
+	}{
+		{
+			name: "no playoff uses the group order",
+			want: map[string]placing{
+				"Ann": {1, "group"}, "Bob": {2, "group"}, "Cid": {3, "group"}, "Dan": {4, "group"},
+				"Eve": {5, "group"}, "Fay": {6, "group"}, "Gus": {7, "group"},
+			},
+		},
+		{
+			name: "semi-final losers ordered by group position",
+			playoff: &db.Playoff{
+				// Полуфинал с худшим по группе проигравшим записан первым
+				SemiFinals: []db.Match{
+					{Team1: "Arsenal", Team2: "Chelsea", Score1: 3, Score2: 1},
+					{Team1: "Barcelona", Team2: "Dortmund", Score1: 0, Score2: 2},
+				},
+				Final:  &db.Match{Team1: "Dortmund", Team2: "Arsenal", Score1: 2, Score2: 1},
+				Winner: "Dortmund",
+			},
+			want: map[string]placing{
+				"Dan": {1, "first"}, "Ann": {2, "second"}, "Bob": {3, "third"}, "Cid": {4, "third"},
+				"Eve": {5, "group"}, "Fay": {6, "group"}, "Gus": {7, "group"},
+			},
+		},
+		{
+			name: "quarter-final losers ordered by group position",
+			playoff: &db.Playoff{
+				QuarterFinals: []db.Match{
+					{Team1: "Fulham", Team2: "Chelsea", Score1: 0, Score2: 1},
+					{Team1: "Everton", Team2: "Barcelona", Score1: 2, Score2: 0},
+				},
+				SemiFinals: []db.Match{
+					{Team1: "Arsenal", Team2: "Everton", Score1: 1, Score2: 0},
+					{Team1: "Dortmund", Team2: "Chelsea", Score1: 0, Score2: 1},
+				},
+				Final:  &db.Match{Team1: "Arsenal", Team2: "Chelsea", Score1: 2, Score2: 0},
+				Winner: "Arsenal",
+			},
+			want: map[string]placing{
+				"Ann": {1, "first"}, "Cid": {2, "second"}, "Dan": {3, "third"}, "Eve": {4, "third"},
+				"Bob": {5, "quarter_final"}, "Fay": {6, "quarter_final"}, "Gus": {7, "group"},
+			},
+		},
+		{
+			name: "unfinished playoff is ignored",
+			playoff: &db.Playoff{
+				SemiFinals: []db.Match{{Team1: "Dortmund", Team2: "Arsenal", Score1: 1, Score2: 0}},
+			},
+			want: map[string]placing{
+				"Ann": {1, "group"}, "Bob": {2, "group"}, "Cid": {3, "group"}, "Dan": {4, "group"},
+				"Eve": {5, "group"}, "Fay": {6, "group"}, "Gus": {7, "group"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tournament := &db.Tournament{Participants: participants, ParticipantTeams: teams, Playoff: tt.playoff}
+			got := finalPlacings(tournament, groupStage)
+			if len(got) != len(tt.want) {
+				t.Fatalf("placings = %v, want %v", got, tt.want)
+			}
+			for participant, want := range tt.want {
+				if got[participant] != want {
+					t.Errorf("%s: placing = %+v, want %+v", participant, got[participant], want)
+				}
+			}
+		})
+	}
+}
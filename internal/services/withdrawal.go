@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"log/slog"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/notifications"
+)
+
+// Счет технического поражения: победитель, проигравший
+var forfeitScore = [2]int{3, 0}
+
+// SetForfeitScore задает счет, с которым засчитываются технические поражения
+func SetForfeitScore(winnerGoals, loserGoals int) {
+	forfeitScore = [2]int{winnerGoals, loserGoals}
+}
+
+// WithdrawWithForfeits снимает участника с начатого турнира: все его несыгранные
+// матчи группового этапа засчитываются соперникам как технические победы, в двухкруговом
+// турнире — по одной за каждый несыгранный круг. Возвращает добавленные матчи.
+func WithdrawWithForfeits(tournamentID int, participantName string) ([]db.Match, error) {
+	tournament, err := GetTournament(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	if !tournament.IsActive {
+		return nil, errors.New("the tournament has not started")
+	}
+	if tournament.Playoff != nil {
+		return nil, errors.New("withdrawals are not supported once the playoff has started")
+	}
+	if !tournament.HasParticipant(participantName) {
+		return nil, ErrNotRegistered
+	}
+
+	team := tournament.ParticipantTeams[participantName]
+	if team == "" {
+		return nil, errors.New("participant has no team")
+	}
+
+	forfeits, err := withdrawalForfeits(tournament, participantName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Условие withdrawn $ne защищает от двойного снятия при повторном нажатии
+	filter := bson.M{"id": tournamentID, "withdrawn": bson.M{"$ne": participantName}}
+	update := bson.M{"$addToSet": bson.M{"withdrawn": participantName}}
+	if len(forfeits) > 0 {
+		update["$push"] = bson.M{"matches": bson.M{"$each": forfeits}}
+	}
+	result, err := db.DB.Collection("tournaments").UpdateOne(context.TODO(), filter, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.ModifiedCount == 0 {
+		return nil, errors.New("participant has already withdrawn")
+	}
+
+	err = updateStandings(tournamentID)
+	if err != nil {
+		return forfeits, err
+	}
+
+	slog.Info("Participant withdrew with forfeits", "tournament_id", tournamentID,
+		"participant", participantName, "forfeits", len(forfeits))
+
+	updated, err := GetTournament(tournamentID)
+	if err == nil {
+		err = notifications.SendWithdrawalMessage(updated, participantName, team, forfeits)
+	}
+	if err != nil {
+		slog.Error("Error sending withdrawal message", "tournament_id", tournamentID, "err", err)
+	}
+
+	return forfeits, nil
+}
+
+// withdrawalForfeits создает технические поражения снявшегося участника во всех
+// несыгранных матчах: с каждым соперником до Rounds() решенных встреч
+func withdrawalForfeits(tournament *db.Tournament, participantName string) ([]db.Match, error) {
+	team := tournament.ParticipantTeams[participantName]
+	var forfeits []db.Match
+	for _, opponent := range tournament.Participants {
+		opponentTeam := tournament.ParticipantTeams[opponent]
+		if opponent == participantName || opponentTeam == "" {
+			continue
+		}
+		for played := tournament.MatchesBetween(team, opponentTeam); played < tournament.Rounds(); played++ {
+			match, err := newStatusMatch(opponentTeam, team, db.MatchForfeit, "withdrawn")
+			if err != nil {
+				return nil, err
+			}
+			forfeits = append(forfeits, match)
+		}
+	}
+	return forfeits, nil
+}
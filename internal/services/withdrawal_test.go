@@ -0,0 +1,85 @@
+package services
+
+import (
+	"testing"
+	"tournament-bot/internal/db"
+)
+
+func TestWithdrawalForfeits(t *testing.T) {
+	teams := map[string]string{"Ann": "Arsenal", "Bob": "Barcelona", "Cid": "Chelsea"}
+	played := db.Match{Team1: "Arsenal", Team2: "Barcelona", Score1: 1, Score2: 0}
+	reversed := db.Match{Team1: "Barcelona", Team2: "Arsenal", Score1: 2, Score2: 2}
+	postponed := db.Match{Team1: "Arsenal", Team2: "Chelsea", Status: db.MatchPostponed}
+
+	tests := []struct {
+		name    string
+		format  string
+		matches []db.Match
+		// want — число технических поражений Ann по соперникам
+		want map[string]int
+	}{
+		{
+			name: "single round robin, nothing played",
+			want: map[string]int{"Barcelona": 1, "Chelsea": 1},
+		},
+		{
+			name:    "single round robin skips decided pairs",
+			matches: []db.Match{played},
+			want:    map[string]int{"Chelsea": 1},
+		},
+		{
+			name:    "postponed match is still owed",
+			matches: []db.Match{postponed},
+			want:    map[string]int{"Barcelona": 1, "Chelsea": 1},
+		},
+		{
+			name:   "double round robin, nothing played",
+			format: db.FormatDoubleRoundRobin,
+			want:   map[string]int{"Barcelona": 2, "Chelsea": 2},
+		},
+		{
+			name:    "double round robin counts each decided meeting",
+			format:  db.FormatDoubleRoundRobin,
+			matches: []db.Match{played, postponed},
+			want:    map[string]int{"Barcelona": 1, "Chelsea": 2},
+		},
+		{
+			name:    "double round robin, both legs played",
+			format:  db.FormatDoubleRoundRobin,
+			matches: []db.Match{played, reversed},
+			want:    map[string]int{"Chelsea": 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tournament := &db.Tournament{
+				Participants:     []string{"Ann", "Bob", "Cid"},
+				ParticipantTeams: teams,
+				Format:           tt.format,
+				Matches:          tt.matches,
+			}
+			forfeits, err := withdrawalForfeits(tournament, "Ann")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := map[string]int{}
+			for _, match := range forfeits {
+				if match.Team2 != "Arsenal" || match.Winner != match.Team1 || match.Status != db.MatchForfeit {
+					t.Fatalf("forfeit should be awarded to the opponent: %+v", match)
+				}
+				got[match.Team1]++
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("forfeits = %v, want %v", got, tt.want)
+			}
+			for team, count := range tt.want {
+				if got[team] != count {
+					t.Errorf("forfeits = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"tournament-bot/internal/db"
+	"tournament-bot/internal/services"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// headToHeadHandler отдает личные встречи двух игроков:
+// GET /api/h2h?player1=<имя>&player2=<имя>
+func headToHeadHandler(w http.ResponseWriter, r *http.Request) {
+	name1, name2 := r.URL.Query().Get("player1"), r.URL.Query().Get("player2")
+	if name1 == "" || name2 == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "player1 and player2 are required"})
+		return
+	}
+
+	var players []*db.Participant
+	for _, name := range []string{name1, name2} {
+		player, err := db.FindParticipant(name)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Error finding participant", "name", name, "err", err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "internal error"})
+			return
+		}
+		if player == nil {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: fmt.Sprintf("participant %s not found", name)})
+			return
+		}
+		players = append(players, player)
+	}
+	if players[0].ID == players[1].ID {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "player1 and player2 are the same participant"})
+		return
+	}
+
+	h2h, err := services.GetHeadToHead(players[0], players[1])
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error getting head-to-head", "player1", name1, "player2", name2, "err", err)
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "internal error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, h2h)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"tournament-bot/internal/bot"
+	"tournament-bot/internal/db"
+)
+
+// Сколько ждать ответа каждой зависимости при проверке готовности
+const readinessTimeout = 5 * time.Second
+
+type readinessReport struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// healthzHandler отвечает, что процесс жив
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok"))
+}
+
+// readyzHandler проверяет MongoDB, Bot API и настройки вебхука.
+// Пустой webhookURL означает режим long polling, в котором вебхук должен отсутствовать.
+func readyzHandler(webhookURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		checks := map[string]error{
+			"mongo":    db.Ping(ctx),
+			"telegram": withTimeout(ctx, checkTelegram),
+			"webhook": withTimeout(ctx, func() error {
+				return checkWebhook(webhookURL)
+			}),
+		}
+
+		report := readinessReport{Status: "ok", Checks: make(map[string]string)}
+		status := http.StatusOK
+		for name, err := range checks {
+			if err != nil {
+				report.Checks[name] = err.Error()
+				report.Status = "unavailable"
+				status = http.StatusServiceUnavailable
+			} else {
+				report.Checks[name] = "ok"
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+func checkTelegram() error {
+	_, err := bot.GetMe()
+	return err
+}
+
+func checkWebhook(webhookURL string) error {
+	info, err := bot.GetWebhookInfo()
+	if err != nil {
+		return err
+	}
+
+	if info.URL != webhookURL {
+		if webhookURL == "" {
+			return fmt.Errorf("webhook is set to %q in long polling mode", info.URL)
+		}
+		return fmt.Errorf("webhook is set to %q instead of %q", info.URL, webhookURL)
+	}
+
+	// Ошибка доставки за последние 5 минут означает, что Telegram не может достучаться до нас
+	if info.LastErrorDate != 0 && time.Since(time.Unix(int64(info.LastErrorDate), 0)) < 5*time.Minute {
+		return fmt.Errorf("last delivery error: %s", info.LastErrorMessage)
+	}
+
+	return nil
+}
+
+// withTimeout выполняет проверку, которая не принимает контекст, с ограничением по времени
+func withTimeout(ctx context.Context, check func() error) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- check()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,21 +1,53 @@
 package web
 
 import (
+	"errors"
 	"github.com/gorilla/mux"
-	"log"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
 	"tournament-bot/internal/bot"
 )
 
-func StartServer(addr string) {
+// StartServer открывает порт и обслуживает HTTP-запросы в отдельной горутине; к возврату
+// сервер уже принимает соединения. Сервер возвращается для последующей остановки.
+// Маршрут вебхука регистрируется только при заданном webhookURL (в режиме long polling он не нужен).
+func StartServer(addr, webhookURL string) (*http.Server, error) {
 	r := mux.NewRouter()
 	//r.HandleFunc("/api/tournaments", getTournaments).Methods("GET")
 	//r.HandleFunc("/api/tournaments/{id}", getTournament).Methods("GET")
 	//r.HandleFunc("/api/tournaments/{id}/standings", getTournamentStandings).Methods("GET")
+	r.HandleFunc("/api/h2h", headToHeadHandler).Methods("GET")
 
 	// Добавьте новый маршрут для обработки входящих запросов от Telegram
-	r.HandleFunc("/webhook", bot.WebhookHandler).Methods("POST")
+	if webhookURL != "" {
+		r.HandleFunc("/webhook", bot.WebhookHandler).Methods("POST")
+	}
 
-	log.Printf("Starting server on %s", addr)
-	log.Fatal(http.ListenAndServe(addr, r))
+	// Проверки состояния и метрики
+	r.HandleFunc("/healthz", healthzHandler).Methods("GET")
+	r.HandleFunc("/readyz", readyzHandler(webhookURL)).Methods("GET")
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
+
+	srv := &http.Server{Addr: addr, Handler: r}
+
+	// Порт открывается до возврата: вебхук регистрируется только после этого,
+	// и первые обновления от Telegram не попадают на закрытый порт
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		slog.Info("Starting server", "addr", addr)
+		err := srv.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Web server failed", "err", err)
+			os.Exit(1)
+		}
+	}()
+
+	return srv, nil
 }